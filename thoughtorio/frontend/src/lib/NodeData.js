@@ -318,6 +318,62 @@ export class NodeData {
         }
     }
 
+    // Variables for backend prompt templates (processing.prompt_template). The
+    // built-in "context" partial lays out system_prompt, facts, history and task
+    // like getProcessedInput does. Each connected node's output is also available
+    // by its title, e.g. {{.research_notes}} for "Research Notes", and by ID in
+    // nodes: {{(index .nodes "node-3").output}}. getNode looks up upstream nodes
+    // for their titles; without it only the IDs are known.
+    getPromptVariables(getNode = null) {
+        /** @type {StructuredContext} */
+        const context = (this.data.output.value && typeof this.data.output.value === 'object') ? this.data.output.value : {};
+        const variables = {
+            system_prompt: this.data.processing?.system_prompt || '',
+            facts: context.facts || [],
+            history: context.history || [],
+            task: context.task || '',
+            nodes: {}
+        };
+
+        (this.data.inputs || []).forEach(input => {
+            const upstream = getNode ? getNode(input.source_id) : null;
+            const entry = {
+                id: input.source_id,
+                title: upstream?.data.metadata?.title || '',
+                type: upstream?.data.node_type || '',
+                output: NodeData._inputText(input, upstream)
+            };
+            variables.nodes[input.source_id] = entry;
+
+            // A title that matches a built-in variable or an earlier node stays reachable through nodes only
+            const name = NodeData._variableName(entry.title);
+            if (name && !(name in variables)) {
+                variables[name] = entry.output;
+            }
+        });
+
+        return variables;
+    }
+
+    // The text an upstream node contributed: its answer for AI nodes, its content otherwise
+    static _inputText(input, upstream) {
+        if (typeof input.data === 'string') {
+            return input.data;
+        }
+        if (upstream) {
+            return upstream.data.node_type === 'dynamic'
+                ? (upstream.data.execution?.result_string || '')
+                : (upstream.data.content || '');
+        }
+        return input.data?.task || '';
+    }
+
+    // Turn a node title into a template variable name: "Research Notes" becomes research_notes
+    static _variableName(title) {
+        const name = (title || '').toLowerCase().replace(/[^a-z0-9]+/g, '_').replace(/^_+|_+$/g, '');
+        return /^[a-z]/.test(name) ? name : '';
+    }
+
     // The Prompt Assembler now has a cleaner input.
     getProcessedInput() {
         if (this.data.node_type !== 'dynamic' || !this.data.output.value || typeof this.data.output.value !== 'object') {
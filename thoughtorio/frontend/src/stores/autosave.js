@@ -0,0 +1,78 @@
+import { writable } from 'svelte/store';
+
+// Identifies this window's canvas in the backend's recovery journal while it is untitled
+export const sessionId = crypto.randomUUID();
+
+// Canvases with unsaved changes left behind by an earlier session
+export const recoverableSessions = writable([]);
+
+// How long edits settle before the canvas is sent to the backend, which debounces again before writing
+const AUTOSAVE_DELAY_MS = 1000;
+
+let autosaveTimer = null;
+
+function backend() {
+    const app = window.go && (/** @type {any} */ (window.go)).app && (/** @type {any} */ (window.go)).app.App;
+    return app && app.AutosaveCanvas ? app : null;
+}
+
+export const autosaveActions = {
+    // Send the canvas to the recovery journal once edits settle. snapshot returns
+    // { canvasPath, canvasData } or null when there is nothing worth keeping.
+    schedule: (snapshot) => {
+        clearTimeout(autosaveTimer);
+        autosaveTimer = setTimeout(async () => {
+            const app = backend();
+            const state = snapshot();
+            if (!app || !state) {
+                return;
+            }
+            try {
+                await app.AutosaveCanvas(sessionId, state.canvasPath || '', JSON.stringify(state.canvasData));
+            } catch (error) {
+                console.error('Autosave failed:', error);
+            }
+        }, AUTOSAVE_DELAY_MS);
+    },
+
+    // Drop this session's journalled changes once they are saved or abandoned
+    discard: async () => {
+        clearTimeout(autosaveTimer);
+        const app = backend();
+        if (!app) {
+            return;
+        }
+        try {
+            await app.DiscardAutosave(sessionId);
+        } catch (error) {
+            console.error('Failed to discard autosave:', error);
+        }
+    },
+
+    loadRecoverable: async () => {
+        const app = backend();
+        if (!app) {
+            return [];
+        }
+        try {
+            const sessions = await app.GetRecoverableSessions();
+            recoverableSessions.set(sessions || []);
+            return sessions || [];
+        } catch (error) {
+            console.error('Failed to check for unsaved canvases:', error);
+            return [];
+        }
+    },
+
+    // Load a journalled canvas; this session keeps autosaving to the same entry
+    recover: async (key) => {
+        const result = await backend().RecoverSession(key, sessionId);
+        recoverableSessions.update(list => list.filter(session => session.key !== key));
+        return result;
+    },
+
+    dismiss: async (key) => {
+        await backend().DiscardRecoverySession(key);
+        recoverableSessions.update(list => list.filter(session => session.key !== key));
+    }
+};
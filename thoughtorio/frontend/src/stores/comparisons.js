@@ -0,0 +1,122 @@
+import { writable, get } from 'svelte/store';
+import { nodes, connections, nodeActions } from './nodes.js';
+import { documentPath } from './document.js';
+
+// Comparison runs keyed by comparison ID. Each run holds one lane per
+// provider/model target with its streamed text, latency, usage and error.
+export const comparisons = writable({});
+
+let listenersRegistered = false;
+
+// Subscribe to the backend comparison events once the Wails runtime is available
+function registerListeners() {
+    if (listenersRegistered || !window.runtime) return;
+    listenersRegistered = true;
+
+    window.runtime.EventsOn('compare:started', (started) => {
+        comparisons.update(runs => ({
+            ...runs,
+            [started.comparisonId]: {
+                id: started.comparisonId,
+                prompt: started.prompt,
+                running: true,
+                lanes: started.targets.map((target, index) => ({
+                    index,
+                    provider: target.provider,
+                    model: target.model,
+                    content: '',
+                    error: '',
+                    latencyMs: 0,
+                    usage: null
+                }))
+            }
+        }));
+    });
+
+    window.runtime.EventsOn('compare:chunk', (chunk) => {
+        comparisons.update(runs => {
+            const run = runs[chunk.comparisonId];
+            if (!run || !run.lanes[chunk.lane]) return runs;
+
+            const lanes = run.lanes.map(lane =>
+                lane.index === chunk.lane ? { ...lane, content: lane.content + chunk.chunk } : lane
+            );
+            return { ...runs, [chunk.comparisonId]: { ...run, lanes } };
+        });
+    });
+}
+
+export const comparisonActions = {
+    // Send one prompt to several { provider, model, options } targets at once;
+    // each lane's key is resolved by the backend for the open canvas
+    run: async (prompt, targets) => {
+        registerListeners();
+
+        if (!window.go || !(/** @type {any} */ (window.go)).app || !(/** @type {any} */ (window.go)).app.App) {
+            throw new Error('Wails runtime not available');
+        }
+
+        const result = await (/** @type {any} */ (window.go)).app.App.CompareCompletions(get(documentPath) || '', prompt, targets);
+
+        // The final result replaces the streamed lanes so latency, usage and errors are filled in
+        comparisons.update(runs => ({
+            ...runs,
+            [result.comparisonId]: {
+                id: result.comparisonId,
+                prompt: result.prompt,
+                running: false,
+                durationMs: result.durationMs,
+                lanes: result.lanes
+            }
+        }));
+
+        return result;
+    },
+
+    cancel: async (comparisonId) => {
+        if (!window.go || !(/** @type {any} */ (window.go)).app) return false;
+        return (/** @type {any} */ (window.go)).app.App.CancelComparison(comparisonId);
+    },
+
+    remove: (comparisonId) => {
+        comparisons.update(runs => {
+            const { [comparisonId]: _, ...rest } = runs;
+            return rest;
+        });
+    },
+
+    // Save the successful lanes of a comparison as completed AI nodes next to
+    // sourceNodeId, wired to the same inputs so they sit as its siblings
+    saveAsSiblings: (comparisonId, sourceNodeId) => {
+        const run = get(comparisons)[comparisonId];
+        const source = get(nodes).find(n => n.id === sourceNodeId);
+        if (!run || !source) return [];
+
+        const inputs = get(connections).filter(c => c.toId === sourceNodeId);
+        const created = [];
+
+        run.lanes
+            .filter(lane => !lane.error && lane.content)
+            .forEach((lane, offset) => {
+                const x = source.x + (source.width + 40) * (offset + 1);
+                const node = nodeActions.add('dynamic', x, source.y);
+                nodeActions.update(node.id, { title: `${lane.provider}: ${lane.model}` });
+                nodeActions.setNodeCompleted(node.id, lane.content);
+
+                // Connections are added directly so saving doesn't trigger auto-execution
+                const siblingConnections = inputs.map(input => ({
+                    id: crypto.randomUUID(),
+                    fromId: input.fromId,
+                    toId: node.id,
+                    fromPort: input.fromPort,
+                    toPort: input.toPort,
+                    created: Date.now()
+                }));
+                connections.update(c => [...c, ...siblingConnections]);
+
+                created.push(node);
+            });
+
+        return created;
+    }
+};
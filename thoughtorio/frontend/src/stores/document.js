@@ -0,0 +1,73 @@
+import { writable, get } from 'svelte/store';
+import { sessionId } from './autosave.js';
+
+// Whether this window's canvas has changes that aren't in its file
+export const canvasDirty = writable(false);
+
+// The file this window's canvas was loaded from or saved to, or null while untitled.
+// Completions send it so audit entries, budgets and .env credentials follow the canvas.
+export const documentPath = writable(null);
+
+// The canvas content as last loaded or saved, compared against to track dirty state
+let cleanSnapshot = null;
+
+function backend() {
+    const app = window.go && (/** @type {any} */ (window.go)).app && (/** @type {any} */ (window.go)).app.App;
+    if (!app) {
+        throw new Error('Wails runtime not available');
+    }
+    return app;
+}
+
+function setDirty(dirty) {
+    if (get(canvasDirty) === dirty) {
+        return;
+    }
+    canvasDirty.set(dirty);
+    // The backend asks before quitting with unsaved changes
+    try {
+        backend().SetCanvasDirty(sessionId, dirty);
+    } catch (error) {
+        console.warn('Failed to record unsaved changes:', error);
+    }
+}
+
+export const documentActions = {
+    // Load a canvas into this window through an open dialog; saves then go back to its file
+    open: () => backend().OpenCanvas(sessionId),
+
+    openPath: (path) => backend().OpenCanvasFromPath(sessionId, path),
+
+    // Save to path, or to this window's current file when path is empty. The
+    // result has conflict set when the file changed on disk since it was loaded.
+    save: (path, canvasData, overwrite = false) =>
+        backend().SaveCanvasToPath(sessionId, path || '', JSON.stringify(canvasData), overwrite),
+
+    saveAs: (canvasData) => backend().SaveCanvasAs(sessionId, JSON.stringify(canvasData)),
+
+    // Forget this window's file, as when starting a new canvas
+    close: async () => {
+        try {
+            await backend().CloseCanvas(sessionId);
+        } catch (error) {
+            console.warn('Failed to close canvas document:', error);
+        }
+    },
+
+    // Record the content now matching the file on disk
+    markClean: (snapshot) => {
+        cleanSnapshot = snapshot;
+        setDirty(false);
+    },
+
+    // Recovered changes aren't in the file until the next save
+    markDirty: () => {
+        cleanSnapshot = null;
+        setDirty(true);
+    },
+
+    // Compare the current content with the content last loaded or saved
+    update: (snapshot) => {
+        setDirty(snapshot !== cleanSnapshot);
+    }
+};
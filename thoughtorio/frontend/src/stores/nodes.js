@@ -156,6 +156,39 @@ export const nodeActions = {
         console.log('✅ Node deletion completed:', id);
     },
 
+    // Nodes as saved in a canvas file, each carrying its workflow data
+    serialize: () => {
+        const store = get(nodeDataStore);
+        return get(nodes).map(node => {
+            const nodeData = store.get(node.id);
+            return nodeData ? { ...node, data: nodeData.data } : node;
+        });
+    },
+
+    // Replace the canvas with nodes loaded from a file, rebuilding their workflow
+    // data from what was saved or, for older canvases, from the node itself
+    restore: (nodeList) => {
+        const store = new Map();
+        const visualNodes = nodeList.map(({ data, ...node }) => {
+            let nodeData;
+            if (data) {
+                nodeData = new NodeData(data.node_type || node.type, node.id);
+                nodeData.data = data;
+            } else if (node.type === 'input') {
+                nodeData = NodeData.createInput(node.id, node.content, node.title);
+            } else if (node.type === 'dynamic') {
+                nodeData = NodeData.createDynamic(node.id, node.title);
+            } else {
+                nodeData = NodeData.createStatic(node.id, node.content, node.title);
+            }
+            store.set(node.id, nodeData);
+            return node;
+        });
+
+        nodeDataStore.set(store);
+        nodes.set(visualNodes);
+    },
+
     // Get YAML backend data for a node
     getNodeData: (id) => {
         let nodeData = null;
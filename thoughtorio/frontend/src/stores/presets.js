@@ -0,0 +1,60 @@
+import { writable, get } from 'svelte/store';
+import { documentPath } from './document.js';
+
+// Named model presets stored by the backend, e.g. "fast-summarizer" or "deep-reasoner".
+// Each bundles a provider, model, generation parameters and system prompt.
+export const presets = writable([]);
+export const presetsError = writable('');
+
+function backend() {
+    const app = window.go && (/** @type {any} */ (window.go)).app && (/** @type {any} */ (window.go)).app.App;
+    if (!app) {
+        throw new Error('Wails runtime not available');
+    }
+    return app;
+}
+
+export const presetActions = {
+    load: async () => {
+        try {
+            const result = await backend().GetModelPresets();
+            if (!result.success) {
+                throw new Error(result.error);
+            }
+            presets.set(result.presets || []);
+            presetsError.set('');
+        } catch (error) {
+            console.error('Failed to load presets:', error);
+            presetsError.set(`Error loading presets: ${error.message || error}`);
+        }
+        return get(presets);
+    },
+
+    save: async (preset) => {
+        await backend().SaveModelPreset(preset);
+        return presetActions.load();
+    },
+
+    delete: async (name) => {
+        await backend().DeleteModelPreset(name);
+        return presetActions.load();
+    },
+
+    // Returns a JSON document with the named presets, or all presets when names is empty
+    export: async (names = []) => {
+        return backend().ExportModelPresets(names);
+    },
+
+    import: async (json, overwrite = false) => {
+        const imported = await backend().ImportModelPresets(json, overwrite);
+        await presetActions.load();
+        return imported;
+    },
+
+    // Checks that the preset's model is still offered by its provider
+    validate: async (name, apiKey) => {
+        return backend().ValidateModelPreset(name, apiKey, get(documentPath) || '');
+    },
+
+    find: (name) => get(presets).find(p => p.name === name)
+};
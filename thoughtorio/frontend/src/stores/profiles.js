@@ -0,0 +1,55 @@
+import { writable, get } from 'svelte/store';
+import { presetActions } from './presets.js';
+
+// Named profiles, e.g. "personal" with cloud providers or "client" kept local-only.
+// Each has its own settings, keys, presets, recents, budgets and caches in the backend.
+export const profiles = writable([]);
+export const activeProfile = writable('default');
+export const profilesError = writable('');
+
+function backend() {
+    const app = window.go && (/** @type {any} */ (window.go)).app && (/** @type {any} */ (window.go)).app.App;
+    if (!app) {
+        throw new Error('Wails runtime not available');
+    }
+    return app;
+}
+
+// Another window may switch profiles, so follow the backend
+if (window.runtime && window.runtime.EventsOn) {
+    window.runtime.EventsOn('profile:changed', (profile) => {
+        activeProfile.set(profile.name);
+        presetActions.load();
+    });
+}
+
+export const profileActions = {
+    load: async () => {
+        try {
+            const result = await backend().ListProfiles();
+            profiles.set(result.profiles || []);
+            activeProfile.set(result.active);
+            profilesError.set('');
+        } catch (error) {
+            console.error('Failed to load profiles:', error);
+            profilesError.set(`Error loading profiles: ${error.message || error}`);
+        }
+        return get(profiles);
+    },
+
+    switch: async (name) => {
+        // Settings and presets follow through the profile:changed and settings:changed events
+        await backend().SwitchProfile(name);
+        activeProfile.set(name);
+    },
+
+    create: async (name, description = '') => {
+        await backend().CreateProfile({ name, description });
+        return profileActions.load();
+    },
+
+    delete: async (name) => {
+        await backend().DeleteProfile(name);
+        return profileActions.load();
+    }
+};
@@ -0,0 +1,44 @@
+import { writable } from 'svelte/store';
+
+// Where API keys are kept. Without an OS keyring they go to an encrypted vault,
+// which must be unlocked with its passphrase before keys can be read or stored.
+export const secretsStatus = writable({ backend: '', locked: false, initialized: true, stored: [], pending: [] });
+
+function backend() {
+    const app = window.go && (/** @type {any} */ (window.go)).app && (/** @type {any} */ (window.go)).app.App;
+    if (!app) {
+        throw new Error('Wails runtime not available');
+    }
+    return app;
+}
+
+// Another window, or a profile switch, may have locked or unlocked the vault
+if (window.runtime && window.runtime.EventsOn) {
+    window.runtime.EventsOn('secrets:changed', (status) => {
+        secretsStatus.set(status);
+    });
+    window.runtime.EventsOn('profile:changed', () => {
+        secretsActions.load();
+    });
+}
+
+export const secretsActions = {
+    load: async () => {
+        try {
+            secretsStatus.set(await backend().GetSecretsStatus());
+        } catch (error) {
+            console.error('Failed to load secrets status:', error);
+        }
+    },
+
+    // The first unlock sets the vault's passphrase
+    unlock: async (passphrase) => {
+        await backend().UnlockSecrets(passphrase);
+        await secretsActions.load();
+    },
+
+    lock: async () => {
+        await backend().LockSecrets();
+        await secretsActions.load();
+    }
+};
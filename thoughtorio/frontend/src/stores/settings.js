@@ -1,4 +1,5 @@
-import { writable } from 'svelte/store';
+import { writable, get } from 'svelte/store';
+import { documentPath } from './document.js';
 
 // Available AI providers based on your Go lego code
 export const availableProviders = [
@@ -23,6 +24,13 @@ export const availableProviders = [
         requiresApiKey: true,
         models: ['gemini-pro', 'gemini-pro-vision']
     },
+    {
+        id: 'azure',
+        name: 'Azure OpenAI',
+        description: 'OpenAI models deployed to an Azure OpenAI resource',
+        requiresApiKey: true,
+        models: []
+    },
     {
         id: 'local',
         name: 'Local (Ollama)',
@@ -68,19 +76,30 @@ export const settings = writable({
     openai_embedding_api_key: '',
     gemini_api_key: '',
     gemini_embedding_api_key: '',
+    azure_api_key: '',
     chat_model_id: '',
     story_processing_model_id: '',
     local_embedding_model_name: '',
+    reasoning_effort: '',
+    auto_continue: false,
+    // Runtime options for local completions: { options: { num_ctx, num_gpu, ... }, keep_alive, raw }
+    ollama: { options: {} },
+    // Azure OpenAI resource: { endpoint, api_version, deployments: { model: deployment } }
+    azure_openai: { endpoint: '', api_version: '', deployments: {} },
+    // How prompts too long for the model are shortened
+    prompt_budget: { strategy: 'drop_oldest', reserveOutputTokens: 1000 },
     
     // UI settings
     showContainerLabels: true,
     autoExecuteWorkflows: false,
     debugMode: false,
+    showReasoning: false,
     
     // Canvas settings  
     defaultNodeWidth: 200,
     defaultNodeHeight: 120,
-    containerPadding: 20
+    containerPadding: 20,
+    canvasBackups: 5
 });
 
 // Store provider-specific model selections
@@ -88,36 +107,74 @@ export const providerModels = writable({
     openrouter: { chat: '', story: '' },
     openai: { chat: '', story: '' },
     gemini: { chat: '', story: '' },
+    azure: { chat: '', story: '' },
     local: { chat: '', story: '' }
 });
 
+const LEGACY_SETTINGS_KEY = 'thoughtorio-settings';
+
+function backend() {
+    const app = window.go && (/** @type {any} */ (window.go)).app && (/** @type {any} */ (window.go)).app.App;
+    return app && app.GetSettings ? app : null;
+}
+
+// Keep every window in step when settings are saved elsewhere
+if (window.runtime && window.runtime.EventsOn) {
+    window.runtime.EventsOn('settings:changed', (saved) => {
+        settings.update(current => ({ ...current, ...saved }));
+    });
+}
+
 // Settings actions
 export const settingsActions = {
-    // Load settings from backend/localStorage
+    // Load settings from the backend's settings.json. Settings left in
+    // localStorage by earlier versions are handed to the backend once, which
+    // migrates them and moves their API keys into the secrets service.
     load: async () => {
         try {
-            // TODO: Call Wails backend to get config from ~/.llore/config.json
-            // For now, try localStorage as fallback
-            const saved = localStorage.getItem('thoughtorio-settings');
-            if (saved) {
-                const parsed = JSON.parse(saved);
-                settings.update(current => ({ ...current, ...parsed }));
+            const app = backend();
+            const legacy = localStorage.getItem(LEGACY_SETTINGS_KEY);
+            if (!app) {
+                if (legacy) {
+                    settings.update(current => ({ ...current, ...JSON.parse(legacy) }));
+                }
+                return;
+            }
+
+            const loaded = await app.GetSettings();
+            settings.update(current => ({ ...current, ...loaded }));
+            if (!legacy) {
+                return;
             }
+
+            // A blob the backend rejects is set aside rather than retried on
+            // every start
+            try {
+                const imported = await app.ImportSettings(legacy);
+                settings.update(current => ({ ...current, ...imported }));
+            } catch (error) {
+                console.error('Failed to import legacy settings:', error);
+                localStorage.setItem(`${LEGACY_SETTINGS_KEY}-unimported`, legacy);
+            }
+            localStorage.removeItem(LEGACY_SETTINGS_KEY);
         } catch (error) {
             console.error('Failed to load settings:', error);
         }
     },
     
-    // Save settings to backend/localStorage
+    // Save settings to the backend; API keys come back as secret references
     save: async (newSettings) => {
         try {
             settings.update(current => ({ ...current, ...newSettings }));
-            
-            // TODO: Call Wails backend to save config to ~/.llore/config.json
-            // For now, save to localStorage as fallback
-            localStorage.setItem('thoughtorio-settings', JSON.stringify(newSettings));
-            
-            console.log('Settings saved:', newSettings);
+
+            const app = backend();
+            if (!app) {
+                localStorage.setItem(LEGACY_SETTINGS_KEY, JSON.stringify(newSettings));
+                return;
+            }
+
+            const saved = await app.UpdateSettings(newSettings);
+            settings.update(current => ({ ...current, ...saved }));
         } catch (error) {
             console.error('Failed to save settings:', error);
         }
@@ -134,7 +191,17 @@ export const settingsActions = {
     },
     
     // Reset to defaults
-    reset: () => {
+    reset: async () => {
+        const app = backend();
+        if (app) {
+            try {
+                settings.set(await app.ResetSettings());
+            } catch (error) {
+                console.error('Failed to reset settings:', error);
+            }
+            return;
+        }
+
         const defaults = {
             activeMode: 'openrouter',
             openrouter_api_key: '',
@@ -142,15 +209,23 @@ export const settingsActions = {
             openai_embedding_api_key: '',
             gemini_api_key: '',
             gemini_embedding_api_key: '',
+            azure_api_key: '',
             chat_model_id: '',
             story_processing_model_id: '',
             local_embedding_model_name: '',
+            reasoning_effort: '',
+            auto_continue: false,
+            ollama: { options: {} },
+            azure_openai: { endpoint: '', api_version: '', deployments: {} },
+            prompt_budget: { strategy: 'drop_oldest', reserveOutputTokens: 1000 },
             showContainerLabels: true,
             autoExecuteWorkflows: false,
             debugMode: false,
+            showReasoning: false,
             defaultNodeWidth: 200,
             defaultNodeHeight: 120,
-            containerPadding: 20
+            containerPadding: 20,
+            canvasBackups: 5
         };
         settings.set(defaults);
         settingsActions.save(defaults);
@@ -206,15 +281,19 @@ export const settingsActions = {
                 return models;
             }
             
-            // Make actual API calls using new backend structure
+            // Make actual API calls using new backend structure; the open
+            // canvas's .env file can supply a key the settings don't hold
+            const canvasPath = get(documentPath) || '';
             if (providerId === 'openrouter') {
-                models = await window.go.app.App.FetchOpenRouterModels(apiKey);
+                models = await window.go.app.App.FetchOpenRouterModels(apiKey, canvasPath);
             } else if (providerId === 'openai') {
-                models = await window.go.app.App.FetchOpenAIModels(apiKey);
+                models = await window.go.app.App.FetchOpenAIModels(apiKey, canvasPath);
             } else if (providerId === 'gemini') {
-                models = await window.go.app.App.FetchGeminiModels(apiKey);
+                models = await window.go.app.App.FetchGeminiModels(apiKey, canvasPath);
+            } else if (providerId === 'azure') {
+                models = await window.go.app.App.FetchAzureOpenAIModels(apiKey, canvasPath);
             } else if (providerId === 'local') {
-                models = await window.go.app.App.FetchOllamaModels();
+                models = await window.go.app.App.FetchOllamaModels(canvasPath);
             }
             
             modelList.set(models || []);
@@ -249,7 +328,7 @@ export const settingsActions = {
             }
             
             // Use Ollama models for embedding (same API)
-            const models = await window.go.app.App.FetchOllamaModels();
+            const models = await window.go.app.App.FetchOllamaModels(get(documentPath) || '');
             
             embeddingModelList.set(models || []);
             return models || [];
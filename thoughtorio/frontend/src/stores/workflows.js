@@ -2,6 +2,8 @@ import { writable, get, derived } from 'svelte/store';
 import { nodeActions, nodeDataStore, nodes, connections } from './nodes.js';
 import { ContextEngine } from '../lib/ContextEngine.js';
 import { settings } from './settings.js';
+import { presetActions } from './presets.js';
+import { documentPath } from './document.js';
 
 // Canvas-based counters that reset on new canvas
 export const machineCounter = writable(0);
@@ -198,6 +200,19 @@ export const workflowContainers = derived([nodes, connections, nodeStateKey], ([
     return [...allNetworks, ...allFactories, ...allMachines];
 });
 
+/**
+ * Reduces containers to the shape saved in a canvas file: their type, members and bounds
+ */
+export function serializeContainers(containerList) {
+    return containerList.map(container => ({
+        id: container.id,
+        type: container.id.split('-')[0],
+        nodeIds: container.nodes ? container.nodes.map(n => n.id) : (container.nodeIds || []),
+        containerIds: [...(container.factories || []), ...(container.machines || [])].map(c => c.id),
+        bounds: container.bounds
+    }));
+}
+
 /**
  * Detects connected components in the node graph
  * Returns array of workflow containers, each containing connected nodes
@@ -774,6 +789,95 @@ function collectAllNodeIds(container, nodeIdSet = new Set()) {
     return nodeIdSet;
 }
 
+// Nodes with a processing.preset run with that preset's provider, model and
+// parameters instead of the global model selection
+function presetRequestFor(nodeId, currentSettings) {
+    const nodeData = nodeActions.getNodeData(nodeId);
+    const preset = nodeData?.data.processing?.preset && presetActions.find(nodeData.data.processing.preset);
+    if (!preset) return {};
+    return {
+        preset: preset.name,
+        apiKey: preset.provider === 'local' ? '' : currentSettings[`${preset.provider}_api_key`]
+    };
+}
+
+// Nodes with a processing.prompt_template have their prompt rendered by the
+// backend template engine instead of the assembled input text
+function promptTemplateFor(nodeId) {
+    const nodeData = nodeActions.getNodeData(nodeId);
+    const template = nodeData?.data.processing?.prompt_template;
+    if (!template) return {};
+    return { template, variables: nodeData.getPromptVariables(nodeActions.getNodeData) };
+}
+
+/**
+ * Fails when a provider has no API key. Keys can also come from a stored secret,
+ * the environment or a .env file, so the backend is asked before giving up.
+ * @param {string} provider
+ * @param {string} apiKey
+ */
+async function requireApiKey(provider, apiKey) {
+    if (provider === 'local' || apiKey) return;
+
+    const app = window.go && (/** @type {any} */ (window.go)).app && (/** @type {any} */ (window.go)).app.App;
+    if (app && app.GetCredentialSources) {
+        const sources = await app.GetCredentialSources(get(documentPath) || '', {});
+        if (sources.some(source => source.provider === provider && source.field === 'apiKey' && source.source)) {
+            return;
+        }
+    }
+    throw new Error(`API key required for ${provider} provider`);
+}
+
+/**
+ * Every local model the settings and the canvas's presets are configured with:
+ * the story, chat and embedding models in local mode, and the model of each
+ * local preset a node runs with.
+ * @param {any} currentSettings
+ */
+function configuredLocalModels(currentSettings) {
+    const models = [];
+    if (currentSettings.activeMode === 'local') {
+        models.push(
+            currentSettings.story_processing_model_id,
+            currentSettings.chat_model_id,
+            currentSettings.local_embedding_model_name
+        );
+    }
+    for (const node of get(nodes)) {
+        const name = nodeActions.getNodeData(node.id)?.data.processing?.preset;
+        const preset = name && presetActions.find(name);
+        if (preset && preset.provider === 'local') {
+            models.push(preset.model);
+        }
+    }
+    return [...new Set(models.filter(Boolean))];
+}
+
+/**
+ * Offers to pull the configured local models that aren't installed in Ollama yet.
+ * Presets must be loaded first.
+ * @param {any} currentSettings
+ */
+async function ensureLocalModelsInstalled(currentSettings) {
+    const models = configuredLocalModels(currentSettings);
+    if (models.length === 0) return;
+    const app = window.go && (/** @type {any} */ (window.go)).app && (/** @type {any} */ (window.go)).app.App;
+    if (!app || !app.FindMissingOllamaModels) return;
+
+    const missing = await app.FindMissingOllamaModels(models);
+    if (!missing || missing.length === 0) return;
+
+    const list = missing.map(model => `"${model}"`).join(', ');
+    if (!confirm(`These models are not installed in Ollama: ${list}. Pull them now?`)) {
+        throw new Error(`Local models not installed: ${list}`);
+    }
+    for (const model of missing) {
+        console.log(`⬇️ Pulling Ollama model: ${model}`);
+        await app.PullOllamaModel(model);
+    }
+}
+
 /**
  * The master execution function. Can execute any container recursively.
  * @param {WorkflowContainer | FactoryContainer | NetworkContainer} container
@@ -815,10 +919,12 @@ async function executeContainer(container) {
             apiKey = currentSettings.openai_api_key; break;
         case 'gemini':
             apiKey = currentSettings.gemini_api_key; break;
+        case 'azure':
+            apiKey = currentSettings.azure_api_key; break;
     }
-    if (currentSettings.activeMode !== 'local' && !apiKey) {
-        throw new Error(`API key required for ${currentSettings.activeMode} provider`);
-    }
+    await requireApiKey(currentSettings.activeMode, apiKey);
+    await presetActions.load();
+    await ensureLocalModelsInstalled(currentSettings);
     
     for (const entityId of executionOrder) {
         const entity = children.find(child => child.id === entityId);
@@ -929,11 +1035,23 @@ async function executeContainer(container) {
                         throw new Error('Wails runtime not available');
                     }
                     
-                    const response = await (/** @type {any} */ (window.go)).app.App.GetAICompletion(
-                        currentSettings.activeMode, currentSettings.story_processing_model_id, inputText, apiKey
-                    );
+                    const response = await (/** @type {any} */ (window.go)).app.App.RequestAICompletion({
+                        provider: currentSettings.activeMode,
+                        model: currentSettings.story_processing_model_id,
+                        prompt: inputText,
+                        apiKey,
+                        canvasPath: get(documentPath) || '',
+                        nodeId: entityId,
+                        ...promptTemplateFor(entityId),
+                        ...presetRequestFor(entityId, currentSettings),
+                        options: {
+                            reasoningEffort: currentSettings.reasoning_effort || '',
+                            autoContinue: !!currentSettings.auto_continue
+                        }
+                    });
                     
                     if (response && response.Content) {
+                        nodeActions.update(entityId, { reasoning: response.Reasoning || '' });
                         nodeActions.setNodeCompleted(entityId, response.Content);
                     } else { 
                         throw new Error(response.Error || 'No content returned'); 
@@ -1084,10 +1202,12 @@ async function executeWorkflow(container) {
             apiKey = currentSettings.openai_api_key; break;
         case 'gemini':
             apiKey = currentSettings.gemini_api_key; break;
+        case 'azure':
+            apiKey = currentSettings.azure_api_key; break;
     }
-    if (currentSettings.activeMode !== 'local' && !apiKey) {
-        throw new Error(`API key required for ${currentSettings.activeMode} provider`);
-    }
+    await requireApiKey(currentSettings.activeMode, apiKey);
+    await presetActions.load();
+    await ensureLocalModelsInstalled(currentSettings);
 
     // 2. Execute nodes in sequence
     for (const nodeId of executionOrder) {
@@ -1134,18 +1254,27 @@ async function executeWorkflow(container) {
                         throw new Error('Wails runtime not available. Make sure the app is properly initialized.');
                     }
                     
-                    const response = await (/** @type {any} */ (window.go)).app.App.GetAICompletion(
-                        currentSettings.activeMode,
-                        currentSettings.story_processing_model_id,
-                        inputText,
-                        apiKey
-                    );
+                    const response = await (/** @type {any} */ (window.go)).app.App.RequestAICompletion({
+                        provider: currentSettings.activeMode,
+                        model: currentSettings.story_processing_model_id,
+                        prompt: inputText,
+                        apiKey,
+                        canvasPath: get(documentPath) || '',
+                        nodeId: node.id,
+                        ...promptTemplateFor(nodeId),
+                        ...presetRequestFor(nodeId, currentSettings),
+                        options: {
+                            reasoningEffort: currentSettings.reasoning_effort || '',
+                            autoContinue: !!currentSettings.auto_continue
+                        }
+                    });
 
                     console.log(`   - AI Response:`, response);
                     console.log(`   - About to call setNodeCompleted for ${nodeId}`);
 
                     if (response && response.Content) {
                         console.log(`   - Setting node ${nodeId} as completed with content:`, response.Content);
+                        nodeActions.update(nodeId, { reasoning: response.Reasoning || '' });
                         nodeActions.setNodeCompleted(nodeId, response.Content);
                         console.log(`   - Setting workflow node ${nodeId} as completed`);
                         workflowActions.setNodeCompleted(nodeId);
@@ -1199,11 +1328,12 @@ async function executeFactory(factory) {
         case 'gemini':
             apiKey = currentSettings.gemini_api_key;
             break;
+        case 'azure':
+            apiKey = currentSettings.azure_api_key;
+            break;
     }
     
-    if (currentSettings.activeMode !== 'local' && !apiKey) {
-        throw new Error(`API key required for ${currentSettings.activeMode} provider`);
-    }
+    await requireApiKey(currentSettings.activeMode, apiKey);
     
     // Create dependency graph from factory connections
     const machineIds = factory.machines.map(m => m.id);
@@ -4,13 +4,13 @@ import {providers} from '../models';
 import {app} from '../models';
 import {models} from '../models';
 
-export function FetchGeminiModels(arg1:string):Promise<Array<providers.Model>>;
+export function FetchGeminiModels(arg1:string,arg2:string):Promise<Array<providers.Model>>;
 
-export function FetchOllamaModels():Promise<Array<providers.Model>>;
+export function FetchOllamaModels(arg1:string):Promise<Array<providers.Model>>;
 
-export function FetchOpenAIModels(arg1:string):Promise<Array<providers.Model>>;
+export function FetchOpenAIModels(arg1:string,arg2:string):Promise<Array<providers.Model>>;
 
-export function FetchOpenRouterModels(arg1:string):Promise<Array<providers.Model>>;
+export function FetchOpenRouterModels(arg1:string,arg2:string):Promise<Array<providers.Model>>;
 
 export function GetAICompletion(arg1:string,arg2:string,arg3:string,arg4:string):Promise<app.AICompletionResponse>;
 
@@ -2,20 +2,20 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function FetchGeminiModels(arg1) {
-  return window['go']['app']['App']['FetchGeminiModels'](arg1);
+export function FetchGeminiModels(arg1, arg2) {
+  return window['go']['app']['App']['FetchGeminiModels'](arg1, arg2);
 }
 
-export function FetchOllamaModels() {
-  return window['go']['app']['App']['FetchOllamaModels']();
+export function FetchOllamaModels(arg1) {
+  return window['go']['app']['App']['FetchOllamaModels'](arg1);
 }
 
-export function FetchOpenAIModels(arg1) {
-  return window['go']['app']['App']['FetchOpenAIModels'](arg1);
+export function FetchOpenAIModels(arg1, arg2) {
+  return window['go']['app']['App']['FetchOpenAIModels'](arg1, arg2);
 }
 
-export function FetchOpenRouterModels(arg1) {
-  return window['go']['app']['App']['FetchOpenRouterModels'](arg1);
+export function FetchOpenRouterModels(arg1, arg2) {
+  return window['go']['app']['App']['FetchOpenRouterModels'](arg1, arg2);
 }
 
 export function GetAICompletion(arg1, arg2, arg3, arg4) {
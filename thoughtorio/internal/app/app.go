@@ -2,11 +2,22 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"sync"
 
+	"thoughtorio/internal/audit"
+	"thoughtorio/internal/autosave"
+	"thoughtorio/internal/canvas"
+	"thoughtorio/internal/credentials"
 	"thoughtorio/internal/models"
+	"thoughtorio/internal/prompts"
 	"thoughtorio/internal/providers"
 	"thoughtorio/internal/services"
+	"thoughtorio/internal/spending"
 	"thoughtorio/internal/storage"
+	"thoughtorio/internal/tokenizer"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct - simplified orchestration layer
@@ -14,14 +25,46 @@ type App struct {
 	ctx              context.Context
 	providerManager  *providers.ProviderManager
 	canvasStorage    *storage.CanvasStorage
-	recentsStorage   *storage.RecentsStorage
+	profileStorage   *storage.ProfileStorage
 	clipboardService *services.ClipboardService
+	auditLogger      *audit.Logger
+	spendingTracker  *spending.Tracker
+	autosave         *autosave.Journal
+
+	// The active profile and the stores opened from it, replaced as a whole
+	// when the profile changes; read it through current()
+	state    *profileState
+	stateMu  sync.RWMutex
+	switchMu sync.Mutex
+
+	// Cancel functions for in-flight Ollama pulls, keyed by model name
+	ollamaPulls   map[string]context.CancelFunc
+	ollamaPullsMu sync.Mutex
+
+	// Cancel functions for running model comparisons, keyed by comparison ID
+	comparisons   map[string]context.CancelFunc
+	comparisonsMu sync.Mutex
+
+	// The file each window's canvas is saved to, keyed by frontend session ID
+	documents   map[string]*openDocument
+	documentsMu sync.Mutex
+
+	// messageDialog shows native message dialogs; tests replace it
+	messageDialog func(ctx context.Context, options runtime.MessageDialogOptions) (string, error)
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
 	return &App{
 		providerManager: providers.NewProviderManager(),
+		state: &profileState{
+			promptEngine: prompts.NewEngine(),
+			credentials:  credentials.NewResolver(nil),
+		},
+		ollamaPulls:     make(map[string]context.CancelFunc),
+		comparisons:     make(map[string]context.CancelFunc),
+		documents:       make(map[string]*openDocument),
+		messageDialog:   runtime.MessageDialog,
 	}
 }
 
@@ -30,16 +73,84 @@ func NewApp() *App {
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
 	a.canvasStorage = storage.NewCanvasStorage(ctx)
+	if assets, err := newAssetStore(); err != nil {
+		log.Printf("canvas assets not available: %v", err)
+	} else {
+		a.canvasStorage.SetAssetStore(assets)
+	}
+	if history, err := newHistoryStore(); err != nil {
+		log.Printf("canvas history not available: %v", err)
+	} else {
+		a.canvasStorage.SetHistoryStore(history)
+	}
+	if views, err := newViewStore(); err != nil {
+		log.Printf("canvas viewports not available: %v", err)
+	} else {
+		a.canvasStorage.SetViewStore(views)
+	}
 	a.clipboardService = services.NewClipboardService(ctx)
 	
-	// Initialize recents storage
-	recentsStorage, err := storage.NewRecentsStorage()
+	// Settings, keys, presets, recents, partials, budgets and caches belong to the active profile
+	profileStorage, err := storage.NewProfileStorage()
+	if err != nil {
+		log.Printf("profile storage not available: %v", err)
+		profileStorage = nil
+	}
+	a.profileStorage = profileStorage
+
+	// Keys the caller doesn't pass come from stored secrets, the environment or a canvas .env
+	a.providerManager.SetCredentialResolver(a.resolveCredentials)
+	a.switchMu.Lock()
+	a.openProfile(a.startupProfile())
+	a.switchMu.Unlock()
+
+	// Budgets are enforced before audit so blocked requests never reach the log
+	if a.spendingTracker != nil {
+		a.providerManager.Use(spending.NewInterceptor(a.spendingTracker, a.providerManager, a.emitBudgetWarning))
+	}
+
+	// Audit logging is opt-in; the interceptor is registered last so it records
+	// the exact prompt sent to the provider
+	auditLogger, err := newAuditLogger()
 	if err != nil {
-		// Log error but don't fail startup
-		// Could implement proper logging here
-		recentsStorage = nil
+		log.Printf("audit log not available: %v", err)
+	} else {
+		a.auditLogger = auditLogger
+		a.providerManager.Use(audit.NewInterceptor(auditLogger))
 	}
-	a.recentsStorage = recentsStorage
+
+	// Unsaved changes are journalled so they can be recovered after a crash
+	journal, err := newAutosaveJournal()
+	if err != nil {
+		log.Printf("autosave not available: %v", err)
+	} else {
+		a.autosave = journal
+	}
+
+	// Register out-of-process provider plugins in the background; each one may take
+	// seconds to answer its handshake, and a broken plugin shouldn't block startup
+	go a.loadProviderPlugins()
+}
+
+// loadProviderPlugins discovers provider plugins and tells the frontend the provider list changed
+func (a *App) loadProviderPlugins() {
+	for _, err := range a.providerManager.LoadPlugins(providers.DefaultPluginDir()) {
+		log.Printf("provider plugin not loaded: %v", err)
+	}
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, ProvidersChangedEvent, a.providerManager.ListProviderInfo())
+	}
+}
+
+// Shutdown is called when the app is closing; it flushes the recovery journal and stops provider plugin processes
+func (a *App) Shutdown(ctx context.Context) {
+	// Changes still waiting to be journalled are kept for recovery on the next start
+	if a.autosave != nil {
+		if err := a.autosave.Flush(); err != nil {
+			log.Printf("failed to flush recovery journal: %v", err)
+		}
+	}
+	a.providerManager.Close()
 }
 
 // Greet returns a greeting for the given name
@@ -49,46 +160,236 @@ func (a *App) Greet(name string) string {
 
 // AI Provider Methods
 
+// ListAIProviders returns all registered providers, including plugin providers
+func (a *App) ListAIProviders() []providers.ProviderInfo {
+	return a.providerManager.ListProviderInfo()
+}
+
+// FetchModels fetches models from any registered provider by name. canvasPath
+// is the open canvas, whose .env file may supply the key.
+func (a *App) FetchModels(provider, apiKey, canvasPath string) ([]providers.Model, error) {
+	return a.providerManager.FetchModels(a.requestContext(), provider, apiKey, canvasPath)
+}
+
+// ProvidersChangedEvent is emitted with the provider list once plugin discovery finishes
+const ProvidersChangedEvent = "providers:changed"
+
+// ReloadProviderPlugins restarts provider plugin discovery and returns any load errors
+func (a *App) ReloadProviderPlugins() []string {
+	errs := a.providerManager.LoadPlugins(providers.DefaultPluginDir())
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, ProvidersChangedEvent, a.providerManager.ListProviderInfo())
+	}
+
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return messages
+}
+
 // FetchOpenRouterModels fetches models from OpenRouter
-func (a *App) FetchOpenRouterModels(apiKey string) ([]providers.Model, error) {
+func (a *App) FetchOpenRouterModels(apiKey, canvasPath string) ([]providers.Model, error) {
 	ctx := a.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	return a.providerManager.FetchModels(ctx, "openrouter", apiKey)
+	return a.providerManager.FetchModels(ctx, "openrouter", apiKey, canvasPath)
 }
 
 // FetchOpenAIModels fetches models from OpenAI
-func (a *App) FetchOpenAIModels(apiKey string) ([]providers.Model, error) {
+func (a *App) FetchOpenAIModels(apiKey, canvasPath string) ([]providers.Model, error) {
 	ctx := a.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	return a.providerManager.FetchModels(ctx, "openai", apiKey)
+	return a.providerManager.FetchModels(ctx, "openai", apiKey, canvasPath)
 }
 
 // FetchGeminiModels fetches models from Gemini
-func (a *App) FetchGeminiModels(apiKey string) ([]providers.Model, error) {
+func (a *App) FetchGeminiModels(apiKey, canvasPath string) ([]providers.Model, error) {
 	ctx := a.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	return a.providerManager.FetchModels(ctx, "gemini", apiKey)
+	return a.providerManager.FetchModels(ctx, "gemini", apiKey, canvasPath)
+}
+
+// FetchAzureOpenAIModels fetches the deployments of the configured Azure OpenAI resource
+func (a *App) FetchAzureOpenAIModels(apiKey, canvasPath string) ([]providers.Model, error) {
+	return a.providerManager.FetchModels(a.requestContext(), "azure", apiKey, canvasPath)
+}
+
+// GetAzureOpenAIConfig returns the Azure OpenAI endpoint, API version and deployment mapping
+func (a *App) GetAzureOpenAIConfig() (providers.AzureOpenAIConfig, error) {
+	azure, err := a.providerManager.GetAzureOpenAIProvider()
+	if err != nil {
+		return providers.AzureOpenAIConfig{}, err
+	}
+	return azure.GetConfig(), nil
+}
+
+// SetAzureOpenAIConfig validates the Azure OpenAI resource configuration and
+// saves it in the settings, which applies it
+func (a *App) SetAzureOpenAIConfig(config providers.AzureOpenAIConfig) error {
+	if !config.IsZero() {
+		if err := config.Validate(); err != nil {
+			return err
+		}
+	}
+	return a.changeSettings(func(settings *models.Settings) {
+		settings.AzureOpenAI = config
+	})
 }
 
 // FetchOllamaModels fetches models from Ollama
-func (a *App) FetchOllamaModels() ([]providers.Model, error) {
+func (a *App) FetchOllamaModels(canvasPath string) ([]providers.Model, error) {
 	ctx := a.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	return a.providerManager.FetchModels(ctx, "local", "")
+	return a.providerManager.FetchModels(ctx, "local", "", canvasPath)
+}
+
+// requestContext returns the app context, or a background context before startup
+func (a *App) requestContext() context.Context {
+	if a.ctx == nil {
+		return context.Background()
+	}
+	return a.ctx
+}
+
+// Ollama Model Management
+
+// OllamaPullProgressEvent is the Wails event emitted for every pull progress update
+const OllamaPullProgressEvent = "ollama:pull-progress"
+
+// PullOllamaModel downloads a model into the local Ollama library, emitting
+// progress as OllamaPullProgressEvent events until the pull finishes
+func (a *App) PullOllamaModel(model string) error {
+	ollama, err := a.providerManager.GetOllamaProvider()
+	if err != nil {
+		return err
+	}
+
+	a.ollamaPullsMu.Lock()
+	if _, running := a.ollamaPulls[model]; running {
+		a.ollamaPullsMu.Unlock()
+		return fmt.Errorf("model '%s' is already being pulled", model)
+	}
+	ctx, cancel := context.WithCancel(a.requestContext())
+	a.ollamaPulls[model] = cancel
+	a.ollamaPullsMu.Unlock()
+
+	defer func() {
+		cancel()
+		a.ollamaPullsMu.Lock()
+		delete(a.ollamaPulls, model)
+		a.ollamaPullsMu.Unlock()
+	}()
+
+	err = ollama.PullModel(ctx, model, func(progress providers.OllamaPullProgress) {
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, OllamaPullProgressEvent, progress)
+		}
+	})
+	if err != nil && a.ctx != nil {
+		runtime.EventsEmit(a.ctx, OllamaPullProgressEvent, providers.OllamaPullProgress{
+			Model: model,
+			Done:  true,
+			Error: err.Error(),
+		})
+	}
+
+	return err
+}
+
+// CancelOllamaPull cancels an in-flight pull started with PullOllamaModel
+func (a *App) CancelOllamaPull(model string) bool {
+	a.ollamaPullsMu.Lock()
+	defer a.ollamaPullsMu.Unlock()
+
+	cancel, running := a.ollamaPulls[model]
+	if running {
+		cancel()
+	}
+	return running
+}
+
+// DeleteOllamaModel removes a model from the local Ollama library
+func (a *App) DeleteOllamaModel(model string) error {
+	ollama, err := a.providerManager.GetOllamaProvider()
+	if err != nil {
+		return err
+	}
+	return ollama.DeleteModel(a.requestContext(), model)
+}
+
+// CopyOllamaModel copies a local Ollama model under a new name
+func (a *App) CopyOllamaModel(source, destination string) error {
+	ollama, err := a.providerManager.GetOllamaProvider()
+	if err != nil {
+		return err
+	}
+	return ollama.CopyModel(a.requestContext(), source, destination)
+}
+
+// ShowOllamaModel returns the parameters, template and license of a local Ollama model
+func (a *App) ShowOllamaModel(model string) (providers.OllamaModelInfo, error) {
+	ollama, err := a.providerManager.GetOllamaProvider()
+	if err != nil {
+		return providers.OllamaModelInfo{}, err
+	}
+	return ollama.ShowModel(a.requestContext(), model)
+}
+
+// FindMissingOllamaModels returns the models referenced by a workflow that are
+// not installed locally, so the frontend can offer to pull them
+func (a *App) FindMissingOllamaModels(models []string) ([]string, error) {
+	ollama, err := a.providerManager.GetOllamaProvider()
+	if err != nil {
+		return nil, err
+	}
+	return ollama.FindMissingModels(a.requestContext(), models)
+}
+
+// GetOllamaConfig returns the runtime options applied to local Ollama completions
+func (a *App) GetOllamaConfig() (providers.OllamaConfig, error) {
+	ollama, err := a.providerManager.GetOllamaProvider()
+	if err != nil {
+		return providers.OllamaConfig{}, err
+	}
+	return ollama.GetConfig(), nil
+}
+
+// SetOllamaConfig updates the runtime options (num_ctx, keep_alive, raw mode, ...)
+// applied to local Ollama completions and saves them in the settings
+func (a *App) SetOllamaConfig(config providers.OllamaConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	return a.changeSettings(func(settings *models.Settings) {
+		settings.Ollama = config
+	})
 }
 
 // AICompletionResponse matches the expected frontend format
 type AICompletionResponse struct {
 	Content string
-	Error   string `json:",omitempty"`
+	// Reasoning is the model's thinking, kept out of Content
+	Reasoning string                       `json:",omitempty"`
+	Error     string                       `json:",omitempty"`
+	Timings   *providers.CompletionTimings `json:",omitempty"`
+	Usage     *providers.TokenUsage        `json:",omitempty"`
+	// FinishReason is why generation stopped: stop, length, content_filter, tool_calls or safety
+	FinishReason  string                   `json:",omitempty"`
+	SafetyRatings []providers.SafetyRating `json:",omitempty"`
+	// Continuations counts follow-up requests made by auto-continue
+	Continuations int `json:",omitempty"`
+	// LatencyMs is the wall-clock time of the provider call
+	LatencyMs float64 `json:",omitempty"`
+	// Truncation is set when the prompt had to be shortened to fit the model's context window
+	Truncation *tokenizer.TruncationReport `json:",omitempty"`
 }
 
 // GetAICompletion performs AI completion using the specified provider
@@ -100,13 +401,95 @@ func (a *App) GetAICompletion(provider, model, prompt, apiKey string) (AIComplet
 	}
 	response, err := a.providerManager.GetCompletion(ctx, provider, model, prompt, apiKey)
 	
-	// Convert providers.AICompletionResponse to our frontend-compatible format
-	frontendResponse := AICompletionResponse{
-		Content: response.Content,
-		Error:   response.Error,
+	return toFrontendResponse(response), err
+}
+
+// CompletionRequest is a completion issued on behalf of a canvas node
+type CompletionRequest struct {
+	// Preset names a model preset supplying provider, model, parameters and system prompt
+	Preset     string `json:"preset,omitempty"`
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	Prompt     string `json:"prompt"`
+	APIKey     string `json:"apiKey"`
+	CanvasPath string `json:"canvasPath,omitempty"`
+	NodeID     string `json:"nodeId,omitempty"`
+	// Template, when set, is rendered with Variables to build the prompt instead of using Prompt
+	Template  string                 `json:"template,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	// Options sets per-request behaviour such as reasoning effort or thinking budget
+	Options providers.CompletionOptions `json:"options"`
+}
+
+// RequestAICompletion performs AI completion for a canvas node, passing the canvas
+// path and node ID along so interceptors such as the audit log can record them
+func (a *App) RequestAICompletion(request CompletionRequest) (AICompletionResponse, error) {
+	if request.Template != "" {
+		prompt, err := a.current().promptEngine.Render(request.Template, request.Variables)
+		if err != nil {
+			return AICompletionResponse{Error: err.Error()}, err
+		}
+		request.Prompt = prompt
 	}
-	
-	return frontendResponse, err
+
+	if request.Preset != "" {
+		resolved, err := a.applyPreset(request)
+		if err != nil {
+			return AICompletionResponse{Error: err.Error()}, err
+		}
+		request = resolved
+	}
+
+	response, err := a.providerManager.Complete(a.requestContext(), providers.CompletionRequest{
+		Provider: request.Provider,
+		Model:    request.Model,
+		Prompt:   request.Prompt,
+		APIKey:   request.APIKey,
+		Options:  request.Options,
+		Metadata: map[string]string{
+			providers.MetadataCanvasPath: request.CanvasPath,
+			providers.MetadataNodeID:     request.NodeID,
+		},
+	})
+
+	return toFrontendResponse(response), err
+}
+
+// toFrontendResponse converts providers.AICompletionResponse to our frontend-compatible format
+func toFrontendResponse(response providers.AICompletionResponse) AICompletionResponse {
+	return AICompletionResponse{
+		Content:       response.Content,
+		Reasoning:     response.Reasoning,
+		Error:         response.Error,
+		Timings:       response.Timings,
+		Usage:         response.Usage,
+		FinishReason:  response.FinishReason,
+		SafetyRatings: response.SafetyRatings,
+		Continuations: response.Continuations,
+		LatencyMs:     response.LatencyMs,
+		Truncation:    response.Truncation,
+	}
+}
+
+// GetPromptBudgetConfig returns how prompts are fitted to model context windows
+func (a *App) GetPromptBudgetConfig() tokenizer.BudgetConfig {
+	return a.providerManager.GetBudgetConfig()
+}
+
+// SetPromptBudgetConfig sets the truncation strategy and output reserve used
+// for prompts and saves them in the settings
+func (a *App) SetPromptBudgetConfig(config tokenizer.BudgetConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	return a.changeSettings(func(settings *models.Settings) {
+		settings.PromptBudget = config
+	})
+}
+
+// EstimatePromptTokens counts a prompt's tokens against the model's context window
+func (a *App) EstimatePromptTokens(provider, model, prompt string) providers.PromptEstimate {
+	return a.providerManager.EstimatePrompt(a.requestContext(), provider, model, prompt)
 }
 
 // Canvas File Operations
@@ -116,8 +499,13 @@ func (a *App) SaveCanvas(canvasData string) models.CanvasFileResult {
 	result := a.canvasStorage.SaveCanvas(canvasData)
 	
 	// Add to recents if successful and recents storage is available
-	if result.Success && result.Path != "" && a.recentsStorage != nil {
-		a.recentsStorage.AddToRecentCanvases(result.Path)
+	if result.Success {
+		a.addToRecents(result.Path)
+	}
+	
+	// The saved file now holds the journalled changes
+	if result.Success && a.autosave != nil {
+		a.autosave.DiscardPath(result.Path)
 	}
 	
 	return result
@@ -128,8 +516,8 @@ func (a *App) LoadCanvas() models.CanvasFileResult {
 	result := a.canvasStorage.LoadCanvas()
 	
 	// Add to recents if successful and recents storage is available
-	if result.Success && result.Path != "" && a.recentsStorage != nil {
-		a.recentsStorage.AddToRecentCanvases(result.Path)
+	if result.Success {
+		a.addToRecents(result.Path)
 	}
 	
 	return result
@@ -140,20 +528,27 @@ func (a *App) LoadCanvasFromPath(filePath string) models.CanvasFileResult {
 	result := a.canvasStorage.LoadCanvasFromPath(filePath)
 	
 	// Add to recents if successful and recents storage is available
-	if result.Success && result.Path != "" && a.recentsStorage != nil {
-		a.recentsStorage.AddToRecentCanvases(result.Path)
+	if result.Success {
+		a.addToRecents(result.Path)
 	}
 	
 	return result
 }
 
+// ValidateCanvas checks canvas data without saving it
+func (a *App) ValidateCanvas(canvasData string) canvas.Report {
+	_, report := canvas.Check([]byte(canvasData))
+	return report
+}
+
 // GetRecentCanvases returns the list of recent canvas files
 func (a *App) GetRecentCanvases() models.RecentCanvasesResult {
-	if a.recentsStorage == nil {
+	recentsStorage := a.current().recentsStorage
+	if recentsStorage == nil {
 		return models.RecentCanvasesResult{Success: false, Error: "Recents storage not available"}
 	}
 	
-	return a.recentsStorage.GetRecentCanvases()
+	return recentsStorage.GetRecentCanvases()
 }
 
 // Clipboard Operations
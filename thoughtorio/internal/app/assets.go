@@ -0,0 +1,63 @@
+package app
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/storage"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// newAssetStore opens the shared store canvas pack assets are unpacked into
+func newAssetStore() (*storage.AssetStore, error) {
+	configDir, err := storage.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewAssetStore(filepath.Join(configDir, "assets"))
+}
+
+// assetStore returns the canvas asset store, or an error when there is none
+func (a *App) assetStore() (*storage.AssetStore, error) {
+	if a.canvasStorage == nil || a.canvasStorage.Assets() == nil {
+		return nil, fmt.Errorf("asset storage not available")
+	}
+	return a.canvasStorage.Assets(), nil
+}
+
+// ImportCanvasAsset shows an open dialog and adds the chosen file to the asset
+// store. Canvases refer to it by the returned Ref and carry it when saved as a pack.
+func (a *App) ImportCanvasAsset() (models.CanvasAsset, error) {
+	filePath, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{Title: "Attach File"})
+	if err != nil {
+		return models.CanvasAsset{}, fmt.Errorf("failed to open file dialog: %w", err)
+	}
+	if filePath == "" {
+		return models.CanvasAsset{}, fmt.Errorf("import cancelled by user")
+	}
+	return a.ImportCanvasAssetFromPath(filePath)
+}
+
+// ImportCanvasAssetFromPath adds a file to the asset store
+func (a *App) ImportCanvasAssetFromPath(filePath string) (models.CanvasAsset, error) {
+	store, err := a.assetStore()
+	if err != nil {
+		return models.CanvasAsset{}, err
+	}
+	return store.Import(filePath)
+}
+
+// GetCanvasAssetURL returns an asset as a data URL the frontend can display
+func (a *App) GetCanvasAssetURL(ref string) (string, error) {
+	store, err := a.assetStore()
+	if err != nil {
+		return "", err
+	}
+	data, err := store.Read(ref)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + storage.AssetMediaType(ref) + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"thoughtorio/internal/audit"
+	"thoughtorio/internal/storage"
+)
+
+// newAuditLogger opens the audit log kept in the app's config directory
+func newAuditLogger() (*audit.Logger, error) {
+	configDir, err := storage.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return audit.NewLogger(filepath.Join(configDir, "audit"))
+}
+
+// GetAuditConfig returns the audit log configuration
+func (a *App) GetAuditConfig() (audit.Config, error) {
+	if a.auditLogger == nil {
+		return audit.Config{}, fmt.Errorf("audit log not available")
+	}
+	return a.auditLogger.GetConfig(), nil
+}
+
+// SetAuditConfig enables or disables the audit log and sets strict mode and rotation size
+func (a *App) SetAuditConfig(config audit.Config) error {
+	if a.auditLogger == nil {
+		return fmt.Errorf("audit log not available")
+	}
+	return a.auditLogger.SetConfig(config)
+}
+
+// QueryAuditLog returns recorded AI requests filtered by date range, provider or canvas
+func (a *App) QueryAuditLog(query audit.Query) ([]audit.Entry, error) {
+	if a.auditLogger == nil {
+		return nil, fmt.Errorf("audit log not available")
+	}
+	return a.auditLogger.Query(query)
+}
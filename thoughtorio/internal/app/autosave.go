@@ -0,0 +1,159 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"thoughtorio/internal/autosave"
+	"thoughtorio/internal/canvas"
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/storage"
+)
+
+// newAutosaveJournal opens the recovery journal in the config directory
+func newAutosaveJournal() (*autosave.Journal, error) {
+	configDir, err := storage.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return autosave.NewJournal(filepath.Join(configDir, "recovery"), autosave.DefaultDelay)
+}
+
+// AutosaveCanvas records the current state of a session's canvas in the
+// recovery journal. canvasPath is empty for untitled canvases. Writes are
+// debounced, so the frontend can call this on every change.
+func (a *App) AutosaveCanvas(sessionID, canvasPath, canvasData string) error {
+	if a.autosave == nil {
+		return fmt.Errorf("autosave not available")
+	}
+	if sessionID == "" {
+		return fmt.Errorf("a session ID is required")
+	}
+	a.autosave.Submit(sessionID, canvasPath, canvasData)
+	return nil
+}
+
+// DiscardAutosave drops a session's journalled changes, as after it saves or
+// starts over with a new canvas
+func (a *App) DiscardAutosave(sessionID string) error {
+	if a.autosave == nil {
+		return fmt.Errorf("autosave not available")
+	}
+	return a.autosave.DiscardSession(sessionID)
+}
+
+// GetRecoverableSessions lists canvases with changes that were never saved,
+// most recent first, each with a summary of how it differs from the saved file.
+// Entries that match their file, or untitled canvases left empty, are dropped.
+func (a *App) GetRecoverableSessions() ([]models.RecoverySession, error) {
+	if a.autosave == nil {
+		return nil, fmt.Errorf("autosave not available")
+	}
+	// Include changes still waiting to be written, as when only the webview crashed
+	if err := a.autosave.Flush(); err != nil {
+		log.Printf("failed to flush recovery journal: %v", err)
+	}
+
+	entries, err := a.autosave.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []models.RecoverySession{}
+	for _, entry := range entries {
+		session, worthKeeping := recoverySession(entry)
+		if !worthKeeping {
+			if err := a.autosave.Discard(entry.Key); err != nil {
+				log.Printf("failed to drop recovery entry %s: %v", entry.Key, err)
+			}
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// recoverySession describes a journal entry, reporting whether it holds
+// anything that isn't already in the saved file
+func recoverySession(entry autosave.Entry) (models.RecoverySession, bool) {
+	session := models.RecoverySession{
+		Key:        entry.Key,
+		SessionID:  entry.SessionID,
+		CanvasPath: entry.CanvasPath,
+		Untitled:   entry.Untitled(),
+		SavedAt:    entry.SavedAt,
+		Name:       "Untitled canvas",
+	}
+
+	journalled, _, report := canvas.Load([]byte(entry.Data))
+	if report.Err() != nil {
+		return session, false
+	}
+
+	var saved *canvas.Document
+	if !entry.Untitled() {
+		session.Name = strings.TrimSuffix(filepath.Base(entry.CanvasPath), filepath.Ext(entry.CanvasPath))
+		info, err := os.Stat(entry.CanvasPath)
+		if err != nil {
+			session.FileMissing = true
+		} else {
+			session.FileModifiedAt = info.ModTime().UnixMilli()
+			if data, err := storage.ReadCanvasFile(entry.CanvasPath); err == nil {
+				saved, _, _ = canvas.Load(data)
+			}
+		}
+	}
+
+	diff := canvas.Compare(saved, journalled)
+	session.Changes = diff.Summary()
+	if entry.Untitled() {
+		return session, len(journalled.Nodes) > 0
+	}
+	return session, !diff.Empty() || session.FileMissing
+}
+
+// RecoverSession returns the journalled canvas of a recovery entry and hands
+// the entry to sessionID, which keeps autosaving to it until the canvas is saved
+func (a *App) RecoverSession(key, sessionID string) models.CanvasFileResult {
+	if a.autosave == nil {
+		return models.CanvasFileResult{Success: false, Error: "Autosave not available"}
+	}
+
+	entry, err := a.autosave.Entry(key)
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Error: err.Error()}
+	}
+
+	doc, migration, report := canvas.Load([]byte(entry.Data))
+	if err := report.Err(); err != nil {
+		return models.CanvasFileResult{Success: false, Error: err.Error(), Validation: &report}
+	}
+	data, err := canvas.Marshal(doc)
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Error: fmt.Sprintf("Failed to encode canvas: %v", err)}
+	}
+
+	result := models.CanvasFileResult{Success: true, Path: entry.CanvasPath, Data: string(data), Validation: &report, Migration: migration, Document: doc}
+	if sessionID != "" {
+		a.autosave.Adopt(key, sessionID)
+
+		// The session saves back to the file as it is now, and the recovered changes aren't in it yet
+		if !entry.Untitled() {
+			result.Revision, _ = storage.FileRevision(entry.CanvasPath)
+		}
+		a.trackDocument(sessionID, entry.CanvasPath, result.Revision)
+		a.SetCanvasDirty(sessionID, true)
+	}
+	return result
+}
+
+// DiscardRecoverySession drops a recovery entry the user chose not to recover
+func (a *App) DiscardRecoverySession(key string) error {
+	if a.autosave == nil {
+		return fmt.Errorf("autosave not available")
+	}
+	return a.autosave.Discard(key)
+}
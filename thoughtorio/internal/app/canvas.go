@@ -0,0 +1,36 @@
+package app
+
+import (
+	"fmt"
+
+	"thoughtorio/internal/models"
+)
+
+// applyCanvasSettings passes canvas file settings on to canvas storage
+func (a *App) applyCanvasSettings(settings models.Settings) {
+	if a.canvasStorage != nil {
+		a.canvasStorage.SetBackupGenerations(settings.CanvasBackups)
+	}
+}
+
+// ListCanvasBackups returns the backups kept beside a canvas file, newest save first
+func (a *App) ListCanvasBackups(canvasPath string) ([]models.CanvasBackup, error) {
+	if a.canvasStorage == nil {
+		return nil, fmt.Errorf("canvas storage not available")
+	}
+	return a.canvasStorage.ListBackups(canvasPath)
+}
+
+// RestoreCanvasBackup replaces a canvas file with one of its backups and returns
+// the restored canvas. The replaced version is kept as the newest backup.
+func (a *App) RestoreCanvasBackup(canvasPath, backupPath string) models.CanvasFileResult {
+	if a.canvasStorage == nil {
+		return models.CanvasFileResult{Success: false, Error: "Canvas storage not available"}
+	}
+
+	result := a.canvasStorage.RestoreBackup(canvasPath, backupPath)
+	if result.Success {
+		a.addToRecents(result.Path)
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"thoughtorio/internal/providers"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Events emitted while a comparison runs
+const (
+	CompareStartedEvent = "compare:started"
+	CompareChunkEvent   = "compare:chunk"
+)
+
+// CompareStarted announces a comparison run before any lane produces output
+type CompareStarted struct {
+	ComparisonID string                    `json:"comparisonId"`
+	Prompt       string                    `json:"prompt"`
+	Targets      []providers.CompareTarget `json:"targets"`
+}
+
+// CompareChunk is a piece of streamed output for one lane of a comparison
+type CompareChunk struct {
+	ComparisonID string `json:"comparisonId"`
+	Lane         int    `json:"lane"`
+	Chunk        string `json:"chunk"`
+}
+
+// ComparisonResult holds the side-by-side results of a comparison run
+type ComparisonResult struct {
+	ComparisonID string                  `json:"comparisonId"`
+	Prompt       string                  `json:"prompt"`
+	Lanes        []providers.CompareLane `json:"lanes"`
+	StartedAt    time.Time               `json:"startedAt"`
+	DurationMs   float64                 `json:"durationMs"`
+}
+
+// CompareCompletions sends the same prompt to several provider/model pairs at
+// once. Each lane streams independently as CompareChunkEvent events; the
+// returned result holds every lane's output, latency, usage and error.
+// canvasPath attributes the lanes to a canvas for audit, budgets and credentials.
+func (a *App) CompareCompletions(canvasPath, prompt string, targets []providers.CompareTarget) (ComparisonResult, error) {
+	if len(targets) == 0 {
+		return ComparisonResult{}, fmt.Errorf("at least one comparison target is required")
+	}
+
+	id, err := newComparisonID()
+	if err != nil {
+		return ComparisonResult{}, err
+	}
+
+	ctx, cancel := context.WithCancel(a.requestContext())
+	a.comparisonsMu.Lock()
+	a.comparisons[id] = cancel
+	a.comparisonsMu.Unlock()
+
+	defer func() {
+		cancel()
+		a.comparisonsMu.Lock()
+		delete(a.comparisons, id)
+		a.comparisonsMu.Unlock()
+	}()
+
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, CompareStartedEvent, CompareStarted{ComparisonID: id, Prompt: prompt, Targets: targets})
+	}
+
+	start := time.Now()
+	metadata := map[string]string{providers.MetadataCanvasPath: canvasPath}
+	lanes, err := a.providerManager.CompareCompletions(ctx, prompt, targets, metadata, func(lane int, chunk string) {
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, CompareChunkEvent, CompareChunk{ComparisonID: id, Lane: lane, Chunk: chunk})
+		}
+	})
+	if err != nil {
+		return ComparisonResult{}, err
+	}
+
+	return ComparisonResult{
+		ComparisonID: id,
+		Prompt:       prompt,
+		Lanes:        lanes,
+		StartedAt:    start,
+		DurationMs:   float64(time.Since(start)) / float64(time.Millisecond),
+	}, nil
+}
+
+// CancelComparison cancels every lane of a running comparison
+func (a *App) CancelComparison(comparisonID string) bool {
+	a.comparisonsMu.Lock()
+	defer a.comparisonsMu.Unlock()
+
+	cancel, running := a.comparisons[comparisonID]
+	if running {
+		cancel()
+	}
+	return running
+}
+
+// newComparisonID returns a random identifier used to tag comparison events
+func newComparisonID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate comparison ID: %w", err)
+	}
+	return "cmp-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"log"
+
+	"thoughtorio/internal/credentials"
+	"thoughtorio/internal/providers"
+	"thoughtorio/internal/secrets"
+)
+
+// newCredentialResolver layers stored secrets, the environment and canvas .env files under explicit keys
+func newCredentialResolver(service *secrets.Service) *credentials.Resolver {
+	if service == nil {
+		return credentials.NewResolver(nil)
+	}
+	return credentials.NewResolver(service)
+}
+
+// resolveCredentials is the provider manager's credential resolver
+func (a *App) resolveCredentials(provider, apiKey, canvasPath string) (providers.Credentials, error) {
+	resolved, err := a.current().credentials.Resolve(provider, apiKey, canvasPath)
+	if err != nil {
+		return providers.Credentials{}, err
+	}
+	return providers.Credentials{APIKey: resolved.APIKey, Endpoint: resolved.Endpoint}, nil
+}
+
+// applyOllamaHost points the Ollama provider at OLLAMA_HOST when it is set, so
+// model management calls outside a completion use it too
+func (a *App) applyOllamaHost() {
+	resolved, err := a.current().credentials.Resolve("local", "", "")
+	if err != nil || resolved.Endpoint == "" {
+		return
+	}
+	ollama, err := a.providerManager.GetOllamaProvider()
+	if err != nil {
+		return
+	}
+	if err := ollama.SetHost(resolved.Endpoint); err != nil {
+		log.Printf("ignoring OLLAMA_HOST: %v", err)
+	}
+}
+
+// GetCredentialSources reports where each provider's API key and endpoint come
+// from: the key passed in keys (by provider name), a stored secret, the
+// environment or, for keys, a .env file next to canvasPath. Key values are
+// never returned.
+func (a *App) GetCredentialSources(canvasPath string, keys map[string]string) []credentials.Source {
+	return a.current().credentials.Report(a.providerManager.ListProviders(), keys, canvasPath)
+}
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/storage"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// openDocument is the file a window's canvas was loaded from or saved to
+type openDocument struct {
+	path string
+	// revision is the file's contents as this window last saw them
+	revision string
+	dirty    bool
+}
+
+// newViewStore opens the store of canvas viewports in the config directory
+func newViewStore() (*storage.ViewStore, error) {
+	configDir, err := storage.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewViewStore(filepath.Join(configDir, "views"))
+}
+
+// document returns the tracked document of a session, creating an untitled one
+func (a *App) document(sessionID string) *openDocument {
+	doc := a.documents[sessionID]
+	if doc == nil {
+		doc = &openDocument{}
+		a.documents[sessionID] = doc
+	}
+	return doc
+}
+
+// trackDocument records the file a session's canvas now matches on disk
+func (a *App) trackDocument(sessionID, path, revision string) {
+	a.documentsMu.Lock()
+	defer a.documentsMu.Unlock()
+
+	doc := a.document(sessionID)
+	doc.path = path
+	doc.revision = revision
+	doc.dirty = false
+}
+
+// addToRecents records a canvas file in the recents list when recents storage is available
+func (a *App) addToRecents(path string) {
+	if recentsStorage := a.current().recentsStorage; path != "" && recentsStorage != nil {
+		recentsStorage.AddToRecentCanvases(path)
+	}
+}
+
+// OpenCanvas shows an open dialog and loads the chosen canvas into a session,
+// which then saves back to that file
+func (a *App) OpenCanvas(sessionID string) models.CanvasFileResult {
+	return a.openCanvas(sessionID, a.canvasStorage.LoadCanvas())
+}
+
+// OpenCanvasFromPath loads a canvas file into a session, which then saves back to it
+func (a *App) OpenCanvasFromPath(sessionID, filePath string) models.CanvasFileResult {
+	return a.openCanvas(sessionID, a.canvasStorage.LoadCanvasFromPath(filePath))
+}
+
+func (a *App) openCanvas(sessionID string, result models.CanvasFileResult) models.CanvasFileResult {
+	if !result.Success {
+		return result
+	}
+	a.trackDocument(sessionID, result.Path, result.Revision)
+	a.addToRecents(result.Path)
+	return result
+}
+
+// SaveCanvasToPath saves a session's canvas without a dialog. An empty filePath
+// saves to the session's current file. The save is refused with Conflict set
+// when the file was changed or removed on disk since the session loaded or last
+// saved it, unless overwrite is set.
+func (a *App) SaveCanvasToPath(sessionID, filePath, canvasData string, overwrite bool) models.CanvasFileResult {
+	a.documentsMu.Lock()
+	doc := a.document(sessionID)
+	if filePath == "" {
+		filePath = doc.path
+	}
+	known := filepath.Clean(filePath) == filepath.Clean(doc.path)
+	revision := doc.revision
+	a.documentsMu.Unlock()
+
+	if filePath == "" {
+		return models.CanvasFileResult{Success: false, Error: "The canvas hasn't been saved yet; use Save As"}
+	}
+
+	if !overwrite {
+		if conflict := saveConflict(filePath, known, revision); conflict != "" {
+			return models.CanvasFileResult{Success: false, Path: filePath, Error: conflict, Conflict: true}
+		}
+	}
+
+	return a.savedCanvas(sessionID, a.canvasStorage.SaveCanvasToPath(filePath, canvasData))
+}
+
+// saveConflict explains why saving over filePath would lose changes made
+// outside this session, or returns "" when it is safe
+func saveConflict(filePath string, known bool, revision string) string {
+	current, err := storage.FileRevision(filePath)
+	switch {
+	case os.IsNotExist(err):
+		if known {
+			return "The file was moved or deleted since it was opened"
+		}
+		return ""
+	case err != nil:
+		return fmt.Sprintf("Failed to check the file before saving: %v", err)
+	case !known:
+		return "A different file already exists at this path"
+	case current != revision:
+		return "The file was changed on disk since it was opened"
+	}
+	return ""
+}
+
+// SaveCanvasAs shows a save dialog, starting at the session's current file,
+// and saves the canvas there. The session then saves to the chosen file.
+func (a *App) SaveCanvasAs(sessionID, canvasData string) models.CanvasFileResult {
+	a.documentsMu.Lock()
+	current := a.document(sessionID).path
+	a.documentsMu.Unlock()
+
+	return a.savedCanvas(sessionID, a.canvasStorage.SaveCanvasAs(canvasData, current))
+}
+
+// savedCanvas records a successful save against the session
+func (a *App) savedCanvas(sessionID string, result models.CanvasFileResult) models.CanvasFileResult {
+	if !result.Success {
+		return result
+	}
+	a.trackDocument(sessionID, result.Path, result.Revision)
+	a.addToRecents(result.Path)
+
+	// The saved file now holds the journalled changes
+	if a.autosave != nil {
+		a.autosave.DiscardPath(result.Path)
+		a.autosave.DiscardSession(sessionID)
+	}
+	return result
+}
+
+// SetCanvasDirty records whether a session's canvas has unsaved changes
+func (a *App) SetCanvasDirty(sessionID string, dirty bool) {
+	a.documentsMu.Lock()
+	defer a.documentsMu.Unlock()
+	a.document(sessionID).dirty = dirty
+}
+
+// GetCanvasDocument returns the file a session's canvas is saved to and whether it has unsaved changes
+func (a *App) GetCanvasDocument(sessionID string) models.CanvasDocument {
+	a.documentsMu.Lock()
+	defer a.documentsMu.Unlock()
+
+	doc := a.document(sessionID)
+	name := "Untitled canvas"
+	if doc.path != "" {
+		name = strings.TrimSuffix(filepath.Base(doc.path), filepath.Ext(doc.path))
+	}
+	return models.CanvasDocument{SessionID: sessionID, Path: doc.path, Name: name, Revision: doc.revision, Dirty: doc.dirty}
+}
+
+// CloseCanvas forgets a session's file, as when it starts a new canvas
+func (a *App) CloseCanvas(sessionID string) {
+	a.documentsMu.Lock()
+	defer a.documentsMu.Unlock()
+	delete(a.documents, sessionID)
+}
+
+// hasUnsavedCanvases reports whether any session has unsaved changes
+func (a *App) hasUnsavedCanvases() bool {
+	a.documentsMu.Lock()
+	defer a.documentsMu.Unlock()
+	for _, doc := range a.documents {
+		if doc.dirty {
+			return true
+		}
+	}
+	return false
+}
+
+// BeforeClose asks before quitting with unsaved changes; returning true keeps the window open
+func (a *App) BeforeClose(ctx context.Context) bool {
+	if !a.hasUnsavedCanvases() {
+		return false
+	}
+	// Windows and Linux ignore custom buttons on question dialogs and always
+	// answer "Yes" or "No", so the question is worded for those
+	choice, err := a.messageDialog(ctx, runtime.MessageDialogOptions{
+		Type:          runtime.QuestionDialog,
+		Title:         "Unsaved changes",
+		Message:       "The canvas has unsaved changes. Quit anyway? They can be recovered the next time Thoughtorio starts.",
+		Buttons:       []string{"Yes", "No"},
+		DefaultButton: "No",
+		CancelButton:  "No",
+	})
+	if err != nil {
+		log.Printf("failed to ask about unsaved changes: %v", err)
+		return false
+	}
+	return choice != "Yes"
+}
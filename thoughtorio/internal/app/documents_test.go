@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"thoughtorio/internal/canvas"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+func TestBeforeCloseAsksAboutUnsavedChanges(t *testing.T) {
+	a := newTestApp(t)
+	asked := 0
+	answer, answerErr := "", error(nil)
+	a.messageDialog = func(ctx context.Context, options runtime.MessageDialogOptions) (string, error) {
+		asked++
+		if options.DefaultButton != "No" || options.CancelButton != "No" {
+			t.Errorf("dialog options = %+v, want No as the default and cancel", options)
+		}
+		return answer, answerErr
+	}
+	beforeClose := func() bool { return a.BeforeClose(context.Background()) }
+
+	// Nothing unsaved quits without asking
+	a.SetCanvasDirty("window-1", false)
+	if beforeClose() || asked != 0 {
+		t.Fatalf("BeforeClose with nothing unsaved kept the window or asked %d times", asked)
+	}
+
+	a.SetCanvasDirty("window-1", true)
+	answer = "No"
+	if !beforeClose() || asked != 1 {
+		t.Errorf("BeforeClose answered No = quit, asked %d times; want the window kept", asked)
+	}
+	answer = "Yes"
+	if beforeClose() {
+		t.Error("BeforeClose answered Yes kept the window")
+	}
+	// A dialog that can't be shown must not trap the user in the app
+	answerErr = errors.New("no dialog")
+	if beforeClose() {
+		t.Error("BeforeClose kept the window when the dialog failed")
+	}
+	answerErr = nil
+
+	// Saving the canvas clears its unsaved changes
+	data, err := canvas.MarshalCanonical(&canvas.Document{FormatVersion: canvas.FormatVersion, Version: "1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+	if result := a.SaveCanvasToPath("window-1", path, string(data), false); !result.Success {
+		t.Fatalf("SaveCanvasToPath failed: %s", result.Error)
+	}
+	asked = 0
+	if beforeClose() || asked != 0 {
+		t.Errorf("BeforeClose after saving kept the window or asked %d times", asked)
+	}
+
+	// Any window with unsaved changes asks, until it is closed
+	a.SetCanvasDirty("window-2", true)
+	answer = "No"
+	if !beforeClose() {
+		t.Error("BeforeClose with a second window unsaved quit")
+	}
+	a.CloseCanvas("window-2")
+	if beforeClose() {
+		t.Error("BeforeClose after closing the unsaved canvas kept the window")
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/storage"
+)
+
+// newHistoryStore opens the store of canvas versions in the config directory
+func newHistoryStore() (*storage.HistoryStore, error) {
+	configDir, err := storage.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewHistoryStore(filepath.Join(configDir, "history"))
+}
+
+// ListCanvasVersions returns the versions kept of a canvas file, newest first.
+// A version is kept every time the canvas is saved with changes.
+func (a *App) ListCanvasVersions(canvasPath string) ([]models.CanvasVersion, error) {
+	if a.canvasStorage == nil {
+		return nil, fmt.Errorf("canvas storage not available")
+	}
+	return a.canvasStorage.ListVersions(canvasPath)
+}
+
+// DiffCanvasVersions compares two versions of a canvas: the nodes and
+// connections added, removed and changed, with the fields and text that
+// changed. Version 0 stands for an empty canvas.
+func (a *App) DiffCanvasVersions(canvasPath string, from, to int) (models.CanvasVersionDiff, error) {
+	if a.canvasStorage == nil {
+		return models.CanvasVersionDiff{}, fmt.Errorf("canvas storage not available")
+	}
+	return a.canvasStorage.DiffVersions(canvasPath, from, to)
+}
+
+// RestoreCanvasVersion replaces a canvas file with an earlier version and
+// loads it into the session. Like a save, the restore is refused with Conflict
+// set when the session has unsaved changes or the file changed on disk since
+// the session loaded it, unless overwrite is set.
+func (a *App) RestoreCanvasVersion(sessionID, canvasPath string, number int, overwrite bool) models.CanvasFileResult {
+	if a.canvasStorage == nil {
+		return models.CanvasFileResult{Success: false, Error: "Canvas storage not available"}
+	}
+	if !overwrite {
+		if conflict := a.versionConflict(sessionID, canvasPath); conflict != "" {
+			return models.CanvasFileResult{Success: false, Path: canvasPath, Error: conflict, Conflict: true}
+		}
+	}
+	return a.openCanvas(sessionID, a.canvasStorage.RestoreVersion(canvasPath, number))
+}
+
+// BranchCanvasVersion saves a version of a canvas as a new file, chosen in a
+// save dialog, and loads it into the session. It is refused with Conflict set
+// when the session has unsaved changes, unless overwrite is set.
+func (a *App) BranchCanvasVersion(sessionID, canvasPath string, number int, overwrite bool) models.CanvasFileResult {
+	if a.canvasStorage == nil {
+		return models.CanvasFileResult{Success: false, Error: "Canvas storage not available"}
+	}
+	if !overwrite {
+		if conflict := a.versionConflict(sessionID, ""); conflict != "" {
+			return models.CanvasFileResult{Success: false, Path: canvasPath, Error: conflict, Conflict: true}
+		}
+	}
+	return a.openCanvas(sessionID, a.canvasStorage.BranchVersion(canvasPath, number))
+}
+
+// versionConflict explains why loading a version into a session would lose
+// changes, or returns "" when it is safe. filePath is the file the version is
+// written over, if any; the dialog asks before a branch replaces a file.
+func (a *App) versionConflict(sessionID, filePath string) string {
+	a.documentsMu.Lock()
+	doc := a.document(sessionID)
+	dirty := doc.dirty
+	known := filePath != "" && filepath.Clean(filePath) == filepath.Clean(doc.path)
+	revision := doc.revision
+	a.documentsMu.Unlock()
+
+	if dirty {
+		return "The canvas has unsaved changes"
+	}
+	// A file the session didn't load is kept in the history before the restore
+	if known {
+		return saveConflict(filePath, true, revision)
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/providers"
+	"thoughtorio/internal/storage"
+)
+
+// GetModelPresets returns all named model presets
+func (a *App) GetModelPresets() models.PresetsResult {
+	presetStorage := a.current().presetStorage
+	if presetStorage == nil {
+		return models.PresetsResult{Success: false, Error: "Preset storage not available"}
+	}
+
+	return presetStorage.GetPresets()
+}
+
+// SaveModelPreset creates a preset or replaces the one with the same name
+func (a *App) SaveModelPreset(preset models.ModelPreset) error {
+	presetStorage := a.current().presetStorage
+	if presetStorage == nil {
+		return fmt.Errorf("preset storage not available")
+	}
+	if err := a.checkPreset(preset); err != nil {
+		return err
+	}
+	return presetStorage.SavePreset(preset)
+}
+
+// DeleteModelPreset removes a preset
+func (a *App) DeleteModelPreset(name string) error {
+	presetStorage := a.current().presetStorage
+	if presetStorage == nil {
+		return fmt.Errorf("preset storage not available")
+	}
+	return presetStorage.DeletePreset(name)
+}
+
+// ExportModelPresets returns the named presets, or all when names is empty, as JSON for sharing
+func (a *App) ExportModelPresets(names []string) (string, error) {
+	presetStorage := a.current().presetStorage
+	if presetStorage == nil {
+		return "", fmt.Errorf("preset storage not available")
+	}
+	data, err := presetStorage.ExportPresets(names)
+	return string(data), err
+}
+
+// ImportModelPresets adds presets from exported JSON, replacing same-named presets
+// only when overwrite is set, and returns the names that were imported
+func (a *App) ImportModelPresets(data string, overwrite bool) ([]string, error) {
+	presetStorage := a.current().presetStorage
+	if presetStorage == nil {
+		return nil, fmt.Errorf("preset storage not available")
+	}
+	return presetStorage.ImportPresets([]byte(data), overwrite)
+}
+
+// ValidateModelPreset checks a stored preset against its provider: the provider
+// must be registered, the parameters valid and the model offered by FetchModels
+func (a *App) ValidateModelPreset(name, apiKey, canvasPath string) models.PresetValidation {
+	validation := models.PresetValidation{Name: name}
+
+	preset, err := a.resolvePreset(name)
+	if err != nil {
+		validation.Errors = append(validation.Errors, err.Error())
+		return validation
+	}
+	if err := a.checkPreset(preset); err != nil {
+		validation.Errors = append(validation.Errors, err.Error())
+		return validation
+	}
+
+	available, err := a.providerManager.FetchModels(a.requestContext(), preset.Provider, apiKey, canvasPath)
+	if err != nil {
+		validation.Errors = append(validation.Errors, fmt.Sprintf("could not list %s models: %v", preset.Provider, err))
+		return validation
+	}
+
+	found := false
+	for _, model := range available {
+		if model.ID == preset.Model {
+			found = true
+			break
+		}
+	}
+	if !found {
+		validation.Errors = append(validation.Errors, fmt.Sprintf("model %q is not offered by %s", preset.Model, preset.Provider))
+		return validation
+	}
+
+	validation.Valid = true
+	return validation
+}
+
+// resolvePreset looks up a preset by name
+func (a *App) resolvePreset(name string) (models.ModelPreset, error) {
+	presetStorage := a.current().presetStorage
+	if presetStorage == nil {
+		return models.ModelPreset{}, fmt.Errorf("preset storage not available")
+	}
+	return presetStorage.GetPreset(name)
+}
+
+// checkPreset validates a preset's fields, provider and parameters without contacting the provider
+func (a *App) checkPreset(preset models.ModelPreset) error {
+	if err := storage.ValidatePreset(preset); err != nil {
+		return err
+	}
+	if _, err := a.providerManager.GetProvider(preset.Provider); err != nil {
+		return err
+	}
+	return presetOptions(preset).Validate()
+}
+
+// presetOptions converts a preset's parameters into completion options
+func presetOptions(preset models.ModelPreset) providers.CompletionOptions {
+	return providers.CompletionOptions{
+		SystemPrompt:    preset.SystemPrompt,
+		ReasoningEffort: preset.Parameters.ReasoningEffort,
+		ThinkingBudget:  preset.Parameters.ThinkingBudget,
+		MaxTokens:       preset.Parameters.MaxTokens,
+		Temperature:     preset.Parameters.Temperature,
+		TopP:            preset.Parameters.TopP,
+		AutoContinue:    preset.Parameters.AutoContinue,
+	}
+}
+
+// applyPreset fills a completion request from the named preset. Options set on
+// the request take precedence over the preset's parameters.
+func (a *App) applyPreset(request CompletionRequest) (CompletionRequest, error) {
+	preset, err := a.resolvePreset(request.Preset)
+	if err != nil {
+		return request, err
+	}
+
+	request.Provider = preset.Provider
+	request.Model = preset.Model
+
+	options := presetOptions(preset)
+	if request.Options.SystemPrompt != "" {
+		options.SystemPrompt = request.Options.SystemPrompt
+	}
+	if request.Options.ReasoningEffort != "" {
+		options.ReasoningEffort = request.Options.ReasoningEffort
+	}
+	if request.Options.ThinkingBudget > 0 {
+		options.ThinkingBudget = request.Options.ThinkingBudget
+	}
+	if request.Options.MaxTokens > 0 {
+		options.MaxTokens = request.Options.MaxTokens
+	}
+	if request.Options.Temperature != nil {
+		options.Temperature = request.Options.Temperature
+	}
+	if request.Options.TopP != nil {
+		options.TopP = request.Options.TopP
+	}
+	options.AutoContinue = options.AutoContinue || request.Options.AutoContinue
+	options.ContinueBudget = request.Options.ContinueBudget
+	options.MaxContinuations = request.Options.MaxContinuations
+	request.Options = options
+
+	return request, nil
+}
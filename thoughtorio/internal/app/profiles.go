@@ -0,0 +1,212 @@
+package app
+
+import (
+	"fmt"
+	"log"
+
+	"thoughtorio/internal/credentials"
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/prompts"
+	"thoughtorio/internal/secrets"
+	"thoughtorio/internal/spending"
+	"thoughtorio/internal/storage"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ProfileChangedEvent is emitted with the new profile after switching profiles
+const ProfileChangedEvent = "profile:changed"
+
+// startupProfile returns the profile that was active when the app last closed
+func (a *App) startupProfile() models.Profile {
+	if a.profileStorage == nil {
+		return models.Profile{Name: storage.DefaultProfile}
+	}
+	profile, err := a.profileStorage.ActiveProfile()
+	if err != nil {
+		log.Printf("failed to load active profile, using default: %v", err)
+		return models.Profile{Name: storage.DefaultProfile}
+	}
+	return profile
+}
+
+// profileState is the active profile with the stores opened from its
+// directory. It is never modified once published; switching profiles builds a
+// new one and swaps it in, so a request sees every store from one profile.
+type profileState struct {
+	profile         models.Profile
+	recentsStorage  *storage.RecentsStorage
+	presetStorage   *storage.PresetStorage
+	settingsStorage *storage.SettingsStorage
+	secrets         *secrets.Service
+	credentials     *credentials.Resolver
+	promptEngine    *prompts.Engine
+}
+
+// current returns the active profile's state
+func (a *App) current() *profileState {
+	a.stateMu.RLock()
+	defer a.stateMu.RUnlock()
+	return a.state
+}
+
+// openProfile opens every profile-scoped store from the profile's directory and
+// makes them current, closing the previous profile's secrets connection.
+// A store that can't be opened is left unavailable, as at startup. Callers
+// hold a.switchMu.
+func (a *App) openProfile(profile models.Profile) {
+	state := &profileState{profile: profile, credentials: credentials.NewResolver(nil), promptEngine: prompts.NewEngine()}
+	if a.profileStorage != nil {
+		state = a.loadProfileState(profile)
+	}
+
+	a.stateMu.Lock()
+	previous := a.state
+	a.state = state
+	a.stateMu.Unlock()
+
+	if previous != nil && previous.secrets != nil {
+		if err := previous.secrets.Close(); err != nil {
+			log.Printf("failed to close secrets of profile %s: %v", previous.profile.Name, err)
+		}
+	}
+	if a.profileStorage == nil {
+		return
+	}
+
+	if state.settingsStorage != nil {
+		if settings, err := state.settingsStorage.Load(); err == nil {
+			a.applySettings(settings)
+		}
+	}
+	a.applyOllamaHost()
+
+	dir := a.profileStorage.ProfileDir(profile.Name)
+	if a.spendingTracker == nil {
+		spendingTracker, err := spending.NewTracker(spendingDir(dir))
+		if err != nil {
+			log.Printf("spending tracker not available: %v", err)
+		} else {
+			a.spendingTracker = spendingTracker
+		}
+	} else if err := a.spendingTracker.SwitchDir(spendingDir(dir)); err != nil {
+		log.Printf("failed to load budgets for profile %s: %v", profile.Name, err)
+	}
+
+	if err := a.providerManager.SetCacheDir(a.profileStorage.CacheDir(profile)); err != nil {
+		log.Printf("model cache not available: %v", err)
+	}
+}
+
+// loadProfileState opens the stores kept in a profile's directory
+func (a *App) loadProfileState(profile models.Profile) *profileState {
+	dir := a.profileStorage.ProfileDir(profile.Name)
+	state := &profileState{profile: profile}
+
+	recentsStorage, err := storage.NewRecentsStorageIn(dir)
+	if err != nil {
+		log.Printf("recents storage not available: %v", err)
+		recentsStorage = nil
+	}
+	state.recentsStorage = recentsStorage
+
+	presetStorage, err := storage.NewPresetStorageIn(dir)
+	if err != nil {
+		log.Printf("preset storage not available: %v", err)
+		presetStorage = nil
+	}
+	state.presetStorage = presetStorage
+
+	settingsStorage, err := storage.NewSettingsStorageIn(dir)
+	if err != nil {
+		log.Printf("settings storage not available: %v", err)
+		settingsStorage = nil
+	}
+	state.settingsStorage = settingsStorage
+
+	// Keys are kept in the OS keyring or encrypted vault and passed around by reference
+	state.secrets = secrets.NewService(dir, profile.Name)
+	state.credentials = newCredentialResolver(state.secrets)
+
+	// The in-memory engine keeps built-in partials working if user partials can't be opened
+	promptEngine, err := newPromptEngine(dir)
+	if err != nil {
+		log.Printf("prompt partials not available: %v", err)
+		promptEngine = prompts.NewEngine()
+	}
+	state.promptEngine = promptEngine
+
+	return state
+}
+
+// ListProfiles returns all profiles and the name of the active one
+func (a *App) ListProfiles() (models.ProfilesResult, error) {
+	if a.profileStorage == nil {
+		return models.ProfilesResult{}, fmt.Errorf("profile storage not available")
+	}
+	return a.profileStorage.ListProfiles()
+}
+
+// GetActiveProfile returns the profile in use
+func (a *App) GetActiveProfile() models.Profile {
+	return a.current().profile
+}
+
+// CreateProfile adds an empty profile; switch to it to configure it
+func (a *App) CreateProfile(profile models.Profile) (models.Profile, error) {
+	if a.profileStorage == nil {
+		return models.Profile{}, fmt.Errorf("profile storage not available")
+	}
+	return a.profileStorage.CreateProfile(profile)
+}
+
+// DeleteProfile removes a profile with its settings, presets, recents, budgets
+// and stored keys. The default and active profiles can't be deleted.
+func (a *App) DeleteProfile(name string) error {
+	if a.profileStorage == nil {
+		return fmt.Errorf("profile storage not available")
+	}
+	if name == a.current().profile.Name {
+		return fmt.Errorf("profile %q is active; switch to another profile first", name)
+	}
+	if _, err := a.profileStorage.GetProfile(name); err != nil {
+		return err
+	}
+
+	// Keyring entries live outside the profile directory; a vault goes with it
+	service := secrets.NewService(a.profileStorage.ProfileDir(name), name)
+	defer service.Close()
+	if service.Backend() == secrets.BackendKeyring {
+		for _, secret := range service.Status().Stored {
+			if err := service.Delete(secret); err != nil {
+				log.Printf("failed to delete %s key of profile %s: %v", secret, name, err)
+			}
+		}
+	}
+
+	return a.profileStorage.DeleteProfile(name)
+}
+
+// SwitchProfile makes the named profile active, reopening its settings, keys,
+// presets, recents, budgets and caches, and notifies the frontend
+func (a *App) SwitchProfile(name string) (models.Profile, error) {
+	if a.profileStorage == nil {
+		return models.Profile{}, fmt.Errorf("profile storage not available")
+	}
+
+	a.switchMu.Lock()
+	profile, err := a.profileStorage.SetActiveProfile(name)
+	if err != nil {
+		a.switchMu.Unlock()
+		return models.Profile{}, err
+	}
+	a.openProfile(profile)
+	a.switchMu.Unlock()
+
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, ProfileChangedEvent, profile)
+		if settings, err := a.GetSettings(); err == nil {
+			runtime.EventsEmit(a.ctx, SettingsChangedEvent, settings)
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"testing"
+
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/spending"
+	"thoughtorio/internal/storage"
+)
+
+func TestSwitchProfileSwapsStores(t *testing.T) {
+	a := newTestApp(t)
+	const canvasPath = "/work/plan.thoughtorio"
+
+	// The default profile has its own settings, key and spend
+	settings, err := a.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.DefaultNodeWidth = 300
+	if _, err := a.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.UnlockSecrets("personal"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.StoreAPIKey("openai", "sk-personal"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetSpendingConfig(spending.Config{Enabled: true, DailyLimit: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.spendingTracker.Record("openai", canvasPath, 1.5); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.CreateProfile(models.Profile{Name: "client"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.SwitchProfile("client"); err != nil {
+		t.Fatal(err)
+	}
+	if a.GetActiveProfile().Name != "client" {
+		t.Fatalf("active profile = %q, want client", a.GetActiveProfile().Name)
+	}
+
+	// The new profile starts from nothing
+	settings, err = a.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.DefaultNodeWidth != storage.DefaultSettings().DefaultNodeWidth {
+		t.Errorf("client node width = %d, want the default", settings.DefaultNodeWidth)
+	}
+	status, err := a.GetSecretsStatus()
+	if err != nil || status.Initialized || len(status.Stored) != 0 {
+		t.Errorf("client secrets = %+v, %v, want an empty vault", status, err)
+	}
+	if resolved, err := a.resolveCredentials("openai", "", ""); err != nil || resolved.APIKey != "" {
+		t.Errorf("client openai key = %q, %v, want none", resolved.APIKey, err)
+	}
+	config, err := a.GetSpendingConfig()
+	if err != nil || config.Enabled || config.DailyLimit != 0 {
+		t.Errorf("client spending config = %+v, %v, want none", config, err)
+	}
+	budget, err := a.GetRemainingBudget(canvasPath)
+	if err != nil || budget.Daily.Spent != 0 || budget.Canvas.Spent != 0 {
+		t.Errorf("client spend = %+v, %v, want nothing spent", budget, err)
+	}
+
+	settings.DefaultNodeWidth = 150
+	if _, err := a.UpdateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetSpendingConfig(spending.Config{Enabled: true, DailyLimit: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Switching back restores everything the default profile had
+	if _, err := a.SwitchProfile(storage.DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	settings, err = a.GetSettings()
+	if err != nil || settings.DefaultNodeWidth != 300 {
+		t.Errorf("default node width = %d, %v, want 300", settings.DefaultNodeWidth, err)
+	}
+	if err := a.UnlockSecrets("personal"); err != nil {
+		t.Fatal(err)
+	}
+	if resolved, err := a.resolveCredentials("openai", "", ""); err != nil || resolved.APIKey != "sk-personal" {
+		t.Errorf("default openai key = %q, %v, want sk-personal", resolved.APIKey, err)
+	}
+	config, err = a.GetSpendingConfig()
+	if err != nil || !config.Enabled || config.DailyLimit != 5 {
+		t.Errorf("default spending config = %+v, %v, want the daily limit of 5", config, err)
+	}
+	budget, err = a.GetRemainingBudget(canvasPath)
+	if err != nil || budget.Daily.Spent != 1.5 || budget.Canvas.Spent != 1.5 {
+		t.Errorf("default spend = %+v, %v, want 1.5 spent", budget, err)
+	}
+
+	// The active profile can't be deleted; the other one can
+	if err := a.DeleteProfile(storage.DefaultProfile); err == nil {
+		t.Error("DeleteProfile removed the active profile")
+	}
+	if err := a.DeleteProfile("client"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.SwitchProfile("client"); err == nil {
+		t.Error("SwitchProfile opened a deleted profile")
+	}
+}
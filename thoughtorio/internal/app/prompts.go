@@ -0,0 +1,85 @@
+package app
+
+import (
+	"errors"
+	"path/filepath"
+
+	"thoughtorio/internal/prompts"
+	"thoughtorio/internal/providers"
+)
+
+// newPromptEngine opens the prompt template engine with partials stored in a profile's directory
+func newPromptEngine(profileDir string) (*prompts.Engine, error) {
+	return prompts.NewEngineWithDir(filepath.Join(profileDir, "prompts"))
+}
+
+// PromptPreviewRequest is a template rendered with sample inputs
+type PromptPreviewRequest struct {
+	Template  string                 `json:"template"`
+	Variables map[string]interface{} `json:"variables"`
+	// Provider and Model are optional; when set the preview includes a token estimate
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// PromptPreview is the exact prompt a template produces
+type PromptPreview struct {
+	Prompt string `json:"prompt"`
+	// Variables lists the variables the template refers to
+	Variables []string                  `json:"variables"`
+	Estimate  *providers.PromptEstimate `json:"estimate,omitempty"`
+	// Error is set instead of Prompt when the template fails to parse or render
+	Error *prompts.TemplateError `json:"error,omitempty"`
+}
+
+// PreviewPromptTemplate renders a prompt template with sample inputs so the exact
+// prompt can be checked before it is sent. Template errors are reported in the
+// preview with their line and the missing variable, if any.
+func (a *App) PreviewPromptTemplate(request PromptPreviewRequest) (PromptPreview, error) {
+	preview := PromptPreview{Variables: []string{}}
+
+	engine := a.current().promptEngine
+	variables, err := engine.Variables(request.Template)
+	if err != nil {
+		return previewError(preview, err)
+	}
+	preview.Variables = variables
+
+	prompt, err := engine.Render(request.Template, request.Variables)
+	if err != nil {
+		return previewError(preview, err)
+	}
+	preview.Prompt = prompt
+
+	if request.Provider != "" && request.Model != "" {
+		estimate := a.providerManager.EstimatePrompt(a.requestContext(), request.Provider, request.Model, prompt)
+		preview.Estimate = &estimate
+	}
+
+	return preview, nil
+}
+
+// previewError records a template error on the preview; other errors are returned
+func previewError(preview PromptPreview, err error) (PromptPreview, error) {
+	var templateErr *prompts.TemplateError
+	if errors.As(err, &templateErr) {
+		preview.Error = templateErr
+		return preview, nil
+	}
+	return preview, err
+}
+
+// ListPromptPartials returns the partials prompt templates can include
+func (a *App) ListPromptPartials() []prompts.Partial {
+	return a.current().promptEngine.Partials()
+}
+
+// SavePromptPartial creates or replaces a user partial
+func (a *App) SavePromptPartial(name, body string) error {
+	return a.current().promptEngine.SetPartial(name, body)
+}
+
+// DeletePromptPartial removes a user partial
+func (a *App) DeletePromptPartial(name string) error {
+	return a.current().promptEngine.DeletePartial(name)
+}
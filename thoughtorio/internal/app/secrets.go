@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fmt"
+
+	"thoughtorio/internal/secrets"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// SecretsChangedEvent is emitted with the secrets status when the vault is unlocked or locked
+const SecretsChangedEvent = "secrets:changed"
+
+// GetSecretsStatus reports the secrets backend, whether it is locked and which keys it holds
+func (a *App) GetSecretsStatus() (secrets.Status, error) {
+	service := a.current().secrets
+	if service == nil {
+		return secrets.Status{}, fmt.Errorf("secrets service not available")
+	}
+	return service.Status(), nil
+}
+
+// UnlockSecrets opens the encrypted vault used when no OS keyring is available.
+// The first unlock sets the passphrase.
+func (a *App) UnlockSecrets(passphrase string) error {
+	service := a.current().secrets
+	if service == nil {
+		return fmt.Errorf("secrets service not available")
+	}
+	if err := service.Unlock(passphrase); err != nil {
+		return err
+	}
+	a.emitSecretsChanged()
+	return nil
+}
+
+// LockSecrets locks the encrypted vault until it is unlocked again
+func (a *App) LockSecrets() error {
+	service := a.current().secrets
+	if service == nil {
+		return fmt.Errorf("secrets service not available")
+	}
+	service.Lock()
+	a.emitSecretsChanged()
+	return nil
+}
+
+func (a *App) emitSecretsChanged() {
+	if service := a.current().secrets; a.ctx != nil && service != nil {
+		runtime.EventsEmit(a.ctx, SecretsChangedEvent, service.Status())
+	}
+}
+
+// StoreAPIKey saves a key under name and returns the reference the frontend
+// passes in place of the key from then on
+func (a *App) StoreAPIKey(name, apiKey string) (string, error) {
+	service := a.current().secrets
+	if service == nil {
+		return "", fmt.Errorf("secrets service not available")
+	}
+	return service.Set(name, apiKey)
+}
+
+// DeleteAPIKey removes a stored key
+func (a *App) DeleteAPIKey(name string) error {
+	service := a.current().secrets
+	if service == nil {
+		return fmt.Errorf("secrets service not available")
+	}
+	return service.Delete(name)
+}
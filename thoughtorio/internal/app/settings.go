@@ -0,0 +1,144 @@
+package app
+
+import (
+	"fmt"
+	"log"
+
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/providers"
+	"thoughtorio/internal/secrets"
+	"thoughtorio/internal/storage"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// SettingsChangedEvent is emitted with the new settings whenever they are saved
+const SettingsChangedEvent = "settings:changed"
+
+// GetSettings returns the saved settings, or the defaults when none are saved
+func (a *App) GetSettings() (models.Settings, error) {
+	settingsStorage := a.current().settingsStorage
+	if settingsStorage == nil {
+		return storage.DefaultSettings(), fmt.Errorf("settings storage not available")
+	}
+	return settingsStorage.Load()
+}
+
+// UpdateSettings validates and saves the settings, moving any raw API keys into
+// the secrets service, and notifies every window of the change
+func (a *App) UpdateSettings(settings models.Settings) (models.Settings, error) {
+	settingsStorage := a.current().settingsStorage
+	if settingsStorage == nil {
+		return models.Settings{}, fmt.Errorf("settings storage not available")
+	}
+	if err := a.checkSettings(settings); err != nil {
+		return models.Settings{}, err
+	}
+	if err := a.secureSettingsKeys(&settings); err != nil {
+		return models.Settings{}, err
+	}
+
+	saved, err := settingsStorage.Save(settings)
+	if err != nil {
+		return models.Settings{}, err
+	}
+	a.applySettings(saved)
+
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, SettingsChangedEvent, saved)
+	}
+	return saved, nil
+}
+
+// changeSettings loads the saved settings, lets change modify them and saves
+// them as UpdateSettings does
+func (a *App) changeSettings(change func(settings *models.Settings)) error {
+	settings, err := a.GetSettings()
+	if err != nil {
+		return err
+	}
+	change(&settings)
+	_, err = a.UpdateSettings(settings)
+	return err
+}
+
+// applySettings applies the settings the backend acts on itself, when they
+// are saved and when a profile is opened
+func (a *App) applySettings(settings models.Settings) {
+	a.applyCanvasSettings(settings)
+	a.applyProviderSettings(settings)
+}
+
+// applyProviderSettings configures the providers from the settings
+func (a *App) applyProviderSettings(settings models.Settings) {
+	if err := a.providerManager.SetBudgetConfig(settings.PromptBudget); err != nil {
+		log.Printf("ignoring saved prompt budget: %v", err)
+	}
+	if ollama, err := a.providerManager.GetOllamaProvider(); err == nil {
+		if err := ollama.SetConfig(settings.Ollama); err != nil {
+			log.Printf("ignoring saved Ollama configuration: %v", err)
+		}
+	}
+	if azure, err := a.providerManager.GetAzureOpenAIProvider(); err == nil {
+		if err := azure.SetConfig(settings.AzureOpenAI); err != nil {
+			log.Printf("ignoring saved Azure OpenAI configuration: %v", err)
+		}
+	}
+}
+
+// ImportSettings saves a settings document of any schema version, such as the
+// unversioned blob earlier frontends kept in localStorage, after migrating it
+func (a *App) ImportSettings(data string) (models.Settings, error) {
+	settings, _, err := storage.ParseSettings([]byte(data))
+	if err != nil {
+		return models.Settings{}, err
+	}
+	return a.UpdateSettings(settings)
+}
+
+// ResetSettings restores the default settings
+func (a *App) ResetSettings() (models.Settings, error) {
+	return a.UpdateSettings(storage.DefaultSettings())
+}
+
+// checkSettings validates settings against the registered providers
+func (a *App) checkSettings(settings models.Settings) error {
+	if err := storage.ValidateSettings(settings); err != nil {
+		return err
+	}
+	if _, err := a.providerManager.GetProvider(settings.ActiveMode); err != nil {
+		return err
+	}
+	return providers.CompletionOptions{ReasoningEffort: settings.ReasoningEffort}.Validate()
+}
+
+// secureSettingsKeys stores raw API keys as secrets and replaces them with
+// references. While the vault is locked the keys are held in memory, so the
+// rest of the settings still save and the keys are written once it is unlocked.
+func (a *App) secureSettingsKeys(settings *models.Settings) error {
+	stored := false
+	defer func() {
+		if stored {
+			a.emitSecretsChanged()
+		}
+	}()
+
+	service := a.current().secrets
+	for name, field := range settings.APIKeyFields() {
+		if *field == "" {
+			continue
+		}
+		if _, ok := secrets.ParseRef(*field); ok {
+			continue
+		}
+		if service == nil {
+			return fmt.Errorf("secrets service not available to store the %s key", name)
+		}
+		ref, err := service.Set(name, *field)
+		if err != nil {
+			return fmt.Errorf("failed to store the %s key: %w", name, err)
+		}
+		*field = ref
+		stored = true
+	}
+	return nil
+}
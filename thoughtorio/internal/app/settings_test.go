@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"thoughtorio/internal/secrets"
+	"thoughtorio/internal/storage"
+)
+
+// newTestApp starts an app on a fresh config directory, with the keyring out of
+// reach so keys go to the encrypted vault
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path="+filepath.Join(configDir, "no-bus"))
+
+	a := NewApp()
+	a.canvasStorage = storage.NewCanvasStorage(context.Background())
+	profileStorage, err := storage.NewProfileStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.profileStorage = profileStorage
+	a.switchMu.Lock()
+	a.openProfile(a.startupProfile())
+	a.switchMu.Unlock()
+	t.Cleanup(func() {
+		if service := a.current().secrets; service != nil {
+			service.Close()
+		}
+	})
+	return a
+}
+
+func TestUpdateSettingsHoldsKeysWhileLocked(t *testing.T) {
+	a := newTestApp(t)
+	if backend := a.current().secrets.Backend(); backend != secrets.BackendVault {
+		t.Fatalf("secrets backend = %s, want the vault", backend)
+	}
+
+	settings, err := a.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.OpenAIAPIKey = "sk-test"
+	settings.DefaultNodeWidth = 300
+	saved, err := a.UpdateSettings(settings)
+	if err != nil {
+		t.Fatalf("UpdateSettings while the vault is locked: %v", err)
+	}
+	if saved.OpenAIAPIKey != secrets.Ref("openai") {
+		t.Errorf("saved key = %q, want a reference", saved.OpenAIAPIKey)
+	}
+
+	// The rest of the settings are on disk, the key itself is not
+	loaded, err := a.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.DefaultNodeWidth != 300 || loaded.OpenAIAPIKey != secrets.Ref("openai") {
+		t.Errorf("loaded settings = %+v, want the width and the reference", loaded)
+	}
+	data, err := os.ReadFile(filepath.Join(a.profileStorage.ProfileDir(storage.DefaultProfile), "settings.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-test") {
+		t.Error("settings file holds the raw key")
+	}
+
+	// The held key is usable before the vault is unlocked and stored once it is
+	status, err := a.GetSecretsStatus()
+	if err != nil || !status.Locked || len(status.Pending) != 1 {
+		t.Errorf("status = %+v, %v, want locked with the key pending", status, err)
+	}
+	if resolved, err := a.resolveCredentials("openai", loaded.OpenAIAPIKey, ""); err != nil || resolved.APIKey != "sk-test" {
+		t.Errorf("resolved = %+v, %v, want the held key", resolved, err)
+	}
+	if err := a.UnlockSecrets("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	status, err = a.GetSecretsStatus()
+	if err != nil || len(status.Pending) != 0 || len(status.Stored) != 1 {
+		t.Errorf("status after unlock = %+v, %v, want the key stored", status, err)
+	}
+}
+
+func TestUpdateSettingsRejectsUnknownProvider(t *testing.T) {
+	a := newTestApp(t)
+
+	settings, err := a.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.ActiveMode = "nowhere"
+	if _, err := a.UpdateSettings(settings); err == nil {
+		t.Error("UpdateSettings accepted a provider that isn't registered")
+	}
+	if loaded, err := a.GetSettings(); err != nil || loaded.ActiveMode != "openrouter" {
+		t.Errorf("settings after a rejected update = %+v, %v, want the defaults kept", loaded, err)
+	}
+}
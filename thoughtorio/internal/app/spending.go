@@ -0,0 +1,49 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"thoughtorio/internal/spending"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// BudgetWarningEvent is emitted when spend crosses the warning ratio of a cap
+const BudgetWarningEvent = "budget:warning"
+
+// spendingDir returns where a profile keeps its budgets and spend ledger
+func spendingDir(profileDir string) string {
+	return filepath.Join(profileDir, "spending")
+}
+
+// emitBudgetWarning forwards a soft budget warning to the frontend
+func (a *App) emitBudgetWarning(warning spending.Warning) {
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, BudgetWarningEvent, warning)
+	}
+}
+
+// GetSpendingConfig returns the spending budget configuration
+func (a *App) GetSpendingConfig() (spending.Config, error) {
+	if a.spendingTracker == nil {
+		return spending.Config{}, fmt.Errorf("spending tracker not available")
+	}
+	return a.spendingTracker.GetConfig(), nil
+}
+
+// SetSpendingConfig enables budgets and sets the daily, per-provider and per-canvas caps
+func (a *App) SetSpendingConfig(config spending.Config) error {
+	if a.spendingTracker == nil {
+		return fmt.Errorf("spending tracker not available")
+	}
+	return a.spendingTracker.SetConfig(config)
+}
+
+// GetRemainingBudget reports today's spend and remaining budget for every
+// provider, and for the canvas when canvasPath is set
+func (a *App) GetRemainingBudget(canvasPath string) (spending.Status, error) {
+	if a.spendingTracker == nil {
+		return spending.Status{}, fmt.Errorf("spending tracker not available")
+	}
+	return a.spendingTracker.Status(canvasPath), nil
+}
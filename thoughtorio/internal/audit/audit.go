@@ -0,0 +1,278 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	currentLogName = "audit.jsonl"
+	configFileName = "config.json"
+
+	// DefaultMaxFileBytes is the size at which the active log is rotated
+	DefaultMaxFileBytes = 10 * 1024 * 1024
+)
+
+// Config controls whether and how AI requests are recorded
+type Config struct {
+	// Enabled turns recording on; the audit log is opt-in
+	Enabled bool `json:"enabled"`
+	// Strict stores only SHA-256 hashes of system prompts, prompts and responses
+	// instead of redacted text
+	Strict bool `json:"strict"`
+	// MaxFileBytes rotates the active log once it would grow beyond this size
+	MaxFileBytes int64 `json:"maxFileBytes"`
+}
+
+// DefaultConfig returns the configuration used until the user opts in
+func DefaultConfig() Config {
+	return Config{
+		Enabled:      false,
+		Strict:       false,
+		MaxFileBytes: DefaultMaxFileBytes,
+	}
+}
+
+// Usage mirrors the token usage reported by the provider
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+// Entry is one line of the audit log
+type Entry struct {
+	Timestamp        time.Time `json:"timestamp"`
+	CanvasPath       string    `json:"canvasPath,omitempty"`
+	NodeID           string    `json:"nodeId,omitempty"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	SystemPrompt     string    `json:"systemPrompt,omitempty"`
+	Prompt           string    `json:"prompt,omitempty"`
+	Response         string    `json:"response,omitempty"`
+	PromptHash       string    `json:"promptHash"`
+	SystemPromptHash string    `json:"systemPromptHash,omitempty"`
+	ResponseHash     string    `json:"responseHash,omitempty"`
+	PromptChars      int       `json:"promptChars"`
+	Usage            *Usage    `json:"usage,omitempty"`
+	Outcome          string    `json:"outcome"`
+	Error            string    `json:"error,omitempty"`
+	LatencyMs        float64   `json:"latencyMs,omitempty"`
+}
+
+// Outcomes recorded for each request
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Query filters audit entries; zero values match everything
+type Query struct {
+	// From and To bound the timestamp in Unix milliseconds (inclusive)
+	From       int64  `json:"from,omitempty"`
+	To         int64  `json:"to,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+	CanvasPath string `json:"canvasPath,omitempty"`
+	// Limit returns only the most recent matches when greater than zero
+	Limit int `json:"limit,omitempty"`
+}
+
+// Logger appends audit entries to JSONL files in its directory, rotating by size.
+// Rotated files are kept; entries are never rewritten or removed.
+type Logger struct {
+	dir    string
+	config Config
+	mu     sync.Mutex
+}
+
+// NewLogger creates an audit logger storing its log and configuration in dir
+func NewLogger(dir string) (*Logger, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	l := &Logger{dir: dir, config: DefaultConfig()}
+
+	data, err := os.ReadFile(filepath.Join(dir, configFileName))
+	if err == nil {
+		if err := json.Unmarshal(data, &l.config); err != nil {
+			return nil, fmt.Errorf("failed to parse audit config: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read audit config: %w", err)
+	}
+
+	return l, nil
+}
+
+// Dir returns the directory holding the audit log
+func (l *Logger) Dir() string {
+	return l.dir
+}
+
+// GetConfig returns the current audit configuration
+func (l *Logger) GetConfig() Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config
+}
+
+// SetConfig validates, applies and persists the audit configuration
+func (l *Logger) SetConfig(config Config) error {
+	if config.MaxFileBytes <= 0 {
+		config.MaxFileBytes = DefaultMaxFileBytes
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit config: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(l.dir, configFileName), data, 0600); err != nil {
+		return fmt.Errorf("failed to save audit config: %w", err)
+	}
+	l.config = config
+	return nil
+}
+
+// Append writes an entry to the active log, rotating it first if it would exceed the size limit
+func (l *Logger) Append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.config.Enabled {
+		return nil
+	}
+
+	current := filepath.Join(l.dir, currentLogName)
+	if info, err := os.Stat(current); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > l.config.MaxFileBytes {
+		if err := l.rotate(current); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(current, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return file.Sync()
+}
+
+// rotate renames the active log to a timestamped file
+func (l *Logger) rotate(current string) error {
+	stamp := time.Now().UTC().Format("20060102T150405.000000000")
+	rotated := filepath.Join(l.dir, "audit-"+strings.ReplaceAll(stamp, ".", "")+".jsonl")
+	if err := os.Rename(current, rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return nil
+}
+
+// Query returns matching entries from all log files, oldest first
+func (l *Logger) Query(query Query) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.logFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, path := range files {
+		matches, err := readEntries(path, query)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, matches...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	return entries, nil
+}
+
+// logFiles lists rotated logs in chronological order followed by the active log
+func (l *Logger) logFiles() ([]string, error) {
+	rotated, err := filepath.Glob(filepath.Join(l.dir, "audit-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(rotated)
+
+	current := filepath.Join(l.dir, currentLogName)
+	if _, err := os.Stat(current); err == nil {
+		rotated = append(rotated, current)
+	}
+	return rotated, nil
+}
+
+// readEntries scans one log file, skipping lines that don't parse
+func readEntries(path string, query Query) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if query.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+func (q Query) matches(entry Entry) bool {
+	millis := entry.Timestamp.UnixMilli()
+	if q.From > 0 && millis < q.From {
+		return false
+	}
+	if q.To > 0 && millis > q.To {
+		return false
+	}
+	if q.Provider != "" && entry.Provider != q.Provider {
+		return false
+	}
+	if q.CanvasPath != "" && entry.CanvasPath != q.CanvasPath {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"thoughtorio/internal/providers"
+)
+
+func newTestLogger(t *testing.T, config Config) *Logger {
+	t.Helper()
+	logger, err := NewLogger(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	return logger
+}
+
+func TestAppendRotatesBySize(t *testing.T) {
+	logger := newTestLogger(t, Config{Enabled: true, MaxFileBytes: 400})
+
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		entry := Entry{Timestamp: start.Add(time.Duration(i) * time.Minute), Provider: "openai", Model: "gpt-4o", PromptHash: Hash("prompt")}
+		if err := logger.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+		// Rotated files are named by the time of rotation
+		time.Sleep(time.Millisecond)
+	}
+
+	rotated, err := filepath.Glob(filepath.Join(logger.Dir(), "audit-*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) < 2 {
+		t.Fatalf("rotated files = %v, want the log rotated more than once", rotated)
+	}
+
+	// Every entry survives rotation and comes back oldest first
+	entries, err := logger.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("Query returned %d entries, want 6", len(entries))
+	}
+	for i, entry := range entries {
+		if want := start.Add(time.Duration(i) * time.Minute); !entry.Timestamp.Equal(want) {
+			t.Errorf("entry %d at %s, want %s", i, entry.Timestamp, want)
+		}
+	}
+}
+
+func TestAppendWhileDisabled(t *testing.T) {
+	logger := newTestLogger(t, Config{Enabled: false})
+	if err := logger.Append(Entry{Timestamp: time.Now(), Provider: "openai"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := logger.Query(Query{}); err != nil || len(entries) != 0 {
+		t.Errorf("Query = %v, %v, want nothing recorded while disabled", entries, err)
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	logger := newTestLogger(t, Config{Enabled: true})
+
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 9, 0, 0, 0, time.UTC) }
+	for _, entry := range []Entry{
+		{Timestamp: day(1), Provider: "openai", CanvasPath: "/a.thoughtorio"},
+		{Timestamp: day(2), Provider: "gemini", CanvasPath: "/a.thoughtorio"},
+		{Timestamp: day(3), Provider: "openai", CanvasPath: "/b.thoughtorio"},
+		{Timestamp: day(4), Provider: "openai", CanvasPath: "/a.thoughtorio"},
+	} {
+		if err := logger.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []int
+	}{
+		{name: "everything", query: Query{}, want: []int{1, 2, 3, 4}},
+		{name: "from", query: Query{From: day(2).UnixMilli()}, want: []int{2, 3, 4}},
+		{name: "to is inclusive", query: Query{To: day(2).UnixMilli()}, want: []int{1, 2}},
+		{name: "between", query: Query{From: day(2).UnixMilli() + 1, To: day(4).UnixMilli() - 1}, want: []int{3}},
+		{name: "provider", query: Query{Provider: "openai"}, want: []int{1, 3, 4}},
+		{name: "canvas and date", query: Query{CanvasPath: "/a.thoughtorio", From: day(2).UnixMilli()}, want: []int{2, 4}},
+		{name: "limit keeps the latest", query: Query{Provider: "openai", Limit: 2}, want: []int{3, 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := logger.Query(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			days := []int{}
+			for _, entry := range entries {
+				days = append(days, entry.Timestamp.Day())
+			}
+			if len(days) != len(test.want) {
+				t.Fatalf("days = %v, want %v", days, test.want)
+			}
+			for i := range days {
+				if days[i] != test.want[i] {
+					t.Fatalf("days = %v, want %v", days, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInterceptorStrictMode(t *testing.T) {
+	request := func() *providers.CompletionRequest {
+		return &providers.CompletionRequest{
+			Provider: "openai",
+			Model:    "gpt-4o",
+			Prompt:   "Write to jane@example.com with key sk-abcdefghijklmnopqrstuv",
+			APIKey:   "sk-abcdefghijklmnopqrstuv",
+			Options:  providers.CompletionOptions{SystemPrompt: "You are terse."},
+			Metadata: map[string]string{providers.MetadataCanvasPath: "/a.thoughtorio", providers.MetadataNodeID: "node-1"},
+		}
+	}
+	next := func(ctx context.Context, req *providers.CompletionRequest) (providers.AICompletionResponse, error) {
+		return providers.AICompletionResponse{Content: "Done."}, nil
+	}
+
+	for _, strict := range []bool{false, true} {
+		logger := newTestLogger(t, Config{Enabled: true, Strict: strict})
+		handler := NewInterceptor(logger).WrapCompletion(next)
+		if _, err := handler(context.Background(), request()); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := logger.Query(Query{})
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("strict %v: Query = %v, %v, want one entry", strict, entries, err)
+		}
+		entry := entries[0]
+		if entry.PromptHash != Hash(request().Prompt) || entry.SystemPromptHash != Hash("You are terse.") || entry.ResponseHash != Hash("Done.") {
+			t.Errorf("strict %v: hashes = %q, %q, %q, want the prompt, system prompt and response hashed", strict, entry.PromptHash, entry.SystemPromptHash, entry.ResponseHash)
+		}
+		if entry.CanvasPath != "/a.thoughtorio" || entry.NodeID != "node-1" || entry.Outcome != OutcomeSuccess {
+			t.Errorf("strict %v: entry = %+v, want the canvas, node and success recorded", strict, entry)
+		}
+
+		if strict {
+			if entry.Prompt != "" || entry.SystemPrompt != "" || entry.Response != "" {
+				t.Errorf("strict entry kept text: %+v", entry)
+			}
+			continue
+		}
+		if entry.Prompt != "Write to [REDACTED_EMAIL] with key [REDACTED_KEY]" || entry.SystemPrompt != "You are terse." || entry.Response != "Done." {
+			t.Errorf("entry text = %q, %q, %q, want it redacted", entry.Prompt, entry.SystemPrompt, entry.Response)
+		}
+	}
+}
+
+func TestInterceptorRecordsErrors(t *testing.T) {
+	logger := newTestLogger(t, Config{Enabled: true})
+	handler := NewInterceptor(logger).WrapCompletion(func(ctx context.Context, req *providers.CompletionRequest) (providers.AICompletionResponse, error) {
+		return providers.AICompletionResponse{}, errors.New("rejected key sk-abcdefghijklmnopqrstuv")
+	})
+	if _, err := handler(context.Background(), &providers.CompletionRequest{Provider: "openai", Prompt: "hi"}); err == nil {
+		t.Fatal("handler hid the error")
+	}
+
+	entries, err := logger.Query(Query{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Query = %v, %v, want one entry", entries, err)
+	}
+	if entries[0].Outcome != OutcomeError || entries[0].Error != "rejected key [REDACTED_KEY]" {
+		t.Errorf("entry = %+v, want a redacted error", entries[0])
+	}
+}
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"thoughtorio/internal/providers"
+)
+
+// NewInterceptor returns a provider interceptor that records every completion
+// in the audit log. It sits innermost in the chain so it sees the exact prompt
+// that leaves the machine.
+func NewInterceptor(logger *Logger) providers.Interceptor {
+	return providers.InterceptorFuncs{
+		Label: "audit",
+		Completion: func(next providers.CompletionHandler) providers.CompletionHandler {
+			return func(ctx context.Context, req *providers.CompletionRequest) (providers.AICompletionResponse, error) {
+				start := time.Now()
+				response, err := next(ctx, req)
+
+				if logger.GetConfig().Enabled {
+					entry := buildEntry(logger.GetConfig(), req, response, err)
+					entry.Timestamp = start.UTC()
+					entry.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+					if appendErr := logger.Append(entry); appendErr != nil {
+						log.Printf("failed to write audit entry: %v", appendErr)
+					}
+				}
+
+				return response, err
+			}
+		},
+	}
+}
+
+// buildEntry converts a completed request into an audit entry, redacting or hashing text
+func buildEntry(config Config, req *providers.CompletionRequest, response providers.AICompletionResponse, err error) Entry {
+	entry := Entry{
+		CanvasPath:  req.Metadata[providers.MetadataCanvasPath],
+		NodeID:      req.Metadata[providers.MetadataNodeID],
+		Provider:    req.Provider,
+		Model:       req.Model,
+		PromptHash:  Hash(req.Prompt),
+		PromptChars: len(req.Prompt),
+		Outcome:     OutcomeSuccess,
+	}
+
+	if req.Options.SystemPrompt != "" {
+		entry.SystemPromptHash = Hash(req.Options.SystemPrompt)
+	}
+	if response.Content != "" {
+		entry.ResponseHash = Hash(response.Content)
+	}
+	if !config.Strict {
+		entry.SystemPrompt = Redact(req.Options.SystemPrompt, req.APIKey)
+		entry.Prompt = Redact(req.Prompt, req.APIKey)
+		entry.Response = Redact(response.Content, req.APIKey)
+	}
+
+	if response.Usage != nil {
+		entry.Usage = &Usage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		}
+	}
+
+	if err != nil {
+		entry.Outcome = OutcomeError
+		entry.Error = Redact(err.Error(), req.APIKey)
+	}
+
+	return entry
+}
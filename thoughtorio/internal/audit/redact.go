@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// redactionPatterns match credentials and personal data that shouldn't be kept
+// in the log. A match is only replaced when the rule has no check or passes it.
+var redactionPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+	check       func(match string) bool
+}{
+	{pattern: regexp.MustCompile(`sk-[A-Za-z0-9_\-]{16,}`), replacement: "[REDACTED_KEY]"},
+	{pattern: regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`), replacement: "[REDACTED_KEY]"},
+	{pattern: regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._\-]{16,}`), replacement: "Bearer [REDACTED_TOKEN]"},
+	{pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), replacement: "[REDACTED_EMAIL]"},
+	// Card numbers, told apart from timestamps and other long numbers by their check digit
+	{pattern: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`), replacement: "[REDACTED_NUMBER]", check: luhnValid},
+}
+
+// Redact removes known secrets, the given literal secrets and personal data from text
+func Redact(text string, secrets ...string) string {
+	for _, secret := range secrets {
+		if len(secret) >= 8 {
+			text = strings.ReplaceAll(text, secret, "[REDACTED_KEY]")
+		}
+	}
+	for _, rule := range redactionPatterns {
+		if rule.check == nil {
+			text = rule.pattern.ReplaceAllString(text, rule.replacement)
+			continue
+		}
+		text = rule.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if rule.check(match) {
+				return rule.replacement
+			}
+			return match
+		})
+	}
+	return text
+}
+
+// luhnValid reports whether the digits in number pass the Luhn check used by card numbers
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Hash returns the hex SHA-256 of text, used to prove what was sent without storing it
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
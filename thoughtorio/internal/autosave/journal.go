@@ -0,0 +1,331 @@
+// Package autosave keeps a recovery journal of canvases with unsaved changes,
+// so work survives the app or webview crashing between manual saves.
+package autosave
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDelay is how long the journal waits for changes to settle before writing
+const DefaultDelay = 2 * time.Second
+
+// Key prefixes for journal entries
+const (
+	canvasKeyPrefix   = "canvas-"
+	untitledKeyPrefix = "untitled-"
+)
+
+// Entry is the latest unsaved state of one canvas
+type Entry struct {
+	Key string `json:"key"`
+	// SessionID identifies the frontend session that wrote the entry
+	SessionID string `json:"sessionId"`
+	// CanvasPath is the file the canvas was opened from; empty for untitled canvases
+	CanvasPath string `json:"canvasPath,omitempty"`
+	SavedAt    int64  `json:"savedAt"` // Milliseconds for JavaScript compatibility
+	Data       string `json:"data"`
+}
+
+// Untitled reports whether the canvas has never been saved to a file
+func (e Entry) Untitled() bool {
+	return e.CanvasPath == ""
+}
+
+// pendingWrite is an entry waiting for changes to settle
+type pendingWrite struct {
+	entry Entry
+	timer *time.Timer
+	// replaces is an entry the session wrote under another key, removed once this one lands
+	replaces string
+}
+
+// Journal debounces canvas state sent by the frontend and writes it to one
+// file per canvas, keyed by canvas path or, for untitled canvases, by session
+type Journal struct {
+	dir   string
+	delay time.Duration
+	now   func() time.Time
+
+	pending map[string]*pendingWrite
+	// sessions maps each session to the key it last wrote under
+	sessions map[string]string
+	// untitled maps sessions to an untitled entry they adopted on recovery
+	untitled map[string]string
+	// written holds a hash of the data last written under each key
+	written map[string]string
+	mu      sync.Mutex
+}
+
+// NewJournal creates a journal writing to dir
+func NewJournal(dir string, delay time.Duration) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create recovery directory: %w", err)
+	}
+	if delay <= 0 {
+		delay = DefaultDelay
+	}
+	return &Journal{
+		dir:      dir,
+		delay:    delay,
+		now:      time.Now,
+		pending:  map[string]*pendingWrite{},
+		sessions: map[string]string{},
+		untitled: map[string]string{},
+		written:  map[string]string{},
+	}, nil
+}
+
+// KeyForPath returns the journal key of a saved canvas
+func KeyForPath(canvasPath string) string {
+	return canvasKeyPrefix + hash(filepath.Clean(canvasPath))[:16]
+}
+
+// keyFor returns the key a session's canvas is journalled under
+func (j *Journal) keyFor(sessionID, canvasPath string) string {
+	if canvasPath != "" {
+		return KeyForPath(canvasPath)
+	}
+	if key, ok := j.untitled[sessionID]; ok {
+		return key
+	}
+	return untitledKeyPrefix + hash(sessionID)[:16]
+}
+
+// Submit records the latest state of a session's canvas. It is written once no
+// newer state has arrived for the journal's delay.
+func (j *Journal) Submit(sessionID, canvasPath, data string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key := j.keyFor(sessionID, canvasPath)
+	write := j.pending[key]
+	if write == nil {
+		write = &pendingWrite{}
+		j.pending[key] = write
+	} else {
+		write.timer.Stop()
+	}
+
+	// A session that was untitled and has now been saved moves to its path's key
+	if previous, ok := j.sessions[sessionID]; ok && previous != key {
+		write.replaces = previous
+		if old := j.pending[previous]; old != nil {
+			old.timer.Stop()
+			delete(j.pending, previous)
+		}
+	}
+	j.sessions[sessionID] = key
+
+	write.entry = Entry{Key: key, SessionID: sessionID, CanvasPath: canvasPath, SavedAt: j.now().UnixMilli(), Data: data}
+	write.timer = time.AfterFunc(j.delay, func() { j.flushKey(key) })
+}
+
+// Flush writes every pending entry now, as on shutdown
+func (j *Journal) Flush() error {
+	j.mu.Lock()
+	keys := make([]string, 0, len(j.pending))
+	for key := range j.pending {
+		keys = append(keys, key)
+	}
+	j.mu.Unlock()
+
+	var firstErr error
+	for _, key := range keys {
+		if err := j.flushKey(key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// flushKey writes the pending entry for key, if it is still pending
+func (j *Journal) flushKey(key string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	write := j.pending[key]
+	if write == nil {
+		return nil
+	}
+	write.timer.Stop()
+	delete(j.pending, key)
+
+	digest := hash(write.entry.Data)
+	if j.written[key] != digest {
+		if err := j.writeEntry(write.entry); err != nil {
+			log.Printf("failed to write recovery journal: %v", err)
+			return err
+		}
+		j.written[key] = digest
+	}
+	if write.replaces != "" {
+		j.removeEntry(write.replaces)
+	}
+	return nil
+}
+
+// Adopt hands an entry to a session recovering it, so the session keeps
+// writing under the same key instead of leaving the entry behind
+func (j *Journal) Adopt(key, sessionID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if strings.HasPrefix(key, untitledKeyPrefix) {
+		j.untitled[sessionID] = key
+	}
+	j.sessions[sessionID] = key
+}
+
+// DiscardSession drops the entry a session last wrote, as after it saves or
+// starts a new canvas
+func (j *Journal) DiscardSession(sessionID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	delete(j.sessions, sessionID)
+	delete(j.untitled, sessionID)
+	return j.discard(key)
+}
+
+// DiscardPath drops the entry of a saved canvas, as after it is saved
+func (j *Journal) DiscardPath(canvasPath string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.discard(KeyForPath(canvasPath))
+}
+
+// Discard drops an entry by key
+func (j *Journal) Discard(key string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.discard(key)
+}
+
+// discard cancels any pending write for key and removes its entry
+func (j *Journal) discard(key string) error {
+	if write := j.pending[key]; write != nil {
+		write.timer.Stop()
+		delete(j.pending, key)
+		if write.replaces != "" {
+			j.removeEntry(write.replaces)
+		}
+	}
+	return j.removeEntry(key)
+}
+
+// Entries returns the journalled canvases, most recent first
+func (j *Journal) Entries() ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	files, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recovery directory: %w", err)
+	}
+
+	entries := []Entry{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		entry, err := j.readEntry(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			log.Printf("skipping unreadable recovery entry %s: %v", file.Name(), err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(a, b int) bool { return entries[a].SavedAt > entries[b].SavedAt })
+	return entries, nil
+}
+
+// Entry returns one journalled canvas
+func (j *Journal) Entry(key string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.readEntry(key)
+}
+
+// entryPath returns the file an entry is kept in, refusing keys that aren't journal keys
+func (j *Journal) entryPath(key string) (string, error) {
+	if !strings.HasPrefix(key, canvasKeyPrefix) && !strings.HasPrefix(key, untitledKeyPrefix) || strings.ContainsAny(key, `/\.`) {
+		return "", fmt.Errorf("invalid recovery entry %q", key)
+	}
+	return filepath.Join(j.dir, key+".json"), nil
+}
+
+func (j *Journal) readEntry(key string) (Entry, error) {
+	path, err := j.entryPath(key)
+	if err != nil {
+		return Entry{}, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Entry{}, fmt.Errorf("recovery entry %q not found", key)
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, err
+	}
+	entry.Key = key
+	return entry, nil
+}
+
+// writeEntry writes an entry through a temporary file so a crash mid-write
+// leaves the previous entry intact
+func (j *Journal) writeEntry(entry Entry) error {
+	path, err := j.entryPath(entry.Key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (j *Journal) removeEntry(key string) error {
+	delete(j.written, key)
+	path, err := j.entryPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove recovery entry: %w", err)
+	}
+	return nil
+}
+
+// hash returns the hex SHA-256 of s
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,206 @@
+package autosave
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestJournal returns a journal in a temporary directory whose clock is fixed
+func newTestJournal(t *testing.T, delay time.Duration) *Journal {
+	t.Helper()
+	journal, err := NewJournal(t.TempDir(), delay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.now = func() time.Time { return time.UnixMilli(1700000000000) }
+	return journal
+}
+
+// waitForEntry polls until key has been written or the deadline passes
+func waitForEntry(t *testing.T, journal *Journal, key string) Entry {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entry, err := journal.Entry(key)
+		if err == nil {
+			return entry
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("entry %s was never written: %v", key, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func entryKeys(t *testing.T, journal *Journal) []string {
+	t.Helper()
+	entries, err := journal.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func TestSubmitWaitsForChangesToSettle(t *testing.T) {
+	journal := newTestJournal(t, time.Hour)
+
+	journal.Submit("session-1", "", "first")
+	journal.Submit("session-1", "", "second")
+	if keys := entryKeys(t, journal); len(keys) != 0 {
+		t.Fatalf("entries written before the delay: %v", keys)
+	}
+
+	if err := journal.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	keys := entryKeys(t, journal)
+	if len(keys) != 1 {
+		t.Fatalf("got entries %v, want one", keys)
+	}
+	entry, err := journal.Entry(keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Data != "second" || entry.SessionID != "session-1" || !entry.Untitled() {
+		t.Errorf("entry = %+v, want the latest untitled state", entry)
+	}
+	if entry.SavedAt != 1700000000000 {
+		t.Errorf("SavedAt = %d, want the injected clock", entry.SavedAt)
+	}
+}
+
+func TestSubmitDebounces(t *testing.T) {
+	journal := newTestJournal(t, 20*time.Millisecond)
+	canvasPath := filepath.Join(t.TempDir(), "story.thoughtorio")
+	key := KeyForPath(canvasPath)
+
+	for _, data := range []string{"a", "b", "c"} {
+		journal.Submit("session-1", canvasPath, data)
+	}
+
+	entry := waitForEntry(t, journal, key)
+	if entry.Data != "c" || entry.CanvasPath != canvasPath {
+		t.Errorf("entry = %+v, want the last state for %s", entry, canvasPath)
+	}
+}
+
+func TestSubmitSkipsUnchangedData(t *testing.T) {
+	journal := newTestJournal(t, time.Hour)
+	canvasPath := filepath.Join(t.TempDir(), "story.thoughtorio")
+	key := KeyForPath(canvasPath)
+
+	journal.Submit("session-1", canvasPath, "same")
+	if err := journal.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(journal.dir, key+".json")
+	if err := os.Chtimes(path, time.Unix(1, 0), time.Unix(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	journal.Submit("session-1", canvasPath, "same")
+	if err := journal.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(time.Unix(1, 0)) {
+		t.Error("unchanged data was written again")
+	}
+}
+
+func TestSavingUntitledCanvasReplacesItsEntry(t *testing.T) {
+	journal := newTestJournal(t, time.Hour)
+	canvasPath := filepath.Join(t.TempDir(), "story.thoughtorio")
+
+	journal.Submit("session-1", "", "draft")
+	if err := journal.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	untitled := entryKeys(t, journal)
+	if len(untitled) != 1 {
+		t.Fatalf("got entries %v, want one untitled entry", untitled)
+	}
+
+	// The canvas has been saved and edited again; its journal follows the path
+	journal.Submit("session-1", canvasPath, "edited")
+	if keys := entryKeys(t, journal); len(keys) != 1 || keys[0] != untitled[0] {
+		t.Fatalf("untitled entry removed before its replacement was written: %v", keys)
+	}
+	if err := journal.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := entryKeys(t, journal)
+	if len(keys) != 1 || keys[0] != KeyForPath(canvasPath) {
+		t.Fatalf("got entries %v, want only %s", keys, KeyForPath(canvasPath))
+	}
+}
+
+func TestAdoptKeepsRecoveredEntry(t *testing.T) {
+	journal := newTestJournal(t, time.Hour)
+
+	journal.Submit("crashed-session", "", "recovered")
+	if err := journal.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	recovered := entryKeys(t, journal)[0]
+
+	// A new session recovers the canvas and keeps editing it
+	journal.Adopt(recovered, "session-2")
+	journal.Submit("session-2", "", "edited after recovery")
+	if err := journal.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := entryKeys(t, journal)
+	if len(keys) != 1 || keys[0] != recovered {
+		t.Fatalf("got entries %v, want the adopted entry %s only", keys, recovered)
+	}
+	entry, err := journal.Entry(recovered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Data != "edited after recovery" || entry.SessionID != "session-2" {
+		t.Errorf("entry = %+v, want session-2's edit", entry)
+	}
+
+	if err := journal.DiscardSession("session-2"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := entryKeys(t, journal); len(keys) != 0 {
+		t.Errorf("entries after DiscardSession = %v, want none", keys)
+	}
+}
+
+func TestDiscardCancelsPendingWrite(t *testing.T) {
+	journal := newTestJournal(t, 20*time.Millisecond)
+	canvasPath := filepath.Join(t.TempDir(), "story.thoughtorio")
+
+	journal.Submit("session-1", canvasPath, "unsaved")
+	if err := journal.DiscardPath(canvasPath); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	if keys := entryKeys(t, journal); len(keys) != 0 {
+		t.Errorf("entries after discard = %v, want none", keys)
+	}
+}
+
+func TestEntryRejectsForeignKeys(t *testing.T) {
+	journal := newTestJournal(t, time.Hour)
+	for _, key := range []string{"../settings", "canvas-../../x", "notes", "canvas-a.b"} {
+		if _, err := journal.Entry(key); err == nil {
+			t.Errorf("Entry(%q) succeeded", key)
+		}
+	}
+}
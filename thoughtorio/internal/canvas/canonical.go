@@ -0,0 +1,224 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Fields that change without the canvas changing: the save time and pan and
+// zoom on every save, and run state every time the canvas is executed. They are
+// left out of canonical files, so saving or running a workflow doesn't make its
+// canvas differ from the file. Callers keep the viewport beside the file.
+var (
+	volatileDocumentFields  = []string{"modified", "viewport"}
+	volatileExecutionFields = []string{"state", "started_at", "completed_at", "error"}
+	volatileInputFields     = []string{"received_at"}
+)
+
+// MarshalCanonical encodes a document in the form canvases are saved in, which
+// keeps diffs between saves small and lets version control merge them:
+//
+//   - object members are sorted by key
+//   - nodes, connections and containers are sorted by ID
+//   - each top-level member is on its own line, and each node, connection and
+//     container on its own line within its list
+//   - run state such as execution timestamps is left out, as are the save
+//     time and the viewport
+//
+// The result is ordinary JSON, so Parse reads canonical and earlier files alike.
+func MarshalCanonical(doc *Document) ([]byte, error) {
+	members, err := canonicalMembers(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for i, key := range keys {
+		name, err := compactJSON(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("  " + string(name) + ": ")
+		if err := writeCanonicalValue(&buf, members[key]); err != nil {
+			return nil, err
+		}
+		if i < len(keys)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// Canonical returns the document as it reads back from its canonical form
+func Canonical(doc *Document) (*Document, error) {
+	data, err := MarshalCanonical(doc)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// canonicalMembers decodes the document's members generically, with lists in
+// ID order and run state removed
+func canonicalMembers(doc *Document) (map[string]interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode canvas: %w", err)
+	}
+	var members map[string]interface{}
+	if err := decodeGeneric(data, &members); err != nil {
+		return nil, err
+	}
+
+	for _, key := range volatileDocumentFields {
+		delete(members, key)
+	}
+	for _, key := range []string{"nodes", "connections", "containers"} {
+		if list, ok := members[key].([]interface{}); ok {
+			sortByID(list)
+		}
+	}
+	if nodes, ok := members["nodes"].([]interface{}); ok {
+		for _, node := range nodes {
+			if node, ok := node.(map[string]interface{}); ok {
+				removeVolatile(node)
+			}
+		}
+	}
+	if containers, ok := members["containers"].([]interface{}); ok {
+		for _, container := range containers {
+			container, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// Membership is a set; the frontend lists it in whatever order it found it
+			for _, key := range []string{"nodeIds", "containerIds"} {
+				if ids, ok := container[key].([]interface{}); ok {
+					sortStrings(ids)
+				}
+			}
+		}
+	}
+	return members, nil
+}
+
+// removeVolatile deletes the run state from an encoded node
+func removeVolatile(node map[string]interface{}) {
+	data, ok := node["data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if execution, ok := data["execution"].(map[string]interface{}); ok {
+		for _, field := range volatileExecutionFields {
+			delete(execution, field)
+		}
+		if len(execution) == 0 {
+			delete(data, "execution")
+		}
+	}
+	if inputs, ok := data["inputs"].([]interface{}); ok {
+		for _, input := range inputs {
+			if input, ok := input.(map[string]interface{}); ok {
+				for _, field := range volatileInputFields {
+					delete(input, field)
+				}
+			}
+		}
+	}
+}
+
+// sortByID orders a list of objects by their "id" member. The sort is stable,
+// so entries without an ID, or sharing one, keep their relative order.
+func sortByID(list []interface{}) {
+	id := func(i int) string {
+		if object, ok := list[i].(map[string]interface{}); ok {
+			if id, ok := object["id"].(string); ok {
+				return id
+			}
+		}
+		return ""
+	}
+	sort.SliceStable(list, func(a, b int) bool { return id(a) < id(b) })
+}
+
+// sortStrings orders a list of strings, leaving it alone if it holds anything else
+func sortStrings(list []interface{}) {
+	for _, value := range list {
+		if _, ok := value.(string); !ok {
+			return
+		}
+	}
+	sort.SliceStable(list, func(a, b int) bool { return list[a].(string) < list[b].(string) })
+}
+
+// writeCanonicalValue writes a top-level member: lists of objects one entry
+// per line, anything else on a single line
+func writeCanonicalValue(buf *bytes.Buffer, value interface{}) error {
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 || !allObjects(list) {
+		data, err := compactJSON(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		return nil
+	}
+
+	buf.WriteString("[\n")
+	for i, entry := range list {
+		data, err := compactJSON(entry)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("    ")
+		buf.Write(data)
+		if i < len(list)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("  ]")
+	return nil
+}
+
+func allObjects(list []interface{}) bool {
+	for _, entry := range list {
+		if _, ok := entry.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compactJSON encodes a value on one line with object keys sorted. HTML
+// characters are written as they are, so prompts read naturally in a diff.
+func compactJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to encode canvas: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// decodeGeneric decodes JSON into maps and slices, keeping numbers exactly as written
+func decodeGeneric(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to decode canvas: %w", err)
+	}
+	return nil
+}
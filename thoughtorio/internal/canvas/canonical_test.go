@@ -0,0 +1,96 @@
+package canvas
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCanonicalGoldenFiles saves every loadable canvas in testdata/migrations
+// in canonical form and compares it against testdata/canonical. Run with
+// -update after an intended change to the form.
+func TestCanonicalGoldenFiles(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "migrations", "*.thoughtorio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".thoughtorio")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, _, report := Load(data)
+			if report.Err() != nil {
+				t.Skip("canvas doesn't load")
+			}
+
+			got, err := MarshalCanonical(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "canonical", name+".thoughtorio")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("canonical form of %s no longer matches %s\ngot:\n%s", input, golden, got)
+			}
+		})
+	}
+}
+
+// TestCanonicalFormIsStable checks that a canonical file loads and saves back
+// byte for byte, whatever order its nodes and connections were in and wherever
+// the canvas was panned to
+func TestCanonicalFormIsStable(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "migrations", "v2-current.thoughtorio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _, report := Load(data)
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := MarshalCanonical(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, _, report := Load(saved)
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for i, j := 0, len(again.Nodes)-1; i < j; i, j = i+1, j-1 {
+		again.Nodes[i], again.Nodes[j] = again.Nodes[j], again.Nodes[i]
+	}
+	for i, j := 0, len(again.Connections)-1; i < j; i, j = i+1, j-1 {
+		again.Connections[i], again.Connections[j] = again.Connections[j], again.Connections[i]
+	}
+	// Saving again stamps a new save time and wherever the canvas was panned to
+	again.Modified++
+	again.Viewport = &Viewport{X: 120, Y: -40, Zoom: 1.5}
+	resaved, err := MarshalCanonical(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, resaved) {
+		t.Errorf("canonical form changed on a second save\nfirst:\n%s\nsecond:\n%s", saved, resaved)
+	}
+}
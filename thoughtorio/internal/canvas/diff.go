@@ -0,0 +1,203 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// Diff lists the nodes and connections that differ between two versions of a canvas
+type Diff struct {
+	NodesAdded         []string `json:"nodesAdded"`
+	NodesRemoved       []string `json:"nodesRemoved"`
+	NodesChanged       []string `json:"nodesChanged"`
+	ConnectionsAdded   []string `json:"connectionsAdded"`
+	ConnectionsRemoved []string `json:"connectionsRemoved"`
+	ConnectionsChanged []string `json:"connectionsChanged"`
+	// Changes details the fields that differ in each changed node and connection
+	Changes []ItemChange `json:"changes"`
+}
+
+// Kinds of item an ItemChange describes
+const (
+	ItemNode       = "node"
+	ItemConnection = "connection"
+)
+
+// ItemChange lists the fields that differ between two versions of a node or connection
+type ItemChange struct {
+	Kind   string        `json:"kind"`
+	ID     string        `json:"id"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is one field that differs, named by its JSON path such as
+// "content" or "data.output.value". From is absent when the field was added
+// and To when it was removed.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from,omitempty"`
+	To    json.RawMessage `json:"to,omitempty"`
+	// Lines is a line diff of the two values when both are text
+	Lines []LineChange `json:"lines,omitempty"`
+}
+
+// DiffSummary counts the differences in a Diff
+type DiffSummary struct {
+	NodesAdded         int `json:"nodesAdded"`
+	NodesRemoved       int `json:"nodesRemoved"`
+	NodesChanged       int `json:"nodesChanged"`
+	ConnectionsAdded   int `json:"connectionsAdded"`
+	ConnectionsRemoved int `json:"connectionsRemoved"`
+	ConnectionsChanged int `json:"connectionsChanged"`
+}
+
+// Compare diffs two documents by node and connection ID. Either may be nil,
+// standing for an empty canvas.
+func Compare(from, to *Document) Diff {
+	if from == nil {
+		from = &Document{}
+	}
+	if to == nil {
+		to = &Document{}
+	}
+
+	diff := Diff{Changes: []ItemChange{}}
+	fromNodes, toNodes := nodesByID(from), nodesByID(to)
+	diff.NodesAdded, diff.NodesRemoved, diff.NodesChanged = compareByID(fromNodes, toNodes)
+	for _, id := range diff.NodesChanged {
+		diff.Changes = append(diff.Changes, ItemChange{Kind: ItemNode, ID: id, Fields: compareFields(fromNodes[id], toNodes[id])})
+	}
+
+	fromConns, toConns := connectionsByID(from), connectionsByID(to)
+	diff.ConnectionsAdded, diff.ConnectionsRemoved, diff.ConnectionsChanged = compareByID(fromConns, toConns)
+	for _, id := range diff.ConnectionsChanged {
+		diff.Changes = append(diff.Changes, ItemChange{Kind: ItemConnection, ID: id, Fields: compareFields(fromConns[id], toConns[id])})
+	}
+	return diff
+}
+
+// Summary counts the differences
+func (d Diff) Summary() DiffSummary {
+	return DiffSummary{
+		NodesAdded:         len(d.NodesAdded),
+		NodesRemoved:       len(d.NodesRemoved),
+		NodesChanged:       len(d.NodesChanged),
+		ConnectionsAdded:   len(d.ConnectionsAdded),
+		ConnectionsRemoved: len(d.ConnectionsRemoved),
+		ConnectionsChanged: len(d.ConnectionsChanged),
+	}
+}
+
+// Empty reports whether the two versions have the same nodes and connections
+func (d Diff) Empty() bool {
+	return d.Summary() == DiffSummary{}
+}
+
+// nodesByID encodes each node keyed by its ID, leaving out run state as
+// canonical files do, so running a node doesn't count as changing it
+func nodesByID(doc *Document) map[string][]byte {
+	encoded := make(map[string][]byte, len(doc.Nodes))
+	for _, node := range doc.Nodes {
+		data, _ := json.Marshal(node)
+		var members map[string]interface{}
+		if decodeGeneric(data, &members) == nil {
+			removeVolatile(members)
+			data, _ = compactJSON(members)
+		}
+		encoded[node.ID] = data
+	}
+	return encoded
+}
+
+// connectionsByID encodes each connection keyed by its ID
+func connectionsByID(doc *Document) map[string][]byte {
+	encoded := make(map[string][]byte, len(doc.Connections))
+	for _, conn := range doc.Connections {
+		data, _ := json.Marshal(conn)
+		encoded[conn.ID] = data
+	}
+	return encoded
+}
+
+// compareByID returns the sorted IDs only in to, only in from, and in both with different encodings
+func compareByID(from, to map[string][]byte) (added, removed, changed []string) {
+	added, removed, changed = []string{}, []string{}, []string{}
+	for id, data := range to {
+		previous, existed := from[id]
+		switch {
+		case !existed:
+			added = append(added, id)
+		case !bytes.Equal(previous, data):
+			changed = append(changed, id)
+		}
+	}
+	for id := range from {
+		if _, kept := to[id]; !kept {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// compareFields lists the differing leaf fields of two encoded items, sorted by path
+func compareFields(from, to []byte) []FieldChange {
+	fromFields, toFields := map[string]json.RawMessage{}, map[string]json.RawMessage{}
+	flattenJSON("", from, fromFields)
+	flattenJSON("", to, toFields)
+
+	paths := []string{}
+	for path, value := range toFields {
+		if previous, ok := fromFields[path]; !ok || !bytes.Equal(previous, value) {
+			paths = append(paths, path)
+		}
+	}
+	for path := range fromFields {
+		if _, ok := toFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	fields := make([]FieldChange, 0, len(paths))
+	for _, path := range paths {
+		change := FieldChange{Field: path, From: fromFields[path], To: toFields[path]}
+		var fromText, toText string
+		if json.Unmarshal(change.From, &fromText) == nil && json.Unmarshal(change.To, &toText) == nil {
+			change.Lines = DiffLines(fromText, toText)
+		}
+		fields = append(fields, change)
+	}
+	return fields
+}
+
+// flattenJSON records each leaf of an encoded value under its dotted path.
+// Arrays are indexed, as in "inputs.0.source_id"; empty objects and arrays are leaves.
+func flattenJSON(path string, data []byte, fields map[string]json.RawMessage) {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(data, &object) == nil && len(object) > 0 {
+		for key, value := range object {
+			flattenJSON(joinPath(path, key), value, fields)
+		}
+		return
+	}
+	var array []json.RawMessage
+	if json.Unmarshal(data, &array) == nil && len(array) > 0 {
+		for i, value := range array {
+			flattenJSON(joinPath(path, strconv.Itoa(i)), value, fields)
+		}
+		return
+	}
+	fields[path] = data
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
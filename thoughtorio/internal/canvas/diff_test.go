@@ -0,0 +1,93 @@
+package canvas
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	from := validDocument()
+	from.Nodes[1].Content = "first line\nsecond line"
+	from.Nodes = append(from.Nodes, Node{ID: "node-3", Type: NodeTypeStatic})
+
+	to := validDocument()
+	to.Nodes[1].Content = "first line\nchanged line"
+	to.Nodes[1].X = 40
+	to.Nodes = append(to.Nodes, Node{ID: "node-4", Type: NodeTypeStatic})
+	to.Connections[0].ToPort = []byte(`"left"`)
+	to.Connections = append(to.Connections, Connection{ID: "conn-2", FromID: "node-1", ToID: "node-4"})
+
+	diff := Compare(from, to)
+	want := DiffSummary{NodesAdded: 1, NodesRemoved: 1, NodesChanged: 1, ConnectionsAdded: 1, ConnectionsChanged: 1}
+	if got := diff.Summary(); got != want {
+		t.Fatalf("Summary = %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(diff.NodesAdded, []string{"node-4"}) || !reflect.DeepEqual(diff.NodesRemoved, []string{"node-3"}) {
+		t.Errorf("nodes added %v and removed %v, want [node-4] and [node-3]", diff.NodesAdded, diff.NodesRemoved)
+	}
+	if diff.Empty() {
+		t.Error("Empty = true for differing documents")
+	}
+
+	if len(diff.Changes) != 2 {
+		t.Fatalf("Changes = %+v, want one node and one connection", diff.Changes)
+	}
+	node := diff.Changes[0]
+	if node.Kind != ItemNode || node.ID != "node-2" || len(node.Fields) != 2 {
+		t.Fatalf("node change = %+v, want content and x of node-2", node)
+	}
+	content, x := node.Fields[0], node.Fields[1]
+	if content.Field != "content" || x.Field != "x" {
+		t.Fatalf("fields = %s, %s, want content, x", content.Field, x.Field)
+	}
+	wantLines := []LineChange{
+		{Op: LineKept, Text: "first line"},
+		{Op: LineRemoved, Text: "second line"},
+		{Op: LineAdded, Text: "changed line"},
+	}
+	if !reflect.DeepEqual(content.Lines, wantLines) {
+		t.Errorf("content lines = %+v, want %+v", content.Lines, wantLines)
+	}
+	if string(x.From) != "0" || string(x.To) != "40" || x.Lines != nil {
+		t.Errorf("x change = %+v, want 0 to 40 without lines", x)
+	}
+
+	conn := diff.Changes[1]
+	if conn.Kind != ItemConnection || conn.ID != "conn-1" || len(conn.Fields) != 1 {
+		t.Fatalf("connection change = %+v, want toPort of conn-1", conn)
+	}
+	if field := conn.Fields[0]; field.Field != "toPort" || field.From != nil || string(field.To) != `"left"` {
+		t.Errorf("toPort change = %+v, want added as \"left\"", field)
+	}
+}
+
+func TestCompareIgnoresRunStateAndOrder(t *testing.T) {
+	from := validDocument()
+	to := validDocument()
+	to.Nodes[0], to.Nodes[1] = to.Nodes[1], to.Nodes[0]
+	for i := range to.Nodes {
+		if to.Nodes[i].Data != nil {
+			to.Nodes[i].Data.Execution.State = "completed"
+		}
+	}
+
+	if diff := Compare(from, to); !diff.Empty() {
+		t.Errorf("Compare = %+v, want no differences", diff)
+	}
+}
+
+func TestCompareWithNil(t *testing.T) {
+	doc := validDocument()
+
+	added := Compare(nil, doc)
+	if want := (DiffSummary{NodesAdded: 2, ConnectionsAdded: 1}); added.Summary() != want {
+		t.Errorf("Compare(nil, doc) = %+v, want %+v", added.Summary(), want)
+	}
+	removed := Compare(doc, nil)
+	if want := (DiffSummary{NodesRemoved: 2, ConnectionsRemoved: 1}); removed.Summary() != want {
+		t.Errorf("Compare(doc, nil) = %+v, want %+v", removed.Summary(), want)
+	}
+	if diff := Compare(nil, nil); !diff.Empty() || diff.Changes == nil {
+		t.Errorf("Compare(nil, nil) = %+v, want empty with a non-nil change list", diff)
+	}
+}
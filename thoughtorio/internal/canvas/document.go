@@ -0,0 +1,316 @@
+// Package canvas models the .thoughtorio document: the nodes and their data,
+// the connections between them, workflow containers, viewport and metadata.
+// Members the types don't declare are kept, so a file written by a newer
+// version survives being loaded and saved by this one.
+package canvas
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Node types the canvas knows how to render and execute
+const (
+	NodeTypeStatic  = "static"
+	NodeTypeInput   = "input"
+	NodeTypeDynamic = "dynamic"
+)
+
+// Container types derived from how nodes are connected
+const (
+	ContainerTypeMachine = "machine"
+	ContainerTypeFactory = "factory"
+	ContainerTypeNetwork = "network"
+)
+
+// Document is a whole canvas file
+type Document struct {
+	// FormatVersion is the document format; see FormatVersion and Migrate
+	FormatVersion int          `json:"formatVersion"`
+	Version       string       `json:"version"`
+	Created       int64        `json:"created,omitempty"`
+	Modified      int64        `json:"modified,omitempty"`
+	Viewport      *Viewport    `json:"viewport,omitempty"`
+	Nodes         []Node       `json:"nodes"`
+	Connections   []Connection `json:"connections"`
+	// Containers are derived by the frontend and saved for reference only
+	Containers []Container `json:"containers,omitempty"`
+	Metadata   Metadata    `json:"metadata"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Viewport is the pan and zoom the canvas was saved with
+type Viewport struct {
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	Zoom float64 `json:"zoom"`
+}
+
+// Node is a node as placed on the canvas
+type Node struct {
+	ID      string  `json:"id"`
+	Type    string  `json:"type"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Width   float64 `json:"width"`
+	Height  float64 `json:"height"`
+	Content string  `json:"content"`
+	Title   string  `json:"title"`
+	Created int64   `json:"created,omitempty"`
+	// Data is the node's workflow state; canvases saved before it was stored have none
+	Data *NodeData `json:"data,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// NodeData is the workflow state behind a node, as kept by the frontend's NodeData class
+type NodeData struct {
+	NodeType string       `json:"node_type"`
+	ID       string       `json:"id"`
+	Content  string       `json:"content"`
+	Metadata NodeMetadata `json:"metadata"`
+	// Inputs is nil for static nodes, which can't receive any
+	Inputs     []NodeInput     `json:"inputs"`
+	Processing json.RawMessage `json:"processing,omitempty"`
+	Output     NodeOutput      `json:"output"`
+	Execution  NodeExecution   `json:"execution"`
+	// Purpose says how an input node contributes to context: "fact" or "task"
+	Purpose string `json:"purpose,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// NodeMetadata describes a node's data
+type NodeMetadata struct {
+	Title     string `json:"title"`
+	CreatedAt string `json:"created_at"`
+	Version   int    `json:"version"`
+	Modified  bool   `json:"modified"`
+}
+
+// NodeInput is a value received from an upstream node
+type NodeInput struct {
+	SourceID     string          `json:"source_id"`
+	Data         json.RawMessage `json:"data"`
+	Weight       float64         `json:"weight"`
+	ReceivedAt   string          `json:"received_at,omitempty"`
+	ContextChain json.RawMessage `json:"context_chain,omitempty"`
+	Sources      []string        `json:"sources,omitempty"`
+}
+
+// NodeOutput is what a node passes downstream
+type NodeOutput struct {
+	// Type is "text" or "structured_context"
+	Type string `json:"type"`
+	// Value is a string for text and an object for structured context
+	Value        json.RawMessage `json:"value"`
+	Sources      []string        `json:"sources"`
+	ContextChain json.RawMessage `json:"context_chain,omitempty"`
+}
+
+// Execution states of a node
+const (
+	ExecutionIdle      = "idle"
+	ExecutionExecuting = "executing"
+	ExecutionCompleted = "completed"
+	ExecutionError     = "error"
+)
+
+// NodeExecution is where a node is in its last run
+type NodeExecution struct {
+	State       string  `json:"state"`
+	StartedAt   *string `json:"started_at"`
+	CompletedAt *string `json:"completed_at"`
+	Error       *string `json:"error"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Connection links an output port to an input port
+type Connection struct {
+	ID       string          `json:"id"`
+	FromID   string          `json:"fromId"`
+	ToID     string          `json:"toId"`
+	FromPort json.RawMessage `json:"fromPort,omitempty"`
+	ToPort   json.RawMessage `json:"toPort,omitempty"`
+	Created  int64           `json:"created,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Container is a machine, factory or network grouping nodes on the canvas
+type Container struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// NodeIDs are the nodes inside the container
+	NodeIDs []string `json:"nodeIds,omitempty"`
+	// ContainerIDs are the machines and factories inside a factory or network
+	ContainerIDs []string `json:"containerIds,omitempty"`
+	Bounds       Bounds   `json:"bounds"`
+}
+
+// Bounds is a rectangle on the canvas
+type Bounds struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Metadata describes the canvas as a whole
+type Metadata struct {
+	NodeCount       int `json:"nodeCount"`
+	ConnectionCount int `json:"connectionCount"`
+	// Profile is the profile the canvas is pinned to; opening it offers to switch
+	Profile string `json:"profile,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Parse decodes a canvas document
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("canvas isn't valid JSON: %w", err)
+	}
+	return &doc, nil
+}
+
+// Marshal encodes a canvas document for writing to disk
+func Marshal(doc *Document) ([]byte, error) {
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// NodeIndex maps node IDs to their position in Nodes
+func (d *Document) NodeIndex() map[string]int {
+	index := make(map[string]int, len(d.Nodes))
+	for i, node := range d.Nodes {
+		if _, seen := index[node.ID]; !seen {
+			index[node.ID] = i
+		}
+	}
+	return index
+}
+
+// UpdateCounts sets the metadata counts from the nodes and connections
+func (d *Document) UpdateCounts() {
+	d.Metadata.NodeCount = len(d.Nodes)
+	d.Metadata.ConnectionCount = len(d.Connections)
+}
+
+type documentFields Document
+
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var fields documentFields
+	extra, err := decodeWithExtra(data, &fields)
+	if err != nil {
+		return err
+	}
+	*d = Document(fields)
+	d.Extra = extra
+	return nil
+}
+
+func (d Document) MarshalJSON() ([]byte, error) {
+	if d.Nodes == nil {
+		d.Nodes = []Node{}
+	}
+	if d.Connections == nil {
+		d.Connections = []Connection{}
+	}
+	return encodeWithExtra(documentFields(d), d.Extra)
+}
+
+type nodeFields Node
+
+func (n *Node) UnmarshalJSON(data []byte) error {
+	var fields nodeFields
+	extra, err := decodeWithExtra(data, &fields)
+	if err != nil {
+		return err
+	}
+	*n = Node(fields)
+	n.Extra = extra
+	return nil
+}
+
+func (n Node) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(nodeFields(n), n.Extra)
+}
+
+type nodeDataFields NodeData
+
+func (n *NodeData) UnmarshalJSON(data []byte) error {
+	var fields nodeDataFields
+	extra, err := decodeWithExtra(data, &fields)
+	if err != nil {
+		return err
+	}
+	*n = NodeData(fields)
+	n.Extra = extra
+	// Canonical files leave out the run state, which starts over on loading
+	if n.Execution.State == "" {
+		n.Execution.State = ExecutionIdle
+	}
+	return nil
+}
+
+func (n NodeData) MarshalJSON() ([]byte, error) {
+	// Static nodes have no inputs member at all, while other nodes keep an empty list
+	if n.Inputs == nil {
+		return encodeWithExtra(nodeDataFields(n), n.Extra, "inputs")
+	}
+	return encodeWithExtra(nodeDataFields(n), n.Extra)
+}
+
+type nodeExecutionFields NodeExecution
+
+func (e *NodeExecution) UnmarshalJSON(data []byte) error {
+	var fields nodeExecutionFields
+	extra, err := decodeWithExtra(data, &fields)
+	if err != nil {
+		return err
+	}
+	*e = NodeExecution(fields)
+	e.Extra = extra
+	return nil
+}
+
+func (e NodeExecution) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(nodeExecutionFields(e), e.Extra)
+}
+
+type connectionFields Connection
+
+func (c *Connection) UnmarshalJSON(data []byte) error {
+	var fields connectionFields
+	extra, err := decodeWithExtra(data, &fields)
+	if err != nil {
+		return err
+	}
+	*c = Connection(fields)
+	c.Extra = extra
+	return nil
+}
+
+func (c Connection) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(connectionFields(c), c.Extra)
+}
+
+type metadataFields Metadata
+
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	var fields metadataFields
+	extra, err := decodeWithExtra(data, &fields)
+	if err != nil {
+		return err
+	}
+	*m = Metadata(fields)
+	m.Extra = extra
+	return nil
+}
+
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(metadataFields(m), m.Extra)
+}
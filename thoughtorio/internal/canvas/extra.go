@@ -0,0 +1,86 @@
+package canvas
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// knownKeys caches the JSON member names each struct type declares
+var knownKeys sync.Map
+
+// fieldNames returns the JSON member names a struct type declares
+func fieldNames(t reflect.Type) map[string]bool {
+	if cached, ok := knownKeys.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	knownKeys.Store(t, names)
+	return names
+}
+
+// decodeWithExtra decodes data into v, a pointer to a struct without custom
+// decoding, and returns the members v has no field for so they survive a save
+func decodeWithExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+
+	known := fieldNames(reflect.TypeOf(v).Elem())
+	var extra map[string]json.RawMessage
+	for key, value := range members {
+		if known[key] {
+			continue
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[key] = value
+	}
+	return extra, nil
+}
+
+// encodeWithExtra encodes v, a struct without custom encoding, together with
+// the extra members preserved from the file it was read from
+func encodeWithExtra(v interface{}, extra map[string]json.RawMessage, omit ...string) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(extra) == 0 && len(omit) == 0 {
+		return data, nil
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		if _, declared := members[key]; !declared {
+			members[key] = value
+		}
+	}
+	for _, key := range omit {
+		delete(members, key)
+	}
+	return json.Marshal(members)
+}
@@ -0,0 +1,197 @@
+package canvas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// FormatVersion is the document format this version writes. Files without a
+// formatVersion predate it and are format 1.
+const FormatVersion = 2
+
+// legacyFormatVersion is the format of documents with no formatVersion member
+const legacyFormatVersion = 1
+
+// Defaults the frontend gives new nodes, used to fill in sizes older files lack
+const (
+	defaultNodeWidth  = 250
+	defaultNodeHeight = 120
+)
+
+// ErrNewerFormat is returned for documents written by a newer version of the app
+var ErrNewerFormat = errors.New("canvas was saved by a newer version of Thoughtorio")
+
+// Migration describes how a document was upgraded on load
+type Migration struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	// Steps describes each upgrade applied, oldest first
+	Steps []string `json:"steps"`
+	// BackupPath is where the original file was copied before upgrading, when it was
+	BackupPath string `json:"backupPath,omitempty"`
+}
+
+// migration upgrades a decoded document from one format version to the next
+type migration struct {
+	from     int
+	describe string
+	apply    func(doc map[string]interface{}) error
+}
+
+// migrations are applied in order; each moves a document up exactly one version
+var migrations = []migration{
+	{from: 1, describe: "add formatVersion, fill in missing node sizes, connection IDs and node data identity, and reset interrupted executions", apply: migrateV1},
+}
+
+// DetectFormatVersion returns the format version a document declares
+func DetectFormatVersion(data []byte) (int, error) {
+	var header struct {
+		FormatVersion *int `json:"formatVersion"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, fmt.Errorf("canvas isn't valid JSON: %w", err)
+	}
+	if header.FormatVersion == nil {
+		return legacyFormatVersion, nil
+	}
+	if *header.FormatVersion < legacyFormatVersion {
+		return 0, fmt.Errorf("canvas has an invalid format version %d", *header.FormatVersion)
+	}
+	return *header.FormatVersion, nil
+}
+
+// Migrate upgrades a document to FormatVersion one step at a time. Current
+// documents are returned unchanged with a nil Migration.
+func Migrate(data []byte) ([]byte, *Migration, error) {
+	version, err := DetectFormatVersion(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version > FormatVersion {
+		return nil, nil, fmt.Errorf("%w (format %d, this version reads up to %d)", ErrNewerFormat, version, FormatVersion)
+	}
+	if version == FormatVersion {
+		return data, nil, nil
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("canvas isn't valid JSON: %w", err)
+	}
+
+	result := &Migration{From: version, To: version}
+	for _, step := range migrations {
+		if step.from != result.To {
+			continue
+		}
+		if err := step.apply(doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to upgrade canvas from format %d: %w", step.from, err)
+		}
+		result.To = step.from + 1
+		doc["formatVersion"] = result.To
+		result.Steps = append(result.Steps, fmt.Sprintf("%d→%d: %s", step.from, result.To, step.describe))
+	}
+	if result.To != FormatVersion {
+		return nil, nil, fmt.Errorf("no upgrade path from canvas format %d", result.To)
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrated, result, nil
+}
+
+// migrateV1 upgrades documents saved before formatVersion existed. Those files
+// could hold nodes without a size, connections without an ID, node data out of
+// step with its node and executions frozen mid-run when the canvas was saved.
+func migrateV1(doc map[string]interface{}) error {
+	nodes, err := objectList(doc, "nodes")
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if !isPositive(node["width"]) {
+			node["width"] = defaultNodeWidth
+		}
+		if !isPositive(node["height"]) {
+			node["height"] = defaultNodeHeight
+		}
+
+		data, ok := node["data"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, _ := data["id"].(string); id == "" {
+			data["id"] = node["id"]
+		}
+		if nodeType, _ := data["node_type"].(string); nodeType == "" {
+			data["node_type"] = node["type"]
+		}
+		// A run can't survive a restart, so a saved "executing" state is stale
+		if execution, ok := data["execution"].(map[string]interface{}); ok && execution["state"] == ExecutionExecuting {
+			execution["state"] = ExecutionIdle
+			execution["started_at"] = nil
+		}
+	}
+
+	connections, err := objectList(doc, "connections")
+	if err != nil {
+		return err
+	}
+	used := map[string]bool{}
+	for _, conn := range connections {
+		if id, _ := conn["id"].(string); id != "" {
+			used[id] = true
+		}
+	}
+	for i, conn := range connections {
+		if id, _ := conn["id"].(string); id != "" {
+			continue
+		}
+		id := fmt.Sprintf("connection-%d", i+1)
+		for n := 2; used[id]; n++ {
+			id = fmt.Sprintf("connection-%d-%d", i+1, n)
+		}
+		used[id] = true
+		conn["id"] = id
+	}
+
+	metadata, ok := doc["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+		doc["metadata"] = metadata
+	}
+	metadata["nodeCount"] = len(nodes)
+	metadata["connectionCount"] = len(connections)
+	return nil
+}
+
+// objectList returns the objects in an array member, creating an empty one when it is missing
+func objectList(doc map[string]interface{}, key string) ([]map[string]interface{}, error) {
+	raw, present := doc[key]
+	if !present || raw == nil {
+		doc[key] = []interface{}{}
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list", key)
+	}
+	objects := make([]map[string]interface{}, 0, len(items))
+	for i, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be an object", key, i)
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// isPositive reports whether a decoded JSON value is a number above zero
+func isPositive(value interface{}) bool {
+	number, ok := value.(float64)
+	return ok && number > 0
+}
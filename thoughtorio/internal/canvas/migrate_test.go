@@ -0,0 +1,121 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// loadOutcome is what loading a historical canvas produces, as recorded in its golden file
+type loadOutcome struct {
+	Migration *Migration `json:"migration"`
+	Errors    []Issue    `json:"errors"`
+	Warnings  []Issue    `json:"warnings"`
+	Document  *Document  `json:"document"`
+}
+
+// TestMigrationGoldenFiles loads every canvas in testdata/migrations and compares
+// the upgraded document, migration steps and validation issues against its
+// .golden.json file. Run with -update after an intended format change.
+func TestMigrationGoldenFiles(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "migrations", "*.thoughtorio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no canvases in testdata/migrations")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".thoughtorio")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			doc, migration, report := Load(data)
+			got, err := json.MarshalIndent(loadOutcome{Migration: migration, Errors: report.Errors, Warnings: report.Warnings, Document: doc}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(input, ".thoughtorio") + ".golden.json"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file (run go test -update): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("loading %s no longer matches %s\ngot:\n%s", input, golden, got)
+			}
+		})
+	}
+}
+
+// TestMigratedDocumentsAreCurrent checks that a migrated canvas, once saved,
+// loads again without further migration and without changing
+func TestMigratedDocumentsAreCurrent(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "migrations", "v1-*.thoughtorio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			doc, migration, report := Load(data)
+			if err := report.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if migration == nil || migration.From != legacyFormatVersion || migration.To != FormatVersion {
+				t.Fatalf("expected a migration from %d to %d, got %+v", legacyFormatVersion, FormatVersion, migration)
+			}
+
+			saved, err := Marshal(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, migration, report := Load(saved)
+			if err := report.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if migration != nil {
+				t.Fatalf("saved document was migrated again: %+v", migration)
+			}
+
+			resaved, err := Marshal(again)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(saved, resaved) {
+				t.Errorf("document changed on a second load\nfirst:\n%s\nsecond:\n%s", saved, resaved)
+			}
+		})
+	}
+}
+
+func TestMigrateRejectsNewerFormats(t *testing.T) {
+	_, _, err := Migrate([]byte(`{"formatVersion": 99, "nodes": [], "connections": []}`))
+	if !errors.Is(err, ErrNewerFormat) {
+		t.Fatalf("expected ErrNewerFormat, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package canvas
+
+import "strings"
+
+// Line diff operations
+const (
+	LineKept    = "="
+	LineAdded   = "+"
+	LineRemoved = "-"
+)
+
+// maxLineDiffCells bounds the work DiffLines does; texts beyond it are shown
+// as wholly removed and added
+const maxLineDiffCells = 1 << 20
+
+// LineChange is one line of a line diff
+type LineChange struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines returns the lines of from and to in order, marking those only in
+// from as removed and those only in to as added. Lines in both are found by
+// longest common subsequence.
+func DiffLines(from, to string) []LineChange {
+	a, b := splitLines(from), splitLines(to)
+
+	// Trim the common prefix and suffix, which is most of a typical edit
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	changes := make([]LineChange, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		changes = append(changes, LineChange{Op: LineKept, Text: line})
+	}
+	changes = append(changes, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		changes = append(changes, LineChange{Op: LineKept, Text: line})
+	}
+	return changes
+}
+
+// diffMiddle diffs the lines left once the common prefix and suffix are trimmed
+func diffMiddle(a, b []string) []LineChange {
+	changes := []LineChange{}
+	if len(a)*len(b) > maxLineDiffCells {
+		for _, line := range a {
+			changes = append(changes, LineChange{Op: LineRemoved, Text: line})
+		}
+		for _, line := range b {
+			changes = append(changes, LineChange{Op: LineAdded, Text: line})
+		}
+		return changes
+	}
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			changes = append(changes, LineChange{Op: LineKept, Text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			changes = append(changes, LineChange{Op: LineRemoved, Text: a[i]})
+			i++
+		default:
+			changes = append(changes, LineChange{Op: LineAdded, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		changes = append(changes, LineChange{Op: LineRemoved, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		changes = append(changes, LineChange{Op: LineAdded, Text: b[j]})
+	}
+	return changes
+}
+
+// splitLines splits text into lines; empty text has none
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,76 @@
+package canvas
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	kept := func(text string) LineChange { return LineChange{Op: LineKept, Text: text} }
+	added := func(text string) LineChange { return LineChange{Op: LineAdded, Text: text} }
+	removed := func(text string) LineChange { return LineChange{Op: LineRemoved, Text: text} }
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []LineChange
+	}{
+		{name: "both empty", from: "", to: "", want: []LineChange{}},
+		{name: "unchanged", from: "a\nb", to: "a\nb", want: []LineChange{kept("a"), kept("b")}},
+		{name: "from empty", from: "", to: "a\nb", want: []LineChange{added("a"), added("b")}},
+		{name: "to empty", from: "a\nb", to: "", want: []LineChange{removed("a"), removed("b")}},
+		{name: "trailing newline ignored", from: "a\n", to: "a", want: []LineChange{kept("a")}},
+		{
+			name: "line replaced",
+			from: "a\nb\nc",
+			to:   "a\nx\nc",
+			want: []LineChange{kept("a"), removed("b"), added("x"), kept("c")},
+		},
+		{
+			name: "line inserted",
+			from: "a\nc",
+			to:   "a\nb\nc",
+			want: []LineChange{kept("a"), added("b"), kept("c")},
+		},
+		{
+			name: "common lines in the middle",
+			from: "x\na\nb\ny",
+			to:   "p\na\nq\nb\nr",
+			want: []LineChange{removed("x"), added("p"), kept("a"), added("q"), kept("b"), removed("y"), added("r")},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := DiffLines(test.from, test.to); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("DiffLines(%q, %q) = %+v, want %+v", test.from, test.to, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesBeyondLimit(t *testing.T) {
+	// Texts too large to compare line by line are replaced wholesale, common lines and all
+	lines := func(prefix string, n int) string {
+		text := make([]string, n)
+		for i := range text {
+			text[i] = prefix
+		}
+		return strings.Join(text, "\n")
+	}
+	from := "start\n" + lines("a", 1100) + "\nshared\n" + lines("b", 1) + "\nend"
+	to := "start\n" + lines("c", 1000) + "\nshared\n" + lines("d", 1) + "\nend"
+
+	changes := DiffLines(from, to)
+	counts := map[string]int{}
+	for _, change := range changes {
+		counts[change.Op]++
+	}
+	// The middles are 1102 and 1002 lines, over a million cells between them
+	if counts[LineKept] != 2 || counts[LineRemoved] != 1102 || counts[LineAdded] != 1002 {
+		t.Errorf("counts = %v, want the common prefix and suffix kept and the rest replaced", counts)
+	}
+	if changes[0] != (LineChange{Op: LineKept, Text: "start"}) || changes[len(changes)-1] != (LineChange{Op: LineKept, Text: "end"}) {
+		t.Errorf("first and last changes = %+v, %+v, want the shared start and end kept", changes[0], changes[len(changes)-1])
+	}
+}
@@ -0,0 +1,273 @@
+package canvas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities of validation issues; only errors stop a canvas loading or saving
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue codes reported by Validate
+const (
+	CodeMalformed          = "malformed"
+	CodeUnsupportedFormat  = "unsupported_format"
+	CodeMissingID          = "missing_id"
+	CodeDuplicateNodeID    = "duplicate_node_id"
+	CodeDuplicateConnID    = "duplicate_connection_id"
+	CodeDuplicateContainer = "duplicate_container_id"
+	CodeDanglingConnection = "dangling_connection"
+	CodeUnresolvedEndpoint = "unresolved_container_endpoint"
+	CodeSelfConnection     = "self_connection"
+	CodeRepeatedConnection = "repeated_connection"
+	CodeUnknownNodeType    = "unknown_node_type"
+	CodeInvalidSize        = "invalid_size"
+	CodeInvalidZoom        = "invalid_zoom"
+	CodeNodeDataMismatch   = "node_data_mismatch"
+	CodeContainerMember    = "unknown_container_member"
+	CodeCountMismatch      = "count_mismatch"
+)
+
+// Issue is one problem found in a canvas document
+type Issue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	// Path locates the problem in the document, such as "connections[3].toId"
+	Path string `json:"path"`
+	// ID is the node, connection or container the issue is about, when it has one
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// Report is the outcome of validating a canvas document
+type Report struct {
+	Valid    bool    `json:"valid"`
+	Errors   []Issue `json:"errors"`
+	Warnings []Issue `json:"warnings"`
+}
+
+// Issues returns every issue, errors first
+func (r Report) Issues() []Issue {
+	return append(append([]Issue{}, r.Errors...), r.Warnings...)
+}
+
+// Err returns an error describing the validation errors, or nil when there are none
+func (r Report) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: r.Errors}
+}
+
+// ValidationError is returned when a canvas document has validation errors
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	const shown = 3
+	messages := make([]string, 0, shown)
+	for i, issue := range e.Issues {
+		if i == shown {
+			break
+		}
+		messages = append(messages, issue.String())
+	}
+	summary := strings.Join(messages, "; ")
+	if len(e.Issues) > shown {
+		summary += fmt.Sprintf(" (and %d more)", len(e.Issues)-shown)
+	}
+	return fmt.Sprintf("invalid canvas: %s", summary)
+}
+
+// containerPrefixes are the ID prefixes of containers the frontend derives
+var containerPrefixes = []string{ContainerTypeMachine + "-", ContainerTypeFactory + "-", ContainerTypeNetwork + "-"}
+
+// isContainerID reports whether an ID names a derived container
+func isContainerID(id string) bool {
+	for _, prefix := range containerPrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validator collects issues while walking a document
+type validator struct {
+	report Report
+}
+
+func (v *validator) fail(code, path, id, format string, args ...interface{}) {
+	v.report.Errors = append(v.report.Errors, Issue{Severity: SeverityError, Code: code, Path: path, ID: id, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warn(code, path, id, format string, args ...interface{}) {
+	v.report.Warnings = append(v.report.Warnings, Issue{Severity: SeverityWarning, Code: code, Path: path, ID: id, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a canvas document. Errors are problems that would break the
+// canvas once loaded, such as duplicate node IDs or connections to nodes that
+// don't exist; warnings are inconsistencies the canvas can recover from.
+func Validate(doc *Document) Report {
+	v := &validator{}
+
+	if doc.FormatVersion > FormatVersion {
+		v.fail(CodeUnsupportedFormat, "formatVersion", "", "format %d is newer than this version reads (%d)", doc.FormatVersion, FormatVersion)
+	}
+
+	if doc.Viewport != nil && doc.Viewport.Zoom <= 0 {
+		v.fail(CodeInvalidZoom, "viewport.zoom", "", "zoom must be greater than zero, got %g", doc.Viewport.Zoom)
+	}
+
+	nodeIDs := map[string]bool{}
+	for i, node := range doc.Nodes {
+		path := fmt.Sprintf("nodes[%d]", i)
+		v.validateNode(path, node)
+		if node.ID == "" {
+			continue
+		}
+		if nodeIDs[node.ID] {
+			v.fail(CodeDuplicateNodeID, path+".id", node.ID, "node ID %q is used more than once", node.ID)
+			continue
+		}
+		nodeIDs[node.ID] = true
+	}
+
+	containerIDs := map[string]bool{}
+	for i, container := range doc.Containers {
+		path := fmt.Sprintf("containers[%d]", i)
+		if container.ID == "" {
+			v.fail(CodeMissingID, path+".id", "", "container has no ID")
+			continue
+		}
+		if containerIDs[container.ID] {
+			v.fail(CodeDuplicateContainer, path+".id", container.ID, "container ID %q is used more than once", container.ID)
+			continue
+		}
+		containerIDs[container.ID] = true
+	}
+	for i, container := range doc.Containers {
+		path := fmt.Sprintf("containers[%d]", i)
+		for j, id := range container.NodeIDs {
+			if !nodeIDs[id] {
+				v.warn(CodeContainerMember, fmt.Sprintf("%s.nodeIds[%d]", path, j), container.ID, "container %q lists node %q, which doesn't exist", container.ID, id)
+			}
+		}
+		for j, id := range container.ContainerIDs {
+			if !containerIDs[id] {
+				v.warn(CodeContainerMember, fmt.Sprintf("%s.containerIds[%d]", path, j), container.ID, "container %q lists container %q, which doesn't exist", container.ID, id)
+			}
+		}
+	}
+
+	connectionIDs := map[string]bool{}
+	links := map[string]bool{}
+	for i, conn := range doc.Connections {
+		path := fmt.Sprintf("connections[%d]", i)
+		if conn.ID == "" {
+			v.warn(CodeMissingID, path+".id", "", "connection has no ID")
+		} else if connectionIDs[conn.ID] {
+			v.fail(CodeDuplicateConnID, path+".id", conn.ID, "connection ID %q is used more than once", conn.ID)
+		} else {
+			connectionIDs[conn.ID] = true
+		}
+
+		v.validateEndpoint(path+".fromId", conn.ID, conn.FromID, nodeIDs, containerIDs)
+		v.validateEndpoint(path+".toId", conn.ID, conn.ToID, nodeIDs, containerIDs)
+
+		if conn.FromID != "" && conn.FromID == conn.ToID {
+			v.warn(CodeSelfConnection, path, conn.ID, "connection links %q to itself", conn.FromID)
+		}
+
+		link := conn.FromID + "\x00" + string(conn.FromPort) + "\x00" + conn.ToID + "\x00" + string(conn.ToPort)
+		if links[link] {
+			v.warn(CodeRepeatedConnection, path, conn.ID, "connection from %q to %q repeats an earlier one", conn.FromID, conn.ToID)
+		}
+		links[link] = true
+	}
+
+	if doc.Metadata.NodeCount != 0 && doc.Metadata.NodeCount != len(doc.Nodes) {
+		v.warn(CodeCountMismatch, "metadata.nodeCount", "", "metadata counts %d nodes but the canvas has %d", doc.Metadata.NodeCount, len(doc.Nodes))
+	}
+	if doc.Metadata.ConnectionCount != 0 && doc.Metadata.ConnectionCount != len(doc.Connections) {
+		v.warn(CodeCountMismatch, "metadata.connectionCount", "", "metadata counts %d connections but the canvas has %d", doc.Metadata.ConnectionCount, len(doc.Connections))
+	}
+
+	v.report.Valid = len(v.report.Errors) == 0
+	return v.report
+}
+
+// Check parses and validates a canvas document. The document is nil when the
+// data can't be parsed, in which case the report holds a single malformed error.
+func Check(data []byte) (*Document, Report) {
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, Report{Errors: []Issue{{Severity: SeverityError, Code: CodeMalformed, Path: "$", Message: err.Error()}}}
+	}
+	return doc, Validate(doc)
+}
+
+// Load upgrades a document read from disk to the current format, then parses
+// and validates it. The migration is nil when the document was already current.
+func Load(data []byte) (*Document, *Migration, Report) {
+	migrated, migration, err := Migrate(data)
+	if err != nil {
+		code, path := CodeUnsupportedFormat, "formatVersion"
+		if _, detectErr := DetectFormatVersion(data); detectErr != nil {
+			code, path = CodeMalformed, "$"
+		}
+		return nil, nil, Report{Errors: []Issue{{Severity: SeverityError, Code: code, Path: path, Message: err.Error()}}}
+	}
+
+	doc, report := Check(migrated)
+	return doc, migration, report
+}
+
+// validateNode checks a node on its own
+func (v *validator) validateNode(path string, node Node) {
+	if node.ID == "" {
+		v.fail(CodeMissingID, path+".id", "", "node has no ID")
+	}
+
+	switch node.Type {
+	case NodeTypeStatic, NodeTypeInput, NodeTypeDynamic:
+	default:
+		v.warn(CodeUnknownNodeType, path+".type", node.ID, "node type %q isn't known", node.Type)
+	}
+
+	if node.Width <= 0 || node.Height <= 0 {
+		v.warn(CodeInvalidSize, path, node.ID, "node is %gx%g; it will be hard to see", node.Width, node.Height)
+	}
+
+	if node.Data == nil {
+		return
+	}
+	if node.Data.ID != "" && node.Data.ID != node.ID {
+		v.fail(CodeNodeDataMismatch, path+".data.id", node.ID, "node data belongs to %q", node.Data.ID)
+	}
+	if node.Data.NodeType != "" && node.Data.NodeType != node.Type {
+		v.warn(CodeNodeDataMismatch, path+".data.node_type", node.ID, "node data is for a %s node but the node is %s", node.Data.NodeType, node.Type)
+	}
+}
+
+// validateEndpoint checks that one end of a connection exists
+func (v *validator) validateEndpoint(path, connID, id string, nodeIDs, containerIDs map[string]bool) {
+	switch {
+	case id == "":
+		v.fail(CodeDanglingConnection, path, connID, "connection end is empty")
+	case nodeIDs[id] || containerIDs[id]:
+	case isContainerID(id):
+		// Containers are rebuilt from the nodes on load, so this may still resolve
+		v.warn(CodeUnresolvedEndpoint, path, connID, "connection refers to container %q, which isn't saved with the canvas", id)
+	default:
+		v.fail(CodeDanglingConnection, path, connID, "connection refers to node %q, which doesn't exist", id)
+	}
+}
@@ -0,0 +1,293 @@
+package canvas
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// validDocument returns a two-node canvas with one connection that validates cleanly
+func validDocument() *Document {
+	return &Document{
+		FormatVersion: FormatVersion,
+		Version:       "1.0",
+		Viewport:      &Viewport{Zoom: 1},
+		Nodes: []Node{
+			{ID: "node-1", Type: NodeTypeInput, Width: 200, Height: 120, Data: &NodeData{ID: "node-1", NodeType: NodeTypeInput}},
+			{ID: "node-2", Type: NodeTypeDynamic, Width: 200, Height: 120},
+		},
+		Connections: []Connection{
+			{ID: "conn-1", FromID: "node-1", ToID: "node-2"},
+		},
+		Containers: []Container{
+			{ID: "machine-1", Type: ContainerTypeMachine, NodeIDs: []string{"node-1", "node-2"}},
+		},
+		Metadata: Metadata{NodeCount: 2, ConnectionCount: 1},
+	}
+}
+
+func TestValidateValidDocument(t *testing.T) {
+	report := Validate(validDocument())
+	if !report.Valid || len(report.Errors) != 0 || len(report.Warnings) != 0 {
+		t.Fatalf("report = %+v, want valid with no issues", report)
+	}
+	if err := report.Err(); err != nil {
+		t.Errorf("Err = %v, want nil", err)
+	}
+}
+
+func TestValidateIssues(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(doc *Document)
+		severity string
+		code     string
+		path     string
+		id       string
+	}{
+		{
+			name:     "newer format",
+			mutate:   func(doc *Document) { doc.FormatVersion = FormatVersion + 1 },
+			severity: SeverityError,
+			code:     CodeUnsupportedFormat,
+			path:     "formatVersion",
+		},
+		{
+			name:     "zero zoom",
+			mutate:   func(doc *Document) { doc.Viewport.Zoom = 0 },
+			severity: SeverityError,
+			code:     CodeInvalidZoom,
+			path:     "viewport.zoom",
+		},
+		{
+			name: "node without ID",
+			mutate: func(doc *Document) {
+				doc.Nodes = append(doc.Nodes, Node{Type: NodeTypeStatic, Width: 200, Height: 120})
+				doc.Metadata.NodeCount = 3
+			},
+			severity: SeverityError,
+			code:     CodeMissingID,
+			path:     "nodes[2].id",
+		},
+		{
+			name: "duplicate node ID",
+			mutate: func(doc *Document) {
+				doc.Nodes[1].ID = "node-1"
+				doc.Connections, doc.Containers = nil, nil
+				doc.Metadata.ConnectionCount = 0
+			},
+			severity: SeverityError,
+			code:     CodeDuplicateNodeID,
+			path:     "nodes[1].id",
+			id:       "node-1",
+		},
+		{
+			name:     "container without ID",
+			mutate:   func(doc *Document) { doc.Containers[0].ID = "" },
+			severity: SeverityError,
+			code:     CodeMissingID,
+			path:     "containers[0].id",
+		},
+		{
+			name: "duplicate container ID",
+			mutate: func(doc *Document) {
+				doc.Containers = append(doc.Containers, Container{ID: "machine-1", Type: ContainerTypeMachine})
+			},
+			severity: SeverityError,
+			code:     CodeDuplicateContainer,
+			path:     "containers[1].id",
+			id:       "machine-1",
+		},
+		{
+			name: "duplicate connection ID",
+			mutate: func(doc *Document) {
+				doc.Connections = append(doc.Connections, Connection{ID: "conn-1", FromID: "node-2", ToID: "node-1"})
+				doc.Metadata.ConnectionCount = 2
+			},
+			severity: SeverityError,
+			code:     CodeDuplicateConnID,
+			path:     "connections[1].id",
+			id:       "conn-1",
+		},
+		{
+			name:     "connection to a missing node",
+			mutate:   func(doc *Document) { doc.Connections[0].ToID = "node-9" },
+			severity: SeverityError,
+			code:     CodeDanglingConnection,
+			path:     "connections[0].toId",
+			id:       "conn-1",
+		},
+		{
+			name:     "connection with an empty end",
+			mutate:   func(doc *Document) { doc.Connections[0].FromID = "" },
+			severity: SeverityError,
+			code:     CodeDanglingConnection,
+			path:     "connections[0].fromId",
+			id:       "conn-1",
+		},
+		{
+			name:     "node data for another node",
+			mutate:   func(doc *Document) { doc.Nodes[0].Data.ID = "node-2" },
+			severity: SeverityError,
+			code:     CodeNodeDataMismatch,
+			path:     "nodes[0].data.id",
+			id:       "node-1",
+		},
+		{
+			name:     "node data for another type",
+			mutate:   func(doc *Document) { doc.Nodes[0].Data.NodeType = NodeTypeStatic },
+			severity: SeverityWarning,
+			code:     CodeNodeDataMismatch,
+			path:     "nodes[0].data.node_type",
+			id:       "node-1",
+		},
+		{
+			name:     "connection to an unsaved container",
+			mutate:   func(doc *Document) { doc.Connections[0].ToID = "factory-7" },
+			severity: SeverityWarning,
+			code:     CodeUnresolvedEndpoint,
+			path:     "connections[0].toId",
+			id:       "conn-1",
+		},
+		{
+			name:     "connection without ID",
+			mutate:   func(doc *Document) { doc.Connections[0].ID = "" },
+			severity: SeverityWarning,
+			code:     CodeMissingID,
+			path:     "connections[0].id",
+		},
+		{
+			name:     "self connection",
+			mutate:   func(doc *Document) { doc.Connections[0].ToID = "node-1" },
+			severity: SeverityWarning,
+			code:     CodeSelfConnection,
+			path:     "connections[0]",
+			id:       "conn-1",
+		},
+		{
+			name: "repeated connection",
+			mutate: func(doc *Document) {
+				doc.Connections = append(doc.Connections, Connection{ID: "conn-2", FromID: "node-1", ToID: "node-2"})
+				doc.Metadata.ConnectionCount = 2
+			},
+			severity: SeverityWarning,
+			code:     CodeRepeatedConnection,
+			path:     "connections[1]",
+			id:       "conn-2",
+		},
+		{
+			name:     "unknown node type",
+			mutate:   func(doc *Document) { doc.Nodes[1].Type = "hologram" },
+			severity: SeverityWarning,
+			code:     CodeUnknownNodeType,
+			path:     "nodes[1].type",
+			id:       "node-2",
+		},
+		{
+			name:     "zero size",
+			mutate:   func(doc *Document) { doc.Nodes[1].Width = 0 },
+			severity: SeverityWarning,
+			code:     CodeInvalidSize,
+			path:     "nodes[1]",
+			id:       "node-2",
+		},
+		{
+			name:     "container lists a missing node",
+			mutate:   func(doc *Document) { doc.Containers[0].NodeIDs = append(doc.Containers[0].NodeIDs, "node-9") },
+			severity: SeverityWarning,
+			code:     CodeContainerMember,
+			path:     "containers[0].nodeIds[2]",
+			id:       "machine-1",
+		},
+		{
+			name:     "container lists a missing container",
+			mutate:   func(doc *Document) { doc.Containers[0].ContainerIDs = []string{"machine-9"} },
+			severity: SeverityWarning,
+			code:     CodeContainerMember,
+			path:     "containers[0].containerIds[0]",
+			id:       "machine-1",
+		},
+		{
+			name:     "node count",
+			mutate:   func(doc *Document) { doc.Metadata.NodeCount = 5 },
+			severity: SeverityWarning,
+			code:     CodeCountMismatch,
+			path:     "metadata.nodeCount",
+		},
+		{
+			name:     "connection count",
+			mutate:   func(doc *Document) { doc.Metadata.ConnectionCount = 5 },
+			severity: SeverityWarning,
+			code:     CodeCountMismatch,
+			path:     "metadata.connectionCount",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := validDocument()
+			tt.mutate(doc)
+			report := Validate(doc)
+
+			issues := report.Issues()
+			if len(issues) != 1 {
+				t.Fatalf("got %d issues, want 1: %+v", len(issues), issues)
+			}
+			issue := issues[0]
+			if issue.Severity != tt.severity || issue.Code != tt.code || issue.Path != tt.path || issue.ID != tt.id {
+				t.Errorf("issue = %+v, want %s %s at %s (id %q)", issue, tt.severity, tt.code, tt.path, tt.id)
+			}
+			if issue.Message == "" {
+				t.Error("issue has no message")
+			}
+
+			wantValid := tt.severity == SeverityWarning
+			if report.Valid != wantValid {
+				t.Errorf("Valid = %v, want %v", report.Valid, wantValid)
+			}
+			var validationErr *ValidationError
+			if err := report.Err(); wantValid != (err == nil) || (!wantValid && !errors.As(err, &validationErr)) {
+				t.Errorf("Err = %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckMalformed(t *testing.T) {
+	doc, report := Check([]byte(`{"nodes": [`))
+	if doc != nil {
+		t.Error("Check returned a document for malformed JSON")
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Code != CodeMalformed || report.Errors[0].Path != "$" {
+		t.Errorf("errors = %+v, want one malformed error at $", report.Errors)
+	}
+}
+
+func TestLoadUnsupportedFormat(t *testing.T) {
+	data, err := json.Marshal(map[string]interface{}{"formatVersion": FormatVersion + 1, "nodes": []interface{}{}, "connections": []interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _, report := Load(data)
+	if doc != nil {
+		t.Error("Load returned a document in a newer format")
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Code != CodeUnsupportedFormat {
+		t.Errorf("errors = %+v, want one unsupported format error", report.Errors)
+	}
+}
+
+func TestValidationErrorSummarizes(t *testing.T) {
+	node := Node{ID: "same", Type: NodeTypeStatic, Width: 200, Height: 120}
+	doc := &Document{FormatVersion: FormatVersion, Nodes: []Node{node, node, node, node, node}}
+
+	err := Validate(doc).Err()
+	if err == nil {
+		t.Fatal("Err = nil for four duplicate node IDs")
+	}
+	want := `invalid canvas: nodes[1].id: node ID "same" is used more than once; nodes[2].id: node ID "same" is used more than once; nodes[3].id: node ID "same" is used more than once (and 1 more)`
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,173 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"thoughtorio/internal/secrets"
+)
+
+// Sources a credential can come from, in the order they are tried
+const (
+	SourceExplicit    = "explicit"
+	SourceSecret      = "secret"
+	SourceEnvironment = "environment"
+	SourceDotenv      = "dotenv"
+)
+
+// Fields a provider can be given
+const (
+	FieldAPIKey   = "apiKey"
+	FieldEndpoint = "endpoint"
+)
+
+// apiKeyVariables are the environment variables holding each provider's API key
+var apiKeyVariables = map[string]string{
+	"openai":     "OPENAI_API_KEY",
+	"openrouter": "OPENROUTER_API_KEY",
+	"gemini":     "GEMINI_API_KEY",
+	"azure":      "AZURE_OPENAI_API_KEY",
+}
+
+// endpointVariables are the environment variables holding each provider's
+// endpoint. They are only read from the environment: a .env file travels with
+// a canvas someone else wrote, and an endpoint from it could send prompts and
+// keys to their server.
+var endpointVariables = map[string]string{
+	"local": "OLLAMA_HOST",
+}
+
+// SecretStore looks up stored secrets by name
+type SecretStore interface {
+	Get(name string) (string, error)
+}
+
+// Source reports where one credential came from. The value itself is never included.
+type Source struct {
+	Provider string `json:"provider"`
+	Field    string `json:"field"`
+	// Source is empty when nothing supplied the credential
+	Source string `json:"source"`
+	// Detail names the secret, environment variable or .env file used
+	Detail string `json:"detail,omitempty"`
+}
+
+// Resolved holds the credentials found for a provider and where each came from
+type Resolved struct {
+	APIKey   string
+	Endpoint string
+	Sources  []Source
+}
+
+// Resolver finds provider credentials from, in order, the value the caller
+// passed, the secrets service, the process environment and a .env file next
+// to the canvas. Endpoints come from the environment alone.
+type Resolver struct {
+	secrets SecretStore
+	getenv  func(string) string
+}
+
+// NewResolver creates a resolver; store may be nil when no secrets service is available
+func NewResolver(store SecretStore) *Resolver {
+	return &Resolver{secrets: store, getenv: os.Getenv}
+}
+
+// Resolve returns the API key and endpoint for a provider. explicit is the key
+// the caller passed, which may be a secret reference; canvasPath locates the
+// .env file and may be empty.
+func (r *Resolver) Resolve(provider, explicit, canvasPath string) (Resolved, error) {
+	var resolved Resolved
+	var dotenv map[string]string
+	var dotenvPath string
+	if canvasPath != "" {
+		dotenvPath = filepath.Join(filepath.Dir(canvasPath), ".env")
+		dotenv = readDotenv(dotenvPath)
+	}
+
+	key, source, err := r.resolveKey(provider, explicit, dotenv, dotenvPath)
+	if err != nil {
+		return resolved, err
+	}
+	resolved.APIKey = key
+	resolved.Sources = append(resolved.Sources, source)
+
+	if variable, ok := endpointVariables[provider]; ok {
+		source := Source{Provider: provider, Field: FieldEndpoint}
+		if value := r.getenv(variable); value != "" {
+			resolved.Endpoint = value
+			source.Source, source.Detail = SourceEnvironment, variable
+		}
+		resolved.Sources = append(resolved.Sources, source)
+	}
+
+	return resolved, nil
+}
+
+// Report resolves every provider's credentials and returns their sources, sorted by provider.
+// explicit maps provider names to the keys the caller holds for them.
+func (r *Resolver) Report(providers []string, explicit map[string]string, canvasPath string) []Source {
+	sorted := append([]string(nil), providers...)
+	sort.Strings(sorted)
+
+	sources := []Source{}
+	for _, provider := range sorted {
+		resolved, err := r.Resolve(provider, explicit[provider], canvasPath)
+		if err != nil {
+			sources = append(sources, Source{Provider: provider, Field: FieldAPIKey, Detail: err.Error()})
+			continue
+		}
+		sources = append(sources, resolved.Sources...)
+	}
+	return sources
+}
+
+// resolveKey walks the API key sources in order
+func (r *Resolver) resolveKey(provider, explicit string, dotenv map[string]string, dotenvPath string) (string, Source, error) {
+	source := Source{Provider: provider, Field: FieldAPIKey}
+
+	// A reference names the secret to use, so a missing secret is an error
+	if name, ok := secrets.ParseRef(explicit); ok {
+		if r.secrets == nil {
+			return "", source, fmt.Errorf("secrets service not available to resolve the %s key", provider)
+		}
+		key, err := r.secrets.Get(name)
+		if err != nil {
+			return "", source, fmt.Errorf("failed to resolve secret %q: %w", name, err)
+		}
+		source.Source, source.Detail = SourceSecret, name
+		return key, source, nil
+	}
+	if explicit != "" {
+		source.Source = SourceExplicit
+		return explicit, source, nil
+	}
+
+	// Otherwise fall back to a secret stored under the provider's name
+	if r.secrets != nil {
+		key, err := r.secrets.Get(provider)
+		if err == nil && key != "" {
+			source.Source, source.Detail = SourceSecret, provider
+			return key, source, nil
+		}
+		if err != nil && !errors.Is(err, secrets.ErrNotFound) && !errors.Is(err, secrets.ErrLocked) {
+			return "", source, fmt.Errorf("failed to read the %s key: %w", provider, err)
+		}
+	}
+
+	variable, ok := apiKeyVariables[provider]
+	if !ok {
+		return "", source, nil
+	}
+	if key := r.getenv(variable); key != "" {
+		source.Source, source.Detail = SourceEnvironment, variable
+		return key, source, nil
+	}
+	if key := dotenv[variable]; key != "" {
+		source.Source, source.Detail = SourceDotenv, dotenvPath
+		return key, source, nil
+	}
+	return "", source, nil
+}
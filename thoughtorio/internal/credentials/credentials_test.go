@@ -0,0 +1,194 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"thoughtorio/internal/secrets"
+)
+
+// mapSecrets is a SecretStore backed by a map
+type mapSecrets map[string]string
+
+func (m mapSecrets) Get(name string) (string, error) {
+	value, ok := m[name]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return value, nil
+}
+
+// lockedSecrets is a SecretStore whose vault is locked
+type lockedSecrets struct{}
+
+func (lockedSecrets) Get(name string) (string, error) {
+	return "", secrets.ErrLocked
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	canvasPath := filepath.Join(dir, "story.json")
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("OPENAI_API_KEY=from-dotenv\nOLLAMA_HOST=http://dotenv:11434\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		store      SecretStore
+		env        map[string]string
+		explicit   string
+		canvasPath string
+		wantKey    string
+		wantSource string
+		wantDetail string
+	}{
+		{
+			name:       "explicit wins",
+			store:      mapSecrets{"openai": "from-secret"},
+			env:        map[string]string{"OPENAI_API_KEY": "from-env"},
+			explicit:   "from-caller",
+			canvasPath: canvasPath,
+			wantKey:    "from-caller",
+			wantSource: SourceExplicit,
+		},
+		{
+			name:       "explicit reference",
+			store:      mapSecrets{"work": "from-named-secret", "openai": "from-secret"},
+			explicit:   secrets.Ref("work"),
+			canvasPath: canvasPath,
+			wantKey:    "from-named-secret",
+			wantSource: SourceSecret,
+			wantDetail: "work",
+		},
+		{
+			name:       "secret before environment",
+			store:      mapSecrets{"openai": "from-secret"},
+			env:        map[string]string{"OPENAI_API_KEY": "from-env"},
+			canvasPath: canvasPath,
+			wantKey:    "from-secret",
+			wantSource: SourceSecret,
+			wantDetail: "openai",
+		},
+		{
+			name:       "environment before dotenv",
+			store:      mapSecrets{},
+			env:        map[string]string{"OPENAI_API_KEY": "from-env"},
+			canvasPath: canvasPath,
+			wantKey:    "from-env",
+			wantSource: SourceEnvironment,
+			wantDetail: "OPENAI_API_KEY",
+		},
+		{
+			name:       "dotenv last",
+			store:      mapSecrets{},
+			canvasPath: canvasPath,
+			wantKey:    "from-dotenv",
+			wantSource: SourceDotenv,
+			wantDetail: filepath.Join(dir, ".env"),
+		},
+		{
+			name:       "locked vault falls through",
+			store:      lockedSecrets{},
+			canvasPath: canvasPath,
+			wantKey:    "from-dotenv",
+			wantSource: SourceDotenv,
+			wantDetail: filepath.Join(dir, ".env"),
+		},
+		{
+			name:    "no canvas, no dotenv",
+			store:   mapSecrets{},
+			wantKey: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewResolver(tt.store)
+			resolver.getenv = func(name string) string { return tt.env[name] }
+
+			resolved, err := resolver.Resolve("openai", tt.explicit, tt.canvasPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resolved.APIKey != tt.wantKey {
+				t.Errorf("key = %q, want %q", resolved.APIKey, tt.wantKey)
+			}
+			source := resolved.Sources[0]
+			if source.Source != tt.wantSource || source.Detail != tt.wantDetail {
+				t.Errorf("source = %+v, want %q from %q", source, tt.wantSource, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestResolveMissingReference(t *testing.T) {
+	resolver := NewResolver(mapSecrets{})
+	resolver.getenv = func(string) string { return "from-env" }
+
+	if _, err := resolver.Resolve("openai", secrets.Ref("gone"), ""); err == nil {
+		t.Error("Resolve with a missing referenced secret succeeded")
+	}
+}
+
+func TestResolveAPIKeyVariables(t *testing.T) {
+	env := map[string]string{
+		"OPENAI_API_KEY":       "openai-key",
+		"OPENROUTER_API_KEY":   "openrouter-key",
+		"GEMINI_API_KEY":       "gemini-key",
+		"AZURE_OPENAI_API_KEY": "azure-key",
+	}
+	resolver := NewResolver(mapSecrets{})
+	resolver.getenv = func(name string) string { return env[name] }
+
+	for provider, want := range map[string]string{
+		"openai":     "openai-key",
+		"openrouter": "openrouter-key",
+		"gemini":     "gemini-key",
+		"azure":      "azure-key",
+	} {
+		resolved, err := resolver.Resolve(provider, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolved.APIKey != want {
+			t.Errorf("%s key = %q, want %q", provider, resolved.APIKey, want)
+		}
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	canvasPath := filepath.Join(dir, "story.json")
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("OLLAMA_HOST=http://dotenv:11434\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewResolver(nil)
+	env := map[string]string{}
+	resolver.getenv = func(name string) string { return env[name] }
+
+	// A canvas's .env can't redirect completions to another host
+	resolved, err := resolver.Resolve("local", "", canvasPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Endpoint != "" {
+		t.Errorf("endpoint = %q, want none taken from the .env", resolved.Endpoint)
+	}
+	if source := resolved.Sources[1]; source.Field != FieldEndpoint || source.Source != "" {
+		t.Errorf("endpoint source = %+v, want none", source)
+	}
+
+	env["OLLAMA_HOST"] = "http://env:11434"
+	resolved, err = resolver.Resolve("local", "", canvasPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Endpoint != "http://env:11434" {
+		t.Errorf("endpoint = %q, want the environment host", resolved.Endpoint)
+	}
+	if source := resolved.Sources[1]; source.Source != SourceEnvironment || source.Detail != "OLLAMA_HOST" {
+		t.Errorf("endpoint source = %+v, want OLLAMA_HOST from the environment", source)
+	}
+}
@@ -0,0 +1,73 @@
+package credentials
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]string
+	}{
+		{
+			name: "plain",
+			data: "OPENAI_API_KEY=sk-test\nGEMINI_API_KEY = g-key \n",
+			want: map[string]string{"OPENAI_API_KEY": "sk-test", "GEMINI_API_KEY": "g-key"},
+		},
+		{
+			name: "comments and blank lines",
+			data: "# keys\n\n  # indented comment\nKEY=value # trailing comment\n",
+			want: map[string]string{"KEY": "value"},
+		},
+		{
+			name: "export prefix",
+			data: "export KEY=value\n",
+			want: map[string]string{"KEY": "value"},
+		},
+		{
+			name: "double quotes unescape",
+			data: `KEY="a \"quoted\" value # kept\n"` + "\n",
+			want: map[string]string{"KEY": "a \"quoted\" value # kept\n"},
+		},
+		{
+			name: "single quotes are literal",
+			data: `KEY='raw \n # kept'` + "\n",
+			want: map[string]string{"KEY": `raw \n # kept`},
+		},
+		{
+			name: "hash without a space is part of the value",
+			data: "KEY=abc#def\n",
+			want: map[string]string{"KEY": "abc#def"},
+		},
+		{
+			name: "value containing equals",
+			data: "KEY=a=b\n",
+			want: map[string]string{"KEY": "a=b"},
+		},
+		{
+			name: "lines without a key are skipped",
+			data: "not a pair\n=value\nKEY=\n",
+			want: map[string]string{"KEY": ""},
+		},
+		{
+			name: "later lines win",
+			data: "KEY=first\nKEY=second\n",
+			want: map[string]string{"KEY": "second"},
+		},
+		{
+			name: "windows line endings",
+			data: "KEY=value\r\nOTHER=x\r\n",
+			want: map[string]string{"KEY": "value", "OTHER": "x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseDotenv([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDotenv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
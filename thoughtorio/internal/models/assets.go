@@ -0,0 +1,12 @@
+package models
+
+// CanvasAsset is a file such as an image or PDF attached to a canvas. Canvases
+// refer to it by Ref, which is also its path inside a canvas pack.
+type CanvasAsset struct {
+	Ref string `json:"ref"`
+	// Name is the file name it was imported from, when known
+	Name      string `json:"name,omitempty"`
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// CanvasDocument is the file a window's canvas is saved to and whether it has unsaved changes
+type CanvasDocument struct {
+	SessionID string `json:"sessionId"`
+	// Path is empty until the canvas is first saved
+	Path string `json:"path,omitempty"`
+	Name string `json:"name"`
+	// Revision fingerprints the file as last loaded or saved by this window
+	Revision string `json:"revision,omitempty"`
+	Dirty    bool   `json:"dirty"`
+}
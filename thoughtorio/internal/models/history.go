@@ -0,0 +1,25 @@
+package models
+
+import "thoughtorio/internal/canvas"
+
+// CanvasVersion is a snapshot of a canvas taken when it was saved
+type CanvasVersion struct {
+	// Number counts the canvas's versions from 1
+	Number  int   `json:"number"`
+	SavedAt int64 `json:"savedAt"` // Milliseconds for JavaScript compatibility
+	// Snapshot is the content hash of the version; versions with equal snapshots are identical
+	Snapshot        string `json:"snapshot"`
+	NodeCount       int    `json:"nodeCount"`
+	ConnectionCount int    `json:"connectionCount"`
+	// Changes summarises the version against the one before it
+	Changes canvas.DiffSummary `json:"changes"`
+	// Note says where a version came from when it wasn't an ordinary save, e.g. "Restored version 3"
+	Note string `json:"note,omitempty"`
+}
+
+// CanvasVersionDiff compares two versions of a canvas; From is 0 for an empty canvas
+type CanvasVersionDiff struct {
+	From int         `json:"from"`
+	To   int         `json:"to"`
+	Diff canvas.Diff `json:"diff"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// ModelPreset is a named bundle of provider, model, generation parameters and system prompt
+type ModelPreset struct {
+	Name         string           `json:"name"`
+	Description  string           `json:"description,omitempty"`
+	Provider     string           `json:"provider"`
+	Model        string           `json:"model"`
+	Parameters   PresetParameters `json:"parameters"`
+	SystemPrompt string           `json:"systemPrompt,omitempty"`
+	CreatedAt    int64            `json:"createdAt"`
+	UpdatedAt    int64            `json:"updatedAt"`
+}
+
+// PresetParameters are the generation parameters a preset applies; zero values use provider defaults
+type PresetParameters struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxTokens       int      `json:"maxTokens,omitempty"`
+	ReasoningEffort string   `json:"reasoningEffort,omitempty"`
+	ThinkingBudget  int      `json:"thinkingBudget,omitempty"`
+	AutoContinue    bool     `json:"autoContinue,omitempty"`
+}
+
+// PresetsResult represents the result of model preset operations
+type PresetsResult struct {
+	Success bool          `json:"success"`
+	Presets []ModelPreset `json:"presets,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// PresetValidation reports whether a preset's model is offered by its provider
+type PresetValidation struct {
+	Name   string   `json:"name"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Profile is a named workspace with its own settings, keys, presets, recents and caches
+type Profile struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// CacheDir overrides where the profile's caches live; empty uses a cache folder in the profile directory
+	CacheDir  string `json:"cacheDir,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// ProfilesResult lists the profiles and which one is active
+type ProfilesResult struct {
+	Active   string    `json:"active"`
+	Profiles []Profile `json:"profiles"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "thoughtorio/internal/canvas"
+
+// RecoverySession is a canvas with changes that were never saved, offered for recovery on startup
+type RecoverySession struct {
+	Key       string `json:"key"`
+	SessionID string `json:"sessionId"`
+	// CanvasPath is the file the changes belong to; empty for untitled canvases
+	CanvasPath string `json:"canvasPath,omitempty"`
+	Name       string `json:"name"`
+	Untitled   bool   `json:"untitled"`
+	// SavedAt is when the changes were journalled
+	SavedAt int64 `json:"savedAt"` // Milliseconds for JavaScript compatibility
+	// FileModifiedAt is when the canvas file was last saved; zero when it is untitled or missing
+	FileModifiedAt int64 `json:"fileModifiedAt,omitempty"`
+	// FileMissing is set when the canvas file no longer exists
+	FileMissing bool `json:"fileMissing,omitempty"`
+	// Changes summarises the journalled canvas against the file as saved
+	Changes canvas.DiffSummary `json:"changes"`
+}
@@ -1,11 +1,43 @@
 package models
 
+import "thoughtorio/internal/canvas"
+
 // CanvasFileResult represents the result of canvas file operations
 type CanvasFileResult struct {
 	Success bool   `json:"success"`
 	Path    string `json:"path,omitempty"`
 	Data    string `json:"data,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Revision fingerprints the file as loaded or saved
+	Revision string `json:"revision,omitempty"`
+	// Conflict is set when a save was refused because the file changed on disk since it was loaded
+	Conflict bool `json:"conflict,omitempty"`
+	// Validation lists the problems found in the canvas; warnings don't stop it loading or saving
+	Validation *canvas.Report `json:"validation,omitempty"`
+	// Migration describes how an older canvas was upgraded when it was loaded
+	Migration *canvas.Migration `json:"migration,omitempty"`
+	// Document is the typed canvas for backend use; it isn't sent to the frontend
+	Document *canvas.Document `json:"-"`
+}
+
+// Kinds of canvas backup
+const (
+	// BackupKindGeneration is a copy kept by a save, newest at generation 1
+	BackupKindGeneration = "generation"
+	// BackupKindFormat is the original of a canvas upgraded from an older format
+	BackupKindFormat = "format"
+)
+
+// CanvasBackup is a backup copy of a canvas file kept beside it
+type CanvasBackup struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	// Generation counts saves back from the current file; set for generation backups
+	Generation int `json:"generation,omitempty"`
+	// FormatVersion is the format the original was in; set for format backups
+	FormatVersion int   `json:"formatVersion,omitempty"`
+	Size          int64 `json:"size"`
+	ModifiedAt    int64 `json:"modifiedAt"` // Milliseconds for JavaScript compatibility
 }
 
 // RecentCanvas represents a recently opened canvas file
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"thoughtorio/internal/providers"
+	"thoughtorio/internal/tokenizer"
+)
+
+// Settings is the app configuration persisted in settings.json. JSON names match
+// the keys the frontend settings store has always used.
+type Settings struct {
+	// Version is the settings.json schema version
+	Version int `json:"version"`
+
+	// LLM provider settings. API key fields hold "secret:<name>" references.
+	ActiveMode              string `json:"activeMode"`
+	OpenRouterAPIKey        string `json:"openrouter_api_key"`
+	OpenAIAPIKey            string `json:"openai_api_key"`
+	OpenAIEmbeddingAPIKey   string `json:"openai_embedding_api_key"`
+	GeminiAPIKey            string `json:"gemini_api_key"`
+	GeminiEmbeddingAPIKey   string `json:"gemini_embedding_api_key"`
+	AzureOpenAIAPIKey       string `json:"azure_api_key"`
+	ChatModelID             string `json:"chat_model_id"`
+	StoryProcessingModelID  string `json:"story_processing_model_id"`
+	LocalEmbeddingModelName string `json:"local_embedding_model_name"`
+	ReasoningEffort         string `json:"reasoning_effort"`
+	AutoContinue            bool   `json:"auto_continue"`
+	// Ollama is the runtime configuration applied to local completions, such as
+	// num_ctx, keep_alive and raw mode
+	Ollama providers.OllamaConfig `json:"ollama"`
+	// AzureOpenAI is the resource endpoint, API version and deployment mapping
+	// used for Azure OpenAI; it is empty until Azure is configured
+	AzureOpenAI providers.AzureOpenAIConfig `json:"azure_openai"`
+	// PromptBudget is how prompts too long for a model's context window are shortened
+	PromptBudget tokenizer.BudgetConfig `json:"prompt_budget"`
+
+	// UI settings
+	ShowContainerLabels  bool `json:"showContainerLabels"`
+	AutoExecuteWorkflows bool `json:"autoExecuteWorkflows"`
+	DebugMode            bool `json:"debugMode"`
+	ShowReasoning        bool `json:"showReasoning"`
+
+	// Canvas settings
+	DefaultNodeWidth  int `json:"defaultNodeWidth"`
+	DefaultNodeHeight int `json:"defaultNodeHeight"`
+	ContainerPadding  int `json:"containerPadding"`
+	// CanvasBackups is how many earlier versions of a canvas each save keeps beside it
+	CanvasBackups int `json:"canvasBackups"`
+}
+
+// APIKeyFields returns pointers to the settings that hold API keys, keyed by
+// the secret name each is stored under
+func (s *Settings) APIKeyFields() map[string]*string {
+	return map[string]*string{
+		"openrouter":       &s.OpenRouterAPIKey,
+		"openai":           &s.OpenAIAPIKey,
+		"openai_embedding": &s.OpenAIEmbeddingAPIKey,
+		"gemini":           &s.GeminiAPIKey,
+		"gemini_embedding": &s.GeminiEmbeddingAPIKey,
+		"azure":            &s.AzureOpenAIAPIKey,
+	}
+}
@@ -0,0 +1,103 @@
+package prompts
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// filters are the functions available in prompt templates. They take the piped
+// value last so they read naturally in pipelines: {{.notes | truncate 200}}.
+var filters = template.FuncMap{
+	"truncate": truncate,
+	"json":     toJSON,
+	"upper":    upper,
+	"bullet":   bullet,
+}
+
+// truncate shortens a value to at most n characters, marking the cut with an ellipsis
+func truncate(n int, value interface{}) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("truncate length cannot be negative")
+	}
+
+	runes := []rune(toText(value))
+	if len(runes) <= n {
+		return string(runes), nil
+	}
+	if n == 0 {
+		return "", nil
+	}
+	return strings.TrimRight(string(runes[:n-1]), " \t\n") + "…", nil
+}
+
+// toJSON renders a value as indented JSON
+func toJSON(value interface{}) (string, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("json: %w", err)
+	}
+	return string(data), nil
+}
+
+// upper converts a value to upper case
+func upper(value interface{}) string {
+	return strings.ToUpper(toText(value))
+}
+
+// bullet renders a list as one "- item" line per element. A string is split
+// into lines, so multi-line node output becomes a bulleted list too.
+func bullet(value interface{}) string {
+	var items []string
+
+	if list, ok := asList(value); ok {
+		for _, item := range list {
+			items = append(items, toText(item))
+		}
+	} else {
+		items = strings.Split(toText(value), "\n")
+	}
+
+	var lines []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			lines = append(lines, "- "+item)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// toText converts a template value to text; structured values become JSON
+func toText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	kind := reflect.ValueOf(value).Kind()
+	if kind == reflect.Map || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Struct {
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// asList returns the elements of a slice or array value
+func asList(value interface{}) ([]interface{}, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+	list := make([]interface{}, v.Len())
+	for i := range list {
+		list[i] = v.Index(i).Interface()
+	}
+	return list, true
+}
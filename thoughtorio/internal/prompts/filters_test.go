@@ -0,0 +1,52 @@
+package prompts
+
+import "testing"
+
+func TestFilters(t *testing.T) {
+	engine := NewEngine()
+	variables := map[string]interface{}{
+		"text":  "The quick brown fox",
+		"lines": "first\n\n  second  \nthird",
+		"list":  []interface{}{"alpha", " beta ", "", 3},
+		"data":  map[string]interface{}{"b": 2, "a": []string{"x"}},
+		"short": "ok",
+		"name":  "ada",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"truncate cuts and marks", `{{.text | truncate 10}}`, "The quick…"},
+		{"truncate trims before the mark", `{{.text | truncate 5}}`, "The…"},
+		{"truncate keeps short text", `{{.short | truncate 10}}`, "ok"},
+		{"truncate to zero", `{{.text | truncate 0}}`, ""},
+		{"truncate structured", `{{.list | truncate 100}}`, `["alpha"," beta ","",3]`},
+		{"upper", `{{.name | upper}}`, "ADA"},
+		{"json", `{{.data | json}}`, "{\n  \"a\": [\n    \"x\"\n  ],\n  \"b\": 2\n}"},
+		{"bullet list", `{{.list | bullet}}`, "- alpha\n- beta\n- 3"},
+		{"bullet lines", `{{.lines | bullet}}`, "- first\n- second\n- third"},
+		{"chained", `{{.name | upper | truncate 2}}`, "A…"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := engine.Render(test.template, variables)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTruncateRejectsNegativeLength(t *testing.T) {
+	_, err := NewEngine().Render(`{{.text | truncate -1}}`, map[string]interface{}{"text": "x"})
+	templateErr, ok := err.(*TemplateError)
+	if !ok || templateErr.Kind != ErrorKindExecute {
+		t.Fatalf("expected an execute error, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package prompts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// partialExtension is the file extension of partials stored on disk
+const partialExtension = ".tmpl"
+
+// partialNamePattern restricts partial names to something safe to use as a file name
+var partialNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// builtinPartials ship with the app. "context" lays out a dynamic node's inputs
+// the same way the canvas does when no template is set; it expects the
+// system_prompt, facts, history and task variables.
+var builtinPartials = map[string]string{
+	"context": `{{- with .system_prompt}}{{.}}
+{{end}}
+{{- if .facts}}
+--- CONTEXTUAL INFORMATION ---
+{{.facts | bullet}}
+{{end}}
+{{- if .history}}
+--- CONVERSATION HISTORY ---
+{{range .history}}{{if eq .role "assistant"}}AI{{else}}User{{end}}: {{.content}}
+{{end}}{{end}}`,
+}
+
+// Partial is a named template that prompts can include
+type Partial struct {
+	Name    string `json:"name"`
+	Body    string `json:"body"`
+	Builtin bool   `json:"builtin"`
+}
+
+// NewEngineWithDir creates an engine that also loads and stores user partials in dir
+func NewEngineWithDir(dir string) (*Engine, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create partials directory: %w", err)
+	}
+
+	e := NewEngine()
+	e.dir = dir
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+partialExtension))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), partialExtension)
+		if !partialNamePattern.MatchString(name) || builtinPartials[name] != "" {
+			continue
+		}
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read partial %s: %w", name, err)
+		}
+		e.partials[name] = string(body)
+	}
+
+	return e, nil
+}
+
+// Partials lists every partial, built-in ones included, sorted by name
+func (e *Engine) Partials() []Partial {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	partials := make([]Partial, 0, len(e.partials))
+	for name, body := range e.partials {
+		_, builtin := builtinPartials[name]
+		partials = append(partials, Partial{Name: name, Body: body, Builtin: builtin})
+	}
+	sort.Slice(partials, func(i, j int) bool {
+		return partials[i].Name < partials[j].Name
+	})
+	return partials
+}
+
+// SetPartial validates and stores a partial, saving it to disk when the engine has a directory
+func (e *Engine) SetPartial(name, body string) error {
+	if !partialNamePattern.MatchString(name) {
+		return fmt.Errorf("partial name %q must start with a letter and contain only letters, digits, '-' and '_'", name)
+	}
+	if name == templateName {
+		return fmt.Errorf("partial name %q is reserved", name)
+	}
+	if _, builtin := builtinPartials[name]; builtin {
+		return fmt.Errorf("partial %q is built in and cannot be changed", name)
+	}
+	if _, err := template.New(name).Funcs(filters).Parse(body); err != nil {
+		return toTemplateError(err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.dir != "" {
+		if err := os.WriteFile(filepath.Join(e.dir, name+partialExtension), []byte(body), 0644); err != nil {
+			return fmt.Errorf("failed to save partial: %w", err)
+		}
+	}
+	e.partials[name] = body
+	return nil
+}
+
+// DeletePartial removes a user partial
+func (e *Engine) DeletePartial(name string) error {
+	if _, builtin := builtinPartials[name]; builtin {
+		return fmt.Errorf("partial %q is built in and cannot be deleted", name)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.partials[name]; !ok {
+		return fmt.Errorf("partial %q not found", name)
+	}
+	if e.dir != "" {
+		if err := os.Remove(filepath.Join(e.dir, name+partialExtension)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete partial: %w", err)
+		}
+	}
+	delete(e.partials, name)
+	return nil
+}
@@ -0,0 +1,224 @@
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// Prompt templates use Go's text/template syntax. Variables are the outputs of
+// connected nodes, passed in by name:
+//
+//	{{template "context" .}}
+//	Summarize these notes:
+//	{{range .notes}}- {{. | truncate 200}}
+//	{{end}}
+//	Answer in {{.language | upper}}.
+//
+// Partials are named templates included with {{template "name" .}}. Rendering
+// is strict: referencing a variable that wasn't supplied is an error rather than
+// an empty string, so a disconnected input can't silently produce a bad prompt.
+
+// templateName is the name the top-level template is parsed under
+const templateName = "prompt"
+
+// Error kinds reported by TemplateError
+const (
+	ErrorKindParse           = "parse"
+	ErrorKindMissingVariable = "missing_variable"
+	ErrorKindExecute         = "execute"
+)
+
+// TemplateError describes why a template could not be parsed or rendered
+type TemplateError struct {
+	Kind     string `json:"kind"`
+	Template string `json:"template"`
+	Line     int    `json:"line,omitempty"`
+	Variable string `json:"variable,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (e *TemplateError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Template, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Template, e.Message)
+}
+
+// Engine renders prompt templates against a set of named partials
+type Engine struct {
+	partials map[string]string
+	// dir is where user partials are stored; empty keeps them in memory only
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewEngine creates an in-memory engine with the built-in partials registered
+func NewEngine() *Engine {
+	e := &Engine{partials: make(map[string]string)}
+	for name, body := range builtinPartials {
+		e.partials[name] = body
+	}
+	return e
+}
+
+// Render executes source with the given variables
+func (e *Engine) Render(source string, variables map[string]interface{}) (string, error) {
+	tmpl, err := e.parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, variables); err != nil {
+		return "", toTemplateError(err)
+	}
+	return out.String(), nil
+}
+
+// Validate parses source and its partials without rendering it
+func (e *Engine) Validate(source string) error {
+	_, err := e.parse(source)
+	return err
+}
+
+// Variables lists the top-level variables source refers to, including those
+// referenced by the partials it includes
+func (e *Engine) Variables(source string) ([]string, error) {
+	tmpl, err := e.parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	visited := make(map[string]bool)
+	var walkTemplate func(name string)
+	walkTemplate = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		if t := tmpl.Lookup(name); t != nil && t.Tree != nil {
+			walkNode(t.Tree.Root, 0, found, walkTemplate)
+		}
+	}
+	walkTemplate(templateName)
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// parse builds the template with every partial defined alongside it
+func (e *Engine) parse(source string) (*template.Template, error) {
+	tmpl := template.New(templateName).Funcs(filters).Option("missingkey=error")
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for name, body := range e.partials {
+		if _, err := tmpl.New(name).Parse(body); err != nil {
+			return nil, toTemplateError(err)
+		}
+	}
+	if _, err := tmpl.Parse(source); err != nil {
+		return nil, toTemplateError(err)
+	}
+	return tmpl, nil
+}
+
+// walkNode collects field references made against the root data (dot at depth 0).
+// Inside range and with blocks dot changes, so only $-rooted references count there.
+func walkNode(node parse.Node, depth int, found map[string]bool, include func(string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkNode(child, depth, found, include)
+		}
+	case *parse.ActionNode:
+		walkNode(n.Pipe, depth, found, include)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				walkNode(arg, depth, found, include)
+			}
+		}
+	case *parse.FieldNode:
+		if depth == 0 && len(n.Ident) > 0 {
+			found[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			found[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		walkNode(n.Node, depth, found, include)
+	case *parse.IfNode:
+		walkNode(n.Pipe, depth, found, include)
+		walkNode(n.List, depth, found, include)
+		walkNode(n.ElseList, depth, found, include)
+	case *parse.RangeNode:
+		walkNode(n.Pipe, depth, found, include)
+		walkNode(n.List, depth+1, found, include)
+		walkNode(n.ElseList, depth, found, include)
+	case *parse.WithNode:
+		walkNode(n.Pipe, depth, found, include)
+		walkNode(n.List, depth+1, found, include)
+		walkNode(n.ElseList, depth, found, include)
+	case *parse.TemplateNode:
+		walkNode(n.Pipe, depth, found, include)
+		// Partials receive their own dot; they only see root variables when passed "."
+		if depth == 0 && n.Pipe != nil && len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1 {
+			if _, isDot := n.Pipe.Cmds[0].Args[0].(*parse.DotNode); isDot {
+				include(n.Name)
+			}
+		}
+	}
+}
+
+var (
+	// errorLocationPattern extracts the template name and line from text/template errors
+	errorLocationPattern = regexp.MustCompile(`template: ([^:]+):(\d+)(?::\d+)?: (.*)`)
+	// missingKeyPattern matches the error text/template gives for missingkey=error
+	missingKeyPattern = regexp.MustCompile(`map has no entry for key "([^"]+)"`)
+)
+
+// toTemplateError converts text/template errors into TemplateErrors
+func toTemplateError(err error) error {
+	message := err.Error()
+	templateErr := &TemplateError{Kind: ErrorKindExecute, Template: templateName, Message: message}
+
+	if match := errorLocationPattern.FindStringSubmatch(message); match != nil {
+		templateErr.Template = match[1]
+		fmt.Sscanf(match[2], "%d", &templateErr.Line)
+		templateErr.Message = match[3]
+	}
+
+	if match := missingKeyPattern.FindStringSubmatch(message); match != nil {
+		templateErr.Kind = ErrorKindMissingVariable
+		templateErr.Variable = match[1]
+		templateErr.Message = fmt.Sprintf("missing variable %q", match[1])
+	} else if !strings.Contains(message, "executing") {
+		templateErr.Kind = ErrorKindParse
+	}
+
+	return templateErr
+}
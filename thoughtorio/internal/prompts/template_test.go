@@ -0,0 +1,168 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRenderMissingVariable(t *testing.T) {
+	engine := NewEngine()
+
+	tests := []struct {
+		name     string
+		template string
+		variable string
+		line     int
+	}{
+		{"top level", "Hello {{.name}}", "name", 1},
+		{"later line", "Hello\n\n{{.task | upper}}", "task", 3},
+		{"inside a partial", `{{template "context" .}}`, "system_prompt", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := engine.Render(test.template, nil)
+			templateErr, ok := err.(*TemplateError)
+			if !ok {
+				t.Fatalf("expected a TemplateError, got %v", err)
+			}
+			if templateErr.Kind != ErrorKindMissingVariable || templateErr.Variable != test.variable || templateErr.Line != test.line {
+				t.Errorf("got %+v", templateErr)
+			}
+		})
+	}
+}
+
+func TestRenderParseError(t *testing.T) {
+	err := NewEngine().Validate("Hello\n{{.name")
+	templateErr, ok := err.(*TemplateError)
+	if !ok {
+		t.Fatalf("expected a TemplateError, got %v", err)
+	}
+	if templateErr.Kind != ErrorKindParse || templateErr.Template != templateName || templateErr.Line != 2 {
+		t.Errorf("got %+v", templateErr)
+	}
+}
+
+func TestRenderNodeVariables(t *testing.T) {
+	variables := map[string]interface{}{
+		"research_notes": "Bees dance.",
+		"nodes": map[string]interface{}{
+			"node-3": map[string]interface{}{"id": "node-3", "title": "Research Notes", "output": "Bees dance."},
+		},
+	}
+
+	got, err := NewEngine().Render(`{{.research_notes}} / {{(index .nodes "node-3").title}}: {{(index .nodes "node-3").output}}`, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Bees dance. / Research Notes: Bees dance." {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestContextPartial(t *testing.T) {
+	variables := map[string]interface{}{
+		"system_prompt": "Be brief.",
+		"facts":         []interface{}{"Sky is blue"},
+		"history": []interface{}{
+			map[string]interface{}{"role": "user", "content": "Hi"},
+			map[string]interface{}{"role": "assistant", "content": "Hello"},
+		},
+		"task": "Answer",
+	}
+
+	got, err := NewEngine().Render(`{{template "context" .}}{{.task}}`, variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Be brief.\n\n--- CONTEXTUAL INFORMATION ---\n- Sky is blue\n\n--- CONVERSATION HISTORY ---\nUser: Hi\nAI: Hello\nAnswer"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestVariablesIncludesPartials(t *testing.T) {
+	engine := NewEngine()
+	if err := engine.SetPartial("signoff", "Thanks, {{.author}}"); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := engine.Variables(`{{template "context" .}}{{range .notes}}{{.text}}{{$.language}}{{end}}{{template "signoff" .}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"author", "facts", "history", "language", "notes", "system_prompt"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestPartials(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := NewEngineWithDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.SetPartial("greeting", "Hello {{.name}}"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := engine.Render(`{{template "greeting" .}}!`, map[string]interface{}{"name": "Ada"})
+	if err != nil || got != "Hello Ada!" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+
+	// Saved partials are loaded by the next engine using the directory
+	reloaded, err := NewEngineWithDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, partial := range reloaded.Partials() {
+		names = append(names, partial.Name)
+		if partial.Builtin != (partial.Name == "context") {
+			t.Errorf("partial %s reported builtin=%v", partial.Name, partial.Builtin)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"context", "greeting"}) {
+		t.Errorf("reloaded partials %v", names)
+	}
+
+	if err := engine.DeletePartial("greeting"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "greeting"+partialExtension)); !os.IsNotExist(err) {
+		t.Errorf("partial file still exists: %v", err)
+	}
+	if _, err := engine.Render(`{{template "greeting" .}}`, nil); err == nil {
+		t.Error("expected an error rendering a deleted partial")
+	}
+}
+
+func TestSetPartialRejects(t *testing.T) {
+	engine := NewEngine()
+
+	tests := map[string]struct{ name, body string }{
+		"invalid name":   {"1st", "x"},
+		"path in name":   {"../escape", "x"},
+		"reserved name":  {templateName, "x"},
+		"built-in":       {"context", "x"},
+		"invalid syntax": {"broken", "{{.name"},
+		"unknown filter": {"filtered", "{{.name | shout}}"},
+	}
+	for label, test := range tests {
+		if err := engine.SetPartial(test.name, test.body); err == nil {
+			t.Errorf("%s: expected an error", label)
+		}
+	}
+
+	if err := engine.DeletePartial("context"); err == nil {
+		t.Error("expected an error deleting a built-in partial")
+	}
+	if err := engine.DeletePartial("missing"); err == nil {
+		t.Error("expected an error deleting an unknown partial")
+	}
+}
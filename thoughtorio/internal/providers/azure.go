@@ -0,0 +1,343 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// azureDeploymentsAPIVersion is the data-plane API version that still supports listing deployments
+const azureDeploymentsAPIVersion = "2022-12-01"
+
+// azureAPIVersionPattern matches Azure OpenAI API versions such as 2024-06-01 or 2024-10-01-preview
+var azureAPIVersionPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(-preview)?$`)
+
+// AzureOpenAIConfig holds the resource settings needed to reach Azure OpenAI deployments
+type AzureOpenAIConfig struct {
+	// Endpoint is the resource URL, e.g. https://my-resource.openai.azure.com
+	Endpoint   string `json:"endpoint"`
+	APIVersion string `json:"api_version"`
+	// Deployments maps the model IDs shown in the app to Azure deployment names
+	Deployments map[string]string `json:"deployments"`
+}
+
+// AzureOpenAIProvider implements the AIProvider interface for Azure OpenAI deployments
+type AzureOpenAIProvider struct {
+	name   string
+	config AzureOpenAIConfig
+	mu     sync.RWMutex
+}
+
+// NewAzureOpenAIProvider creates a new Azure OpenAI provider instance
+func NewAzureOpenAIProvider() *AzureOpenAIProvider {
+	return &AzureOpenAIProvider{
+		name: "azure",
+		config: AzureOpenAIConfig{
+			Deployments: make(map[string]string),
+		},
+	}
+}
+
+// GetName returns the provider name
+func (p *AzureOpenAIProvider) GetName() string {
+	return p.name
+}
+
+// RequiresAPIKey returns true since Azure OpenAI requires a resource key
+func (p *AzureOpenAIProvider) RequiresAPIKey() bool {
+	return true
+}
+
+// ParseAzureOpenAIConfig converts a generic provider configuration map into an AzureOpenAIConfig
+func ParseAzureOpenAIConfig(config map[string]interface{}) (AzureOpenAIConfig, error) {
+	var azureConfig AzureOpenAIConfig
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return azureConfig, fmt.Errorf("invalid Azure OpenAI configuration: %w", err)
+	}
+	if err := json.Unmarshal(data, &azureConfig); err != nil {
+		return azureConfig, fmt.Errorf("invalid Azure OpenAI configuration: %w", err)
+	}
+
+	return azureConfig, nil
+}
+
+// IsZero reports whether nothing is configured, which leaves Azure OpenAI unavailable
+func (c AzureOpenAIConfig) IsZero() bool {
+	return c.Endpoint == "" && c.APIVersion == "" && len(c.Deployments) == 0
+}
+
+// Validate checks the endpoint, API version and deployment mapping
+func (c AzureOpenAIConfig) Validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("Azure OpenAI requires an endpoint")
+	}
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return fmt.Errorf("Azure OpenAI endpoint must be a URL like https://my-resource.openai.azure.com")
+	}
+
+	if c.APIVersion == "" {
+		return fmt.Errorf("Azure OpenAI requires an API version")
+	}
+	if !azureAPIVersionPattern.MatchString(c.APIVersion) {
+		return fmt.Errorf("Azure OpenAI API version must look like 2024-06-01 or 2024-10-01-preview, got %q", c.APIVersion)
+	}
+
+	if len(c.Deployments) == 0 {
+		return fmt.Errorf("Azure OpenAI requires at least one model to deployment mapping")
+	}
+	for model, deployment := range c.Deployments {
+		if strings.TrimSpace(model) == "" || strings.TrimSpace(deployment) == "" {
+			return fmt.Errorf("Azure OpenAI deployment mapping entries need both a model and a deployment name")
+		}
+	}
+
+	return nil
+}
+
+// ValidateConfig validates Azure OpenAI-specific configuration
+func (p *AzureOpenAIProvider) ValidateConfig(config map[string]interface{}) error {
+	azureConfig, err := ParseAzureOpenAIConfig(config)
+	if err != nil {
+		return err
+	}
+	return azureConfig.Validate()
+}
+
+// GetConfig returns the current resource configuration
+func (p *AzureOpenAIProvider) GetConfig() AzureOpenAIConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	config := p.config
+	config.Deployments = make(map[string]string, len(p.config.Deployments))
+	for model, deployment := range p.config.Deployments {
+		config.Deployments[model] = deployment
+	}
+	return config
+}
+
+// SetConfig validates and applies the resource configuration. The zero
+// configuration clears it, leaving Azure OpenAI unconfigured.
+func (p *AzureOpenAIProvider) SetConfig(config AzureOpenAIConfig) error {
+	if config.IsZero() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.config = AzureOpenAIConfig{Deployments: make(map[string]string)}
+		return nil
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
+	return nil
+}
+
+// FetchModels lists the configured deployment mapping plus any deployments the
+// resource reports. Azure has no /v1/models; deployments are the unit of discovery.
+func (p *AzureOpenAIProvider) FetchModels(ctx context.Context, apiKey string) ([]Model, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("Azure OpenAI API key cannot be empty")
+	}
+
+	config := p.GetConfig()
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("Azure OpenAI is not configured")
+	}
+
+	models := []Model{}
+	mapped := make(map[string]bool)
+	for model, deployment := range config.Deployments {
+		mapped[deployment] = true
+		models = append(models, Model{
+			ID:            model,
+			Name:          fmt.Sprintf("%s (%s)", model, deployment),
+			ContextLength: KnownContextLength(model),
+		})
+	}
+
+	deployments, err := p.listDeployments(ctx, config, apiKey)
+	if err != nil && len(models) == 0 {
+		return nil, err
+	}
+	for _, deployment := range deployments {
+		if mapped[deployment.ID] {
+			continue
+		}
+		models = append(models, Model{
+			ID:            deployment.ID,
+			Name:          fmt.Sprintf("%s (%s)", deployment.ID, deployment.Model),
+			ContextLength: KnownContextLength(deployment.Model),
+		})
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].ID < models[j].ID
+	})
+
+	return models, nil
+}
+
+// azureDeployment is a deployment as reported by the data-plane deployments listing
+type azureDeployment struct {
+	ID     string `json:"id"`
+	Model  string `json:"model"`
+	Status string `json:"status"`
+}
+
+// listDeployments queries the resource for its succeeded deployments
+func (p *AzureOpenAIProvider) listDeployments(ctx context.Context, config AzureOpenAIConfig, apiKey string) ([]azureDeployment, error) {
+	apiURL := fmt.Sprintf("%s/openai/deployments?api-version=%s", config.Endpoint, azureDeploymentsAPIVersion)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("api-key", apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Azure OpenAI deployments: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Azure OpenAI API error: %s", string(body))
+	}
+
+	var result struct {
+		Data []azureDeployment `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	deployments := []azureDeployment{}
+	for _, deployment := range result.Data {
+		if deployment.Status == "" || deployment.Status == "succeeded" {
+			deployments = append(deployments, deployment)
+		}
+	}
+
+	return deployments, nil
+}
+
+// deploymentFor resolves the deployment name for a model ID, treating unmapped IDs as deployment names
+func (c AzureOpenAIConfig) deploymentFor(model string) string {
+	if deployment, ok := c.Deployments[model]; ok {
+		return deployment
+	}
+	return model
+}
+
+// GetCompletion performs text completion using an Azure OpenAI deployment
+func (p *AzureOpenAIProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, apiKey, nil)
+}
+
+// StreamCompletion performs text completion using an Azure OpenAI deployment, calling onChunk as output arrives
+func (p *AzureOpenAIProvider) StreamCompletion(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, apiKey, onChunk)
+}
+
+// complete sends a chat completion request, streaming the reply when onChunk is
+// set. Older API versions reject stream_options, so streamed replies carry no
+// usage and their tokens are estimated.
+func (p *AzureOpenAIProvider) complete(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	config := p.GetConfig()
+	if config.Endpoint == "" {
+		err := fmt.Errorf("Azure OpenAI is not configured")
+		return AICompletionResponse{Error: err.Error()}, err
+	}
+
+	options := CompletionOptionsFrom(ctx)
+
+	reqBody := map[string]interface{}{
+		"messages": options.chatMessages(model, prompt),
+	}
+	// Reasoning deployments reject max_tokens and take an effort level instead
+	if IsReasoningModel(model) {
+		reqBody["max_completion_tokens"] = options.maxTokens(model)
+		if options.ReasoningEffort != "" {
+			reqBody["reasoning_effort"] = options.ReasoningEffort
+		}
+	} else {
+		reqBody["max_tokens"] = options.maxTokens(model)
+	}
+	options.applySampling(reqBody, model)
+	if onChunk != nil {
+		reqBody["stream"] = true
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return AICompletionResponse{Error: err.Error()}, err
+	}
+
+	apiURL := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		config.Endpoint, url.PathEscape(config.deploymentFor(model)), url.QueryEscape(config.APIVersion))
+
+	client := clientFor(onChunk, 60*time.Second)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return AICompletionResponse{Error: err.Error()}, err
+	}
+
+	req.Header.Set("api-key", apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return AICompletionResponse{Error: err.Error()}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		errMsg := fmt.Sprintf("Azure OpenAI API error (%d): %s", resp.StatusCode, string(body))
+		response := AICompletionResponse{Error: errMsg}
+		// Prompts rejected by Azure's content filter come back as a 400 with code content_filter
+		if strings.Contains(string(body), `"content_filter"`) {
+			response.FinishReason = FinishReasonContentFilter
+		}
+		return response, fmt.Errorf("%s", errMsg)
+	}
+
+	result, err := decodeChatCompletion(resp.Body, onChunk)
+	if err != nil {
+		return AICompletionResponse{Error: err.Error()}, err
+	}
+
+	if result.Error.Message != "" {
+		return AICompletionResponse{Error: result.Error.Message}, fmt.Errorf("Azure OpenAI error: %s", result.Error.Message)
+	}
+
+	if len(result.Choices) == 0 {
+		return AICompletionResponse{Error: "No response from Azure OpenAI"}, fmt.Errorf("no response from Azure OpenAI")
+	}
+
+	response := AICompletionResponse{
+		Content:      result.Choices[0].Message.Content,
+		Usage:        result.Usage.toTokenUsage(),
+		FinishReason: normalizeFinishReason(result.Choices[0].FinishReason),
+	}
+	return withReasoning(response, ""), nil
+}
@@ -0,0 +1,195 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"thoughtorio/internal/tokenizer"
+)
+
+// modelCacheFileName is the file the model cache is persisted to
+const modelCacheFileName = "models.json"
+
+// defaultOllamaContext is the context window an Ollama server runs a model
+// with when a request sets no num_ctx
+const defaultOllamaContext = 2048
+
+// PromptEstimate reports how a prompt measures up against a model's context window
+type PromptEstimate struct {
+	Tokens       int    `json:"tokens"`
+	Tokenizer    string `json:"tokenizer"`
+	Exact        bool   `json:"exact"`
+	ContextLimit int    `json:"contextLimit"`
+	Available    int    `json:"available"`
+	Fits         bool   `json:"fits"`
+}
+
+// GetBudgetConfig returns the prompt budgeting configuration
+func (pm *ProviderManager) GetBudgetConfig() tokenizer.BudgetConfig {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.budgetConfig
+}
+
+// SetBudgetConfig validates and applies the prompt budgeting configuration
+func (pm *ProviderManager) SetBudgetConfig(config tokenizer.BudgetConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.budgetConfig = config
+	return nil
+}
+
+// ContextLimit returns the context window for a model in tokens, or 0 if unknown.
+// For Ollama it is the window the server will actually run with: the configured
+// num_ctx, or the server's default when num_ctx is unset, never more than the
+// context length the model was trained with. Ollama silently drops whatever
+// doesn't fit, so budgeting against anything larger would truncate prompts
+// that appeared to fit. ctx bounds the lookup of the model's context length.
+func (pm *ProviderManager) ContextLimit(ctx context.Context, providerName, model string) int {
+	pm.mu.RLock()
+	cached, ok := pm.modelCache[providerName][model]
+	pm.mu.RUnlock()
+
+	if providerName == "local" {
+		ollama, err := pm.GetOllamaProvider()
+		if err != nil {
+			return 0
+		}
+		limit := defaultOllamaContext
+		if numCtx := ollama.GetConfig().Options.NumCtx; numCtx > 0 {
+			limit = numCtx
+		}
+		trained := cached.ContextLength
+		if trained <= 0 {
+			trained = ollama.ContextLength(ctx, model)
+		}
+		if trained > 0 && trained < limit {
+			limit = trained
+		}
+		return limit
+	}
+
+	if ok && cached.ContextLength > 0 {
+		return cached.ContextLength
+	}
+
+	return KnownContextLength(model)
+}
+
+// EstimatePrompt counts the tokens in prompt for the given provider and model
+func (pm *ProviderManager) EstimatePrompt(ctx context.Context, providerName, model, prompt string) PromptEstimate {
+	tok := pm.tokenizers.ForModel(providerName, model)
+	limit := pm.ContextLimit(ctx, providerName, model)
+	tokens := tok.CountTokens(prompt)
+
+	estimate := PromptEstimate{
+		Tokens:       tokens,
+		Tokenizer:    tok.Name(),
+		Exact:        tok.Exact(),
+		ContextLimit: limit,
+		Fits:         true,
+	}
+	if limit > 0 {
+		estimate.Available = limit - pm.GetBudgetConfig().ReserveOutputTokens
+		estimate.Fits = tokens <= estimate.Available
+	}
+
+	return estimate
+}
+
+// cacheModels remembers fetched models so their context limits are known at completion time
+func (pm *ProviderManager) cacheModels(providerName string, models []Model) {
+	byID := make(map[string]Model, len(models))
+	for _, model := range models {
+		byID[model.ID] = model
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.modelCache[providerName] = byID
+
+	if pm.cacheDir != "" {
+		if err := pm.saveModelCache(); err != nil {
+			log.Printf("failed to save model cache: %v", err)
+		}
+	}
+}
+
+// SetCacheDir replaces the model cache with the one stored in dir, so context
+// limits and prices of models fetched in earlier sessions are known
+func (pm *ProviderManager) SetCacheDir(dir string) error {
+	cache := make(map[string]map[string]Model)
+
+	data, err := os.ReadFile(filepath.Join(dir, modelCacheFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read model cache: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cache); err != nil {
+			return fmt.Errorf("failed to parse model cache: %w", err)
+		}
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.cacheDir = dir
+	pm.modelCache = cache
+	return nil
+}
+
+// saveModelCache writes the model cache to the cache directory; callers hold pm.mu
+func (pm *ProviderManager) saveModelCache() error {
+	if err := os.MkdirAll(pm.cacheDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(pm.modelCache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(pm.cacheDir, modelCacheFileName), data, 0644)
+}
+
+// fitPrompt applies the configured truncation strategy when the request's prompt
+// exceeds the model's context window. Summaries are requested through handler,
+// so they pass the same interceptors as the completion they make room for.
+func (pm *ProviderManager) fitPrompt(ctx context.Context, handler CompletionHandler, req CompletionRequest) (string, tokenizer.TruncationReport, error) {
+	tok := pm.tokenizers.ForModel(req.Provider, req.Model)
+	limit := pm.ContextLimit(ctx, req.Provider, req.Model)
+	// The system prompt is sent alongside the prompt and is never truncated
+	if limit > 0 && req.Options.SystemPrompt != "" {
+		limit -= tok.CountTokens(req.Options.SystemPrompt)
+		if limit <= 0 {
+			return req.Prompt, tokenizer.TruncationReport{}, fmt.Errorf("%w: the system prompt alone fills the context window", tokenizer.ErrContextExceeded)
+		}
+	}
+
+	summarize := func(ctx context.Context, text string, targetTokens int) (string, error) {
+		metadata := make(map[string]string, len(req.Metadata)+1)
+		for key, value := range req.Metadata {
+			metadata[key] = value
+		}
+		metadata[MetadataPurpose] = PurposeSummarize
+
+		response, err := handler(ctx, &CompletionRequest{
+			Provider: req.Provider,
+			Model:    req.Model,
+			Prompt:   fmt.Sprintf("Summarize the following context in at most %d tokens. Keep every fact, name and decision that later instructions may depend on. Respond with the summary only.\n\n%s", targetTokens, text),
+			APIKey:   req.APIKey,
+			Metadata: metadata,
+		})
+		if err != nil {
+			return "", err
+		}
+		return response.Content, nil
+	}
+
+	return tokenizer.Fit(ctx, req.Prompt, limit, tok, pm.GetBudgetConfig(), summarize)
+}
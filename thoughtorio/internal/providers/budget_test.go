@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"thoughtorio/internal/tokenizer"
+)
+
+func TestOllamaContextLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model == "slow" {
+			<-r.Context().Done()
+			return
+		}
+		if r.URL.Path != "/api/show" || body.Model != "llama3" {
+			http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model_info": map[string]interface{}{"general.architecture": "llama", "llama.context_length": 8192},
+		})
+	}))
+	defer server.Close()
+
+	pm := NewProviderManager()
+	ollama, err := pm.GetOllamaProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ollama.SetHost(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Without num_ctx the server runs the model in its default window, whatever it was trained with
+	if got := pm.ContextLimit(ctx, "local", "llama3"); got != defaultOllamaContext {
+		t.Errorf("no num_ctx: got %d, want %d", got, defaultOllamaContext)
+	}
+	if got := pm.ContextLimit(ctx, "local", "unknown"); got != defaultOllamaContext {
+		t.Errorf("unreported context: got %d, want %d", got, defaultOllamaContext)
+	}
+
+	if err := ollama.SetConfig(OllamaConfig{Options: OllamaOptions{NumCtx: 4096}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pm.ContextLimit(ctx, "local", "llama3"); got != 4096 {
+		t.Errorf("num_ctx: got %d, want 4096", got)
+	}
+
+	// A window larger than the model was trained with is capped to its length
+	if err := ollama.SetConfig(OllamaConfig{Options: OllamaOptions{NumCtx: 32768}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pm.ContextLimit(ctx, "local", "llama3"); got != 8192 {
+		t.Errorf("num_ctx beyond the model: got %d, want 8192", got)
+	}
+
+	// The model lookup ends with the request
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	done := make(chan int, 1)
+	go func() { done <- pm.ContextLimit(cancelled, "local", "slow") }()
+	select {
+	case got := <-done:
+		if got != 32768 {
+			t.Errorf("cancelled lookup: got %d, want the configured 32768", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ContextLimit ignored the cancelled context")
+	}
+}
+
+// echoProvider answers summarization requests with a short summary and echoes anything else
+type echoProvider struct{}
+
+func (echoProvider) GetName() string { return "echo" }
+
+func (echoProvider) FetchModels(ctx context.Context, apiKey string) ([]Model, error) { return nil, nil }
+
+func (echoProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	if strings.HasPrefix(prompt, "Summarize") {
+		return AICompletionResponse{Content: "gist"}, nil
+	}
+	return AICompletionResponse{Content: prompt}, nil
+}
+
+func (echoProvider) ValidateConfig(config map[string]interface{}) error { return nil }
+
+func (echoProvider) RequiresAPIKey() bool { return false }
+
+func TestSummarizationPassesInterceptors(t *testing.T) {
+	pm := NewProviderManager()
+	pm.RegisterProvider(echoProvider{})
+	pm.cacheModels("echo", []Model{{ID: "m", ContextLength: 60}})
+	if err := pm.SetBudgetConfig(tokenizer.BudgetConfig{Strategy: tokenizer.StrategySummarize}); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []CompletionRequest
+	pm.Use(InterceptorFuncs{
+		Label: "record",
+		Completion: func(next CompletionHandler) CompletionHandler {
+			return func(ctx context.Context, req *CompletionRequest) (AICompletionResponse, error) {
+				seen = append(seen, *req)
+				return next(ctx, req)
+			}
+		},
+	})
+
+	earlier := strings.Repeat("Earlier context that no longer fits. ", 10)
+	prompt := strings.Join([]string{earlier, earlier, earlier, "Answer the question."}, "\n\n")
+	response, err := pm.Complete(context.Background(), CompletionRequest{
+		Provider: "echo",
+		Model:    "m",
+		Prompt:   prompt,
+		Metadata: map[string]string{MetadataCanvasPath: "/canvases/a.thoughtorio"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Truncation == nil || !response.Truncation.Summarized {
+		t.Fatalf("expected a summarized prompt, got %+v", response.Truncation)
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("interceptor saw %d requests, want the summary and the completion", len(seen))
+	}
+	if seen[0].Metadata[MetadataPurpose] != PurposeSummarize || seen[0].Metadata[MetadataCanvasPath] != "/canvases/a.thoughtorio" {
+		t.Errorf("summary request metadata: %v", seen[0].Metadata)
+	}
+	if seen[1].Metadata[MetadataPurpose] != "" || !strings.Contains(seen[1].Prompt, "gist") {
+		t.Errorf("completion request: %q %v", seen[1].Prompt, seen[1].Metadata)
+	}
+}
@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CompareTarget is one provider/model pair in a comparison run. Each lane's
+// credentials are resolved like any completion's, from the canvas path in the
+// run's metadata.
+type CompareTarget struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// Options lets lanes differ in reasoning effort or output limit
+	Options CompletionOptions `json:"options"`
+}
+
+// CompareLane is the outcome of a comparison run for a single target
+type CompareLane struct {
+	Index     int    `json:"index"`
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Content   string `json:"content"`
+	Reasoning string `json:"reasoning,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// FinishReason is why the lane stopped generating
+	FinishReason string             `json:"finishReason,omitempty"`
+	LatencyMs    float64            `json:"latencyMs"`
+	Usage        *TokenUsage        `json:"usage,omitempty"`
+	Timings      *CompletionTimings `json:"timings,omitempty"`
+}
+
+// CompareCompletions sends the same prompt to every target concurrently and
+// returns one lane per target in the order given. A failing lane doesn't stop
+// the others; its error is reported on the lane. When onChunk isn't nil each
+// lane streams independently and chunks are tagged with the lane index.
+func (pm *ProviderManager) CompareCompletions(ctx context.Context, prompt string, targets []CompareTarget, metadata map[string]string, onChunk func(lane int, chunk string)) ([]CompareLane, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("at least one comparison target is required")
+	}
+
+	lanes := make([]CompareLane, len(targets))
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target CompareTarget) {
+			defer wg.Done()
+
+			// Each lane gets its own metadata, since interceptors may annotate it
+			laneMetadata := make(map[string]string, len(metadata))
+			for key, value := range metadata {
+				laneMetadata[key] = value
+			}
+
+			req := CompletionRequest{
+				Provider: target.Provider,
+				Model:    target.Model,
+				Prompt:   prompt,
+				Options:  target.Options,
+				Metadata: laneMetadata,
+			}
+			if onChunk != nil {
+				req.OnChunk = func(chunk string) {
+					onChunk(i, chunk)
+				}
+			}
+
+			response, err := pm.Complete(ctx, req)
+
+			lane := CompareLane{
+				Index:        i,
+				Provider:     target.Provider,
+				Model:        target.Model,
+				Content:      response.Content,
+				Reasoning:    response.Reasoning,
+				FinishReason: response.FinishReason,
+				LatencyMs:    response.LatencyMs,
+				Usage:        response.Usage,
+				Timings:      response.Timings,
+			}
+			if err != nil {
+				lane.Error = err.Error()
+			} else if response.Error != "" {
+				lane.Error = response.Error
+			}
+			lanes[i] = lane
+		}(i, target)
+	}
+
+	wg.Wait()
+	return lanes, nil
+}
@@ -0,0 +1,105 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// laneProvider streams the model name word by word and answers with the key it
+// was given. Model "broken" fails and model "slow" answers last.
+type laneProvider struct{ name string }
+
+func (p laneProvider) GetName() string { return p.name }
+
+func (p laneProvider) FetchModels(ctx context.Context, apiKey string) ([]Model, error) {
+	return nil, nil
+}
+
+func (p laneProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	return p.StreamCompletion(ctx, model, prompt, apiKey, func(string) {})
+}
+
+func (p laneProvider) StreamCompletion(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	switch model {
+	case "broken":
+		return AICompletionResponse{}, fmt.Errorf("%s is down", p.name)
+	case "slow":
+		time.Sleep(50 * time.Millisecond)
+	}
+	onChunk(p.name + ":")
+	onChunk(model)
+	return AICompletionResponse{Content: "key " + apiKey, FinishReason: FinishReasonStop}, nil
+}
+
+func (p laneProvider) ValidateConfig(config map[string]interface{}) error { return nil }
+
+func (p laneProvider) RequiresAPIKey() bool { return true }
+
+func TestCompareCompletions(t *testing.T) {
+	pm := NewProviderManager()
+	pm.RegisterProvider(laneProvider{name: "alpha"})
+	pm.RegisterProvider(laneProvider{name: "beta"})
+	// Keys come from the canvas, as they do for single completions
+	pm.SetCredentialResolver(func(providerName, apiKey, canvasPath string) (Credentials, error) {
+		return Credentials{APIKey: providerName + "-key-for-" + canvasPath}, nil
+	})
+
+	targets := []CompareTarget{
+		{Provider: "alpha", Model: "slow"},
+		{Provider: "beta", Model: "broken"},
+		{Provider: "beta", Model: "fast"},
+		{Provider: "missing", Model: "m"},
+	}
+	var mu sync.Mutex
+	chunks := map[int][]string{}
+	lanes, err := pm.CompareCompletions(context.Background(), "prompt", targets, map[string]string{MetadataCanvasPath: "/a.thoughtorio"}, func(lane int, chunk string) {
+		mu.Lock()
+		defer mu.Unlock()
+		chunks[lane] = append(chunks[lane], chunk)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lanes) != len(targets) {
+		t.Fatalf("got %d lanes, want %d", len(lanes), len(targets))
+	}
+	for i, lane := range lanes {
+		if lane.Index != i || lane.Provider != targets[i].Provider || lane.Model != targets[i].Model {
+			t.Errorf("lane %d = %+v, want it in target order", i, lane)
+		}
+	}
+
+	// A failing lane doesn't stop the others
+	if lanes[0].Error != "" || lanes[0].Content != "key alpha-key-for-/a.thoughtorio" || lanes[0].FinishReason != FinishReasonStop {
+		t.Errorf("slow lane = %+v, want its answer with the canvas's key", lanes[0])
+	}
+	if !strings.Contains(lanes[1].Error, "beta is down") || lanes[1].Content != "" {
+		t.Errorf("broken lane = %+v, want its error alone", lanes[1])
+	}
+	if lanes[2].Error != "" || lanes[2].Content != "key beta-key-for-/a.thoughtorio" {
+		t.Errorf("fast lane = %+v, want its answer", lanes[2])
+	}
+	if lanes[3].Error == "" {
+		t.Errorf("lane for an unknown provider = %+v, want an error", lanes[3])
+	}
+
+	// Chunks are tagged with the lane that produced them
+	if got := strings.Join(chunks[0], ""); got != "alpha:slow" {
+		t.Errorf("lane 0 chunks = %q, want alpha:slow", got)
+	}
+	if got := strings.Join(chunks[2], ""); got != "beta:fast" {
+		t.Errorf("lane 2 chunks = %q, want beta:fast", got)
+	}
+	if len(chunks[1]) != 0 || len(chunks[3]) != 0 {
+		t.Errorf("failed lanes streamed %q and %q", chunks[1], chunks[3])
+	}
+
+	if _, err := pm.CompareCompletions(context.Background(), "prompt", nil, nil, nil); err == nil {
+		t.Error("CompareCompletions without targets succeeded")
+	}
+}
@@ -0,0 +1,34 @@
+package providers
+
+import "strings"
+
+// knownContextLengths lists context windows for models whose APIs don't report them.
+// Entries are matched by prefix, so more specific prefixes must come first.
+var knownContextLengths = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-5", 400000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo-instruct", 4096},
+	{"gpt-3.5-turbo", 16385},
+	{"o1-mini", 128000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+}
+
+// KnownContextLength returns the context window for a well-known model ID, or 0 if unknown
+func KnownContextLength(modelID string) int {
+	id := strings.ToLower(modelID)
+	for _, known := range knownContextLengths {
+		if strings.HasPrefix(id, known.prefix) {
+			return known.tokens
+		}
+	}
+	return 0
+}
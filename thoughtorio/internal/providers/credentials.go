@@ -0,0 +1,50 @@
+package providers
+
+import "context"
+
+// Credentials are the API key and, for providers that take one, the endpoint a request uses
+type Credentials struct {
+	APIKey   string
+	Endpoint string
+}
+
+// CredentialResolver returns the credentials to use for a provider given the
+// key the caller passed, which may be empty or a reference to a stored secret,
+// and the canvas the request comes from, which may be empty
+type CredentialResolver func(providerName, apiKey, canvasPath string) (Credentials, error)
+
+// SetCredentialResolver sets how API keys passed by callers are resolved before use
+func (pm *ProviderManager) SetCredentialResolver(resolver CredentialResolver) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.credentialResolver = resolver
+}
+
+// resolveCredentials applies the credential resolver, if one is set
+func (pm *ProviderManager) resolveCredentials(providerName, apiKey, canvasPath string) (Credentials, error) {
+	pm.mu.RLock()
+	resolver := pm.credentialResolver
+	pm.mu.RUnlock()
+
+	if resolver == nil {
+		return Credentials{APIKey: apiKey}, nil
+	}
+	return resolver(providerName, apiKey, canvasPath)
+}
+
+type endpointKey struct{}
+
+// WithEndpoint returns a context that points the provider at endpoint instead of
+// its configured base URL. An empty endpoint leaves ctx unchanged.
+func WithEndpoint(ctx context.Context, endpoint string) context.Context {
+	if endpoint == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+// endpointFrom returns the endpoint override carried by ctx, if any
+func endpointFrom(ctx context.Context) string {
+	endpoint, _ := ctx.Value(endpointKey{}).(string)
+	return endpoint
+}
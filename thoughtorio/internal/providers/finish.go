@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Normalized reasons a completion stopped generating
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonContentFilter = "content_filter"
+	FinishReasonToolCalls     = "tool_calls"
+	FinishReasonSafety        = "safety"
+)
+
+// SafetyRating is a provider's assessment of a prompt or response for one harm category
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// normalizeFinishReason maps provider-specific finish reasons onto the normalized set.
+// Unknown non-empty reasons are passed through lower-cased.
+func normalizeFinishReason(reason string) string {
+	switch strings.ToLower(reason) {
+	case "":
+		return ""
+	case "stop", "end_turn", "stop_sequence", "eos", "finish_reason_unspecified":
+		return FinishReasonStop
+	case "length", "max_tokens", "max_output_tokens":
+		return FinishReasonLength
+	case "content_filter":
+		return FinishReasonContentFilter
+	case "tool_calls", "function_call", "tool_use", "malformed_function_call":
+		return FinishReasonToolCalls
+	case "safety", "recitation", "blocklist", "prohibited_content", "spii", "image_safety", "language":
+		return FinishReasonSafety
+	default:
+		return strings.ToLower(reason)
+	}
+}
+
+// Defaults for auto-continue when the caller doesn't set limits
+const (
+	defaultContinueBudget   = 8000
+	defaultMaxContinuations = 5
+)
+
+// continuationPrompt asks the model to pick up a reply that hit the output limit
+func continuationPrompt(prompt, partial string) string {
+	return fmt.Sprintf("%s\n\n---\nYour previous reply was cut off because it reached the output limit. This is what you wrote so far:\n\n%s\n\n---\nContinue exactly where it stops. Do not repeat anything already written and do not add any preamble.", prompt, partial)
+}
+
+// completionTokens returns the output tokens a response used, estimating when the provider didn't report usage
+func (pm *ProviderManager) completionTokens(req *CompletionRequest, response AICompletionResponse) int {
+	if response.Usage != nil && response.Usage.CompletionTokens > 0 {
+		return response.Usage.CompletionTokens
+	}
+	return pm.tokenizers.ForModel(req.Provider, req.Model).CountTokens(response.Content + response.Reasoning)
+}
+
+// continueTruncated issues follow-up requests while a response stops on the output
+// limit, stitching the parts together until the reply finishes, the total
+// completion token budget is spent or the continuation limit is reached
+func (pm *ProviderManager) continueTruncated(ctx context.Context, handler CompletionHandler, req CompletionRequest, response AICompletionResponse) (AICompletionResponse, error) {
+	budget := req.Options.ContinueBudget
+	if budget <= 0 {
+		budget = defaultContinueBudget
+	}
+	limit := req.Options.MaxContinuations
+	if limit <= 0 {
+		limit = defaultMaxContinuations
+	}
+
+	prompt := req.Prompt
+	spent := pm.completionTokens(&req, response)
+
+	for response.FinishReason == FinishReasonLength && response.Continuations < limit && spent < budget {
+		if err := ctx.Err(); err != nil {
+			return response, err
+		}
+
+		next := req
+		next.Prompt = continuationPrompt(prompt, response.Content)
+		fitted, _, err := pm.fitPrompt(ctx, handler, next)
+		if err != nil {
+			return response, err
+		}
+		next.Prompt = fitted
+
+		part, err := handler(ctx, &next)
+		if err != nil {
+			// Keep what was produced so far; the finish reason still says it is truncated
+			return response, err
+		}
+		spent += pm.completionTokens(&next, part)
+
+		response = stitchResponses(response, part)
+	}
+
+	return response, nil
+}
+
+// stitchResponses appends a continuation to the response it continues
+func stitchResponses(response, part AICompletionResponse) AICompletionResponse {
+	response.Content += part.Content
+	if part.Reasoning != "" {
+		if response.Reasoning != "" {
+			response.Reasoning += "\n\n"
+		}
+		response.Reasoning += part.Reasoning
+	}
+
+	if part.Usage != nil {
+		if response.Usage == nil {
+			response.Usage = &TokenUsage{}
+		}
+		response.Usage.PromptTokens += part.Usage.PromptTokens
+		response.Usage.CompletionTokens += part.Usage.CompletionTokens
+		response.Usage.TotalTokens += part.Usage.TotalTokens
+		response.Usage.ReasoningTokens += part.Usage.ReasoningTokens
+	}
+
+	response.FinishReason = part.FinishReason
+	response.SafetyRatings = append(response.SafetyRatings, part.SafetyRatings...)
+	response.LatencyMs += part.LatencyMs
+	response.Cost += part.Cost
+	response.Continuations++
+	return response
+}
@@ -0,0 +1,152 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNormalizeFinishReason(t *testing.T) {
+	for reason, want := range map[string]string{
+		"":               "",
+		"stop":           FinishReasonStop,
+		"STOP":           FinishReasonStop,
+		"end_turn":       FinishReasonStop,
+		"length":         FinishReasonLength,
+		"MAX_TOKENS":     FinishReasonLength,
+		"content_filter": FinishReasonContentFilter,
+		"tool_calls":     FinishReasonToolCalls,
+		"SAFETY":         FinishReasonSafety,
+		"RECITATION":     FinishReasonSafety,
+		"SOMETHING_NEW":  "something_new",
+	} {
+		if got := normalizeFinishReason(reason); got != want {
+			t.Errorf("normalizeFinishReason(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
+
+func TestGeminiCompletion(t *testing.T) {
+	decode := func(body string) geminiResponse {
+		t.Helper()
+		result, err := decodeGeminiResponse(strings.NewReader(body), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// A blocked prompt has no candidates, only feedback
+	response, err := geminiCompletion(decode(`{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true},{"category":"HARM_CATEGORY_HATE_SPEECH","probability":"NEGLIGIBLE"}]}}`))
+	if err == nil || !strings.Contains(err.Error(), "blocked the prompt (SAFETY): HARASSMENT: HIGH") {
+		t.Errorf("blocked prompt error = %v", err)
+	}
+	if response.FinishReason != FinishReasonSafety || len(response.SafetyRatings) != 2 || !response.SafetyRatings[0].Blocked {
+		t.Errorf("blocked prompt response = %+v, want the safety finish and ratings", response)
+	}
+
+	// A blocked response has a candidate with no text
+	response, err = geminiCompletion(decode(`{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"MEDIUM"}]}]}`))
+	if err == nil || !strings.Contains(err.Error(), "blocked the response (SAFETY): DANGEROUS_CONTENT: MEDIUM") {
+		t.Errorf("blocked response error = %v", err)
+	}
+	if response.FinishReason != FinishReasonSafety || len(response.SafetyRatings) != 1 {
+		t.Errorf("blocked response = %+v, want the safety finish and rating", response)
+	}
+
+	// Thinking that uses up the output limit is reported rather than "no response"
+	response, err = geminiCompletion(decode(`{"candidates":[{"content":{"parts":[{"text":"pondering","thought":true}]},"finishReason":"MAX_TOKENS"}]}`))
+	if err == nil || !strings.Contains(err.Error(), "output token limit") {
+		t.Errorf("truncated thinking error = %v", err)
+	}
+	if response.FinishReason != FinishReasonLength || response.Reasoning != "pondering" {
+		t.Errorf("truncated thinking response = %+v, want the length finish and the thoughts", response)
+	}
+
+	// An answer keeps its ratings and finish reason
+	response, err = geminiCompletion(decode(`{"candidates":[{"content":{"parts":[{"text":"Hello"}]},"finishReason":"STOP","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"LOW"}]}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1,"totalTokenCount":4}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Content != "Hello" || response.FinishReason != FinishReasonStop || len(response.SafetyRatings) != 1 || response.Usage.TotalTokens != 4 {
+		t.Errorf("answer = %+v, want the content, stop, one rating and usage", response)
+	}
+}
+
+// truncatingProvider answers with parts in turn, every part but the last
+// stopping on the output limit, and records the prompts it was sent
+type truncatingProvider struct {
+	parts   []string
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *truncatingProvider) GetName() string { return "truncating" }
+
+func (p *truncatingProvider) FetchModels(ctx context.Context, apiKey string) ([]Model, error) {
+	return nil, nil
+}
+
+func (p *truncatingProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := len(p.prompts)
+	p.prompts = append(p.prompts, prompt)
+
+	response := AICompletionResponse{
+		Content:       p.parts[i],
+		FinishReason:  FinishReasonLength,
+		Usage:         &TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		SafetyRatings: []SafetyRating{{Category: "part", Probability: p.parts[i]}},
+	}
+	if i == len(p.parts)-1 {
+		response.FinishReason = FinishReasonStop
+	}
+	return response, nil
+}
+
+func (p *truncatingProvider) ValidateConfig(config map[string]interface{}) error { return nil }
+
+func (p *truncatingProvider) RequiresAPIKey() bool { return false }
+
+func TestAutoContinue(t *testing.T) {
+	complete := func(options CompletionOptions) (AICompletionResponse, *truncatingProvider) {
+		t.Helper()
+		provider := &truncatingProvider{parts: []string{"Once upon ", "a time ", "the end."}}
+		pm := NewProviderManager()
+		pm.RegisterProvider(provider)
+		response, err := pm.Complete(context.Background(), CompletionRequest{Provider: "truncating", Model: "m", Prompt: "Tell a story.", Options: options})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response, provider
+	}
+
+	// Without auto-continue the truncated reply is returned as it is
+	response, provider := complete(CompletionOptions{})
+	if response.Content != "Once upon " || response.FinishReason != FinishReasonLength || len(provider.prompts) != 1 {
+		t.Errorf("response = %+v after %d requests, want the first part, truncated", response, len(provider.prompts))
+	}
+
+	response, provider = complete(CompletionOptions{AutoContinue: true})
+	if response.Content != "Once upon a time the end." || response.FinishReason != FinishReasonStop || response.Continuations != 2 {
+		t.Errorf("response = %+v, want the parts stitched and finished after two continuations", response)
+	}
+	if response.Usage.CompletionTokens != 15 || response.Usage.TotalTokens != 45 || len(response.SafetyRatings) != 3 {
+		t.Errorf("usage %+v and ratings %+v, want them summed over the three requests", response.Usage, response.SafetyRatings)
+	}
+	if len(provider.prompts) != 3 || !strings.Contains(provider.prompts[2], "Tell a story.") || !strings.Contains(provider.prompts[2], "Once upon a time ") {
+		t.Errorf("prompts = %q, want continuations carrying the prompt and the reply so far", provider.prompts)
+	}
+
+	// The continuation limit and the token budget both stop it early
+	response, _ = complete(CompletionOptions{AutoContinue: true, MaxContinuations: 1})
+	if response.Content != "Once upon a time " || response.FinishReason != FinishReasonLength || response.Continuations != 1 {
+		t.Errorf("response = %+v, want one continuation, still truncated", response)
+	}
+	response, _ = complete(CompletionOptions{AutoContinue: true, ContinueBudget: 10})
+	if response.Content != "Once upon a time " || response.Continuations != 1 {
+		t.Errorf("response = %+v, want to stop once 10 completion tokens are spent", response)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -47,6 +49,7 @@ type GeminiAPIModelInfo struct {
 	DisplayName                 string   `json:"displayName"`
 	Description                 string   `json:"description"`
 	SupportedGenerationMethods  []string `json:"supportedGenerationMethods"`
+	InputTokenLimit             int      `json:"inputTokenLimit"`
 }
 
 // GeminiAPIModelListResponse is the top-level structure for the API's model list response
@@ -111,8 +114,9 @@ func (p *GeminiProvider) FetchModels(ctx context.Context, apiKey string) ([]Mode
 			// Ensure we have a valid model ID
 			if sdkModelID != "" {
 				models = append(models, Model{
-					ID:   sdkModelID,
-					Name: model.DisplayName,
+					ID:            sdkModelID,
+					Name:          model.DisplayName,
+					ContextLength: model.InputTokenLimit,
 				})
 			}
 		}
@@ -132,6 +136,43 @@ func (p *GeminiProvider) FetchModels(ctx context.Context, apiKey string) ([]Mode
 
 // GetCompletion performs text completion using Gemini
 func (p *GeminiProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, apiKey, nil)
+}
+
+// StreamCompletion performs text completion using Gemini, calling onChunk as output arrives
+func (p *GeminiProvider) StreamCompletion(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, apiKey, onChunk)
+}
+
+// complete calls generateContent, or streamGenerateContent when onChunk is set
+func (p *GeminiProvider) complete(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	options := CompletionOptionsFrom(ctx)
+
+	generationConfig := map[string]interface{}{
+		"maxOutputTokens": 2000,
+	}
+	if options.MaxTokens > 0 {
+		generationConfig["maxOutputTokens"] = options.MaxTokens
+	}
+	if options.Temperature != nil {
+		generationConfig["temperature"] = *options.Temperature
+	}
+	if options.TopP != nil {
+		generationConfig["topP"] = *options.TopP
+	}
+	// Thinking tokens count against maxOutputTokens, so thinking models get the
+	// larger reasoning default and ask for their thoughts back
+	if IsReasoningModel(model) || options.wantsReasoning() {
+		thinkingConfig := map[string]interface{}{"includeThoughts": true}
+		if budget := options.thinkingBudget(); budget > 0 {
+			thinkingConfig["thinkingBudget"] = budget
+		}
+		generationConfig["thinkingConfig"] = thinkingConfig
+		if options.MaxTokens == 0 {
+			generationConfig["maxOutputTokens"] = defaultReasoningMaxTokens + options.thinkingBudget()
+		}
+	}
+
 	reqBody := map[string]interface{}{
 		"contents": []map[string]interface{}{
 			{
@@ -140,9 +181,12 @@ func (p *GeminiProvider) GetCompletion(ctx context.Context, model, prompt, apiKe
 				},
 			},
 		},
-		"generationConfig": map[string]interface{}{
-			"maxOutputTokens": 2000,
-		},
+		"generationConfig": generationConfig,
+	}
+	if options.SystemPrompt != "" {
+		reqBody["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]string{{"text": options.SystemPrompt}},
+		}
 	}
 	
 	jsonBody, err := json.Marshal(reqBody)
@@ -151,8 +195,11 @@ func (p *GeminiProvider) GetCompletion(ctx context.Context, model, prompt, apiKe
 	}
 	
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", model, apiKey)
+	if onChunk != nil {
+		url = fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:streamGenerateContent?alt=sse&key=%s", model, apiKey)
+	}
 	
-	client := &http.Client{Timeout: 60 * time.Second}
+	client := clientFor(onChunk, 60*time.Second)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
@@ -186,30 +233,191 @@ func (p *GeminiProvider) GetCompletion(ctx context.Context, model, prompt, apiKe
 		return AICompletionResponse{Error: errMsg}, fmt.Errorf(errMsg)
 	}
 	
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-		Error struct {
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-	
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	result, err := decodeGeminiResponse(resp.Body, onChunk)
+	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
 	}
 	
+	return geminiCompletion(result)
+}
+
+// geminiCompletion converts a decoded response, reporting blocked prompts and
+// empty answers as errors that carry the finish reason and safety ratings
+func geminiCompletion(result geminiResponse) (AICompletionResponse, error) {
 	if result.Error.Message != "" {
 		return AICompletionResponse{Error: result.Error.Message}, fmt.Errorf("Gemini error: %s", result.Error.Message)
 	}
+
+	var usage *TokenUsage
+	if result.UsageMetadata != nil {
+		usage = &TokenUsage{
+			PromptTokens:     result.UsageMetadata.PromptTokenCount,
+			CompletionTokens: result.UsageMetadata.CandidatesTokenCount + result.UsageMetadata.ThoughtsTokenCount,
+			TotalTokens:      result.UsageMetadata.TotalTokenCount,
+			ReasoningTokens:  result.UsageMetadata.ThoughtsTokenCount,
+		}
+	}
+
+	// A blocked prompt returns no candidates, only the block reason and ratings
+	if feedback := result.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		ratings := toSafetyRatings(feedback.SafetyRatings)
+		errMsg := fmt.Sprintf("Gemini blocked the prompt (%s)%s", feedback.BlockReason, describeBlockedRatings(ratings))
+		return AICompletionResponse{Error: errMsg, FinishReason: FinishReasonSafety, SafetyRatings: ratings, Usage: usage}, fmt.Errorf("%s", errMsg)
+	}
 	
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return AICompletionResponse{Error: "No response from Gemini"}, fmt.Errorf("no response from Gemini")
+	if len(result.Candidates) == 0 {
+		return AICompletionResponse{Error: "No response from Gemini", Usage: usage}, fmt.Errorf("no response from Gemini")
 	}
+	candidate := result.Candidates[0]
 	
-	return AICompletionResponse{Content: result.Candidates[0].Content.Parts[0].Text}, nil
-}
\ No newline at end of file
+	// Thinking models return thought summaries as separate parts
+	var content, thoughts strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			thoughts.WriteString(part.Text)
+		} else {
+			content.WriteString(part.Text)
+		}
+	}
+
+	response := AICompletionResponse{
+		Content:       content.String(),
+		Usage:         usage,
+		FinishReason:  normalizeFinishReason(candidate.FinishReason),
+		SafetyRatings: toSafetyRatings(candidate.SafetyRatings),
+	}
+
+	if response.Content == "" {
+		var errMsg string
+		switch response.FinishReason {
+		case FinishReasonSafety:
+			errMsg = fmt.Sprintf("Gemini blocked the response (%s)%s", candidate.FinishReason, describeBlockedRatings(response.SafetyRatings))
+		case FinishReasonLength:
+			errMsg = "Gemini reached the output token limit before answering; raise the limit or lower the thinking budget"
+		default:
+			errMsg = "No response from Gemini"
+		}
+		response = withReasoning(response, thoughts.String())
+		response.Error = errMsg
+		return response, fmt.Errorf("%s", errMsg)
+	}
+	
+	return withReasoning(response, thoughts.String()), nil
+}
+
+// geminiResponse is a generateContent response, or one event of a streamed one
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []geminiPart `json:"parts"`
+		} `json:"content"`
+		FinishReason  string               `json:"finishReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason   string               `json:"blockReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type geminiPart struct {
+	Text    string `json:"text"`
+	Thought bool   `json:"thought"`
+}
+
+// decodeGeminiResponse reads a generateContent response. When onChunk is set the
+// body is a stream of partial responses: their parts are concatenated, answer
+// text is passed on as it arrives and the last finish reason, ratings and usage win.
+func decodeGeminiResponse(body io.Reader, onChunk func(string)) (geminiResponse, error) {
+	var result geminiResponse
+	if onChunk == nil {
+		err := json.NewDecoder(body).Decode(&result)
+		return result, err
+	}
+
+	err := readServerSentEvents(body, func(data []byte) error {
+		var event geminiResponse
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("invalid stream event: %w", err)
+		}
+		if event.Error.Message != "" {
+			result.Error = event.Error
+		}
+		if event.PromptFeedback != nil {
+			result.PromptFeedback = event.PromptFeedback
+		}
+		if event.UsageMetadata != nil {
+			result.UsageMetadata = event.UsageMetadata
+		}
+		if len(event.Candidates) == 0 {
+			return nil
+		}
+
+		candidate := event.Candidates[0]
+		for _, part := range candidate.Content.Parts {
+			if !part.Thought && part.Text != "" {
+				onChunk(part.Text)
+			}
+		}
+		if len(result.Candidates) == 0 {
+			result.Candidates = event.Candidates[:1]
+			return nil
+		}
+		merged := &result.Candidates[0]
+		merged.Content.Parts = append(merged.Content.Parts, candidate.Content.Parts...)
+		if candidate.FinishReason != "" {
+			merged.FinishReason = candidate.FinishReason
+		}
+		if len(candidate.SafetyRatings) > 0 {
+			merged.SafetyRatings = candidate.SafetyRatings
+		}
+		return nil
+	})
+	return result, err
+}
+
+// geminiSafetyRating is a safety rating as returned by the Gemini API
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked"`
+}
+
+// toSafetyRatings converts Gemini safety ratings to the provider-neutral form
+func toSafetyRatings(ratings []geminiSafetyRating) []SafetyRating {
+	if len(ratings) == 0 {
+		return nil
+	}
+	converted := make([]SafetyRating, len(ratings))
+	for i, rating := range ratings {
+		converted[i] = SafetyRating{
+			Category:    rating.Category,
+			Probability: rating.Probability,
+			Blocked:     rating.Blocked,
+		}
+	}
+	return converted
+}
+
+// describeBlockedRatings lists the categories that caused a block, for error messages
+func describeBlockedRatings(ratings []SafetyRating) string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "HIGH" || rating.Probability == "MEDIUM" {
+			categories = append(categories, fmt.Sprintf("%s: %s", strings.TrimPrefix(rating.Category, "HARM_CATEGORY_"), rating.Probability))
+		}
+	}
+	if len(categories) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(categories, ", ")
+}
@@ -1,17 +1,60 @@
 package providers
 
-import "context"
+import (
+	"context"
+
+	"thoughtorio/internal/tokenizer"
+)
 
 // Model represents a model from any provider
 type Model struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ContextLength int    `json:"contextLength,omitempty"`
+	// Pricing is set when the provider reports what the model costs
+	Pricing *ModelPricing `json:"pricing,omitempty"`
 }
 
 // AICompletionResponse represents the response from an AI completion request
 type AICompletionResponse struct {
 	Content string `json:"content"`
-	Error   string `json:"error,omitempty"`
+	// Reasoning is the model's thinking, kept apart from Content so nodes can show or hide it
+	Reasoning string             `json:"reasoning,omitempty"`
+	Error     string             `json:"error,omitempty"`
+	Timings   *CompletionTimings `json:"timings,omitempty"`
+	Usage     *TokenUsage        `json:"usage,omitempty"`
+	// FinishReason is why generation stopped: stop, length, content_filter, tool_calls or safety
+	FinishReason  string         `json:"finishReason,omitempty"`
+	SafetyRatings []SafetyRating `json:"safetyRatings,omitempty"`
+	// Continuations counts the follow-up requests auto-continue stitched onto this reply
+	Continuations int `json:"continuations,omitempty"`
+	// Cost is the price of the request in US dollars, set when spending is tracked
+	Cost float64 `json:"cost,omitempty"`
+	// LatencyMs is the wall-clock time of the provider call, set by the timing interceptor
+	LatencyMs float64 `json:"latencyMs,omitempty"`
+	// Truncation reports how the prompt was budgeted against the model's context window
+	Truncation *tokenizer.TruncationReport `json:"truncation,omitempty"`
+}
+
+// TokenUsage reports the tokens a provider billed for a completion
+type TokenUsage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+	// ReasoningTokens is the part of CompletionTokens spent thinking, when reported
+	ReasoningTokens int `json:"reasoningTokens,omitempty"`
+}
+
+// CompletionTimings reports the load and evaluation performance of a completion,
+// as returned by providers that expose it (currently Ollama)
+type CompletionTimings struct {
+	TotalDurationMs      float64 `json:"totalDurationMs"`
+	LoadDurationMs       float64 `json:"loadDurationMs"`
+	PromptEvalDurationMs float64 `json:"promptEvalDurationMs"`
+	EvalDurationMs       float64 `json:"evalDurationMs"`
+	PromptEvalCount      int     `json:"promptEvalCount"`
+	EvalCount            int     `json:"evalCount"`
+	TokensPerSecond      float64 `json:"tokensPerSecond"`
 }
 
 // AIProvider defines the interface that all AI providers must implement
@@ -30,4 +73,13 @@ type AIProvider interface {
 	
 	// RequiresAPIKey returns true if this provider requires an API key
 	RequiresAPIKey() bool
-}
\ No newline at end of file
+}
+
+// StreamingProvider is implemented by providers that can deliver partial output
+// while a completion is being generated
+type StreamingProvider interface {
+	AIProvider
+
+	// StreamCompletion performs text completion, calling onChunk with each piece of new output
+	StreamCompletion(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error)
+}
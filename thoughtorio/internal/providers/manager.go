@@ -3,19 +3,55 @@ package providers
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+
+	"thoughtorio/internal/tokenizer"
 )
 
 // ProviderManager manages all AI providers
 type ProviderManager struct {
 	providers map[string]AIProvider
 	mu        sync.RWMutex
+
+	// Models seen by FetchModels, keyed by provider then model ID, used for context
+	// limits and pricing; persisted in cacheDir when one is set
+	modelCache   map[string]map[string]Model
+	cacheDir     string
+	tokenizers   *tokenizer.Registry
+	budgetConfig tokenizer.BudgetConfig
+
+	// Out-of-process providers discovered by LoadPlugins, keyed by provider name;
+	// closed is set by Close so a discovery still running doesn't register more
+	plugins map[string]*PluginProvider
+	closed  bool
+
+	// Turns the API key a caller passed into the credentials sent to the provider
+	credentialResolver CredentialResolver
+
+	// Interceptor chains applied to every provider and to individual providers
+	interceptors         []Interceptor
+	providerInterceptors map[string][]Interceptor
+}
+
+// ProviderInfo describes a registered provider for the frontend
+type ProviderInfo struct {
+	Name           string `json:"name"`
+	RequiresAPIKey bool   `json:"requiresApiKey"`
+	Plugin         bool   `json:"plugin"`
+	Streaming      bool   `json:"streaming"`
 }
 
 // NewProviderManager creates a new provider manager with all default providers
 func NewProviderManager() *ProviderManager {
 	pm := &ProviderManager{
-		providers: make(map[string]AIProvider),
+		providers:    make(map[string]AIProvider),
+		modelCache:   make(map[string]map[string]Model),
+		tokenizers:   tokenizer.NewRegistry(tokenizer.DefaultDir()),
+		budgetConfig: tokenizer.DefaultBudgetConfig(),
+		plugins:      make(map[string]*PluginProvider),
+
+		providerInterceptors: make(map[string][]Interceptor),
 	}
 
 	// Register all default providers
@@ -23,6 +59,10 @@ func NewProviderManager() *ProviderManager {
 	pm.RegisterProvider(NewOpenAIProvider())
 	pm.RegisterProvider(NewGeminiProvider())
 	pm.RegisterProvider(NewOllamaProvider())
+	pm.RegisterProvider(NewAzureOpenAIProvider())
+
+	// Record latency on every completion
+	pm.Use(NewTimingInterceptor(nil))
 
 	return pm
 }
@@ -38,12 +78,12 @@ func (pm *ProviderManager) RegisterProvider(provider AIProvider) {
 func (pm *ProviderManager) GetProvider(name string) (AIProvider, error) {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
-	
+
 	provider, exists := pm.providers[name]
 	if !exists {
 		return nil, fmt.Errorf("provider '%s' not found", name)
 	}
-	
+
 	return provider, nil
 }
 
@@ -51,33 +91,170 @@ func (pm *ProviderManager) GetProvider(name string) (AIProvider, error) {
 func (pm *ProviderManager) ListProviders() []string {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
-	
+
 	names := make([]string, 0, len(pm.providers))
 	for name := range pm.providers {
 		names = append(names, name)
 	}
-	
+
 	return names
 }
 
-// FetchModels fetches models for a specific provider
-func (pm *ProviderManager) FetchModels(ctx context.Context, providerName, apiKey string) ([]Model, error) {
+// ListProviderInfo returns details of all registered providers, sorted by name
+func (pm *ProviderManager) ListProviderInfo() []ProviderInfo {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	infos := make([]ProviderInfo, 0, len(pm.providers))
+	for name, provider := range pm.providers {
+		_, streaming := provider.(StreamingProvider)
+		infos = append(infos, ProviderInfo{
+			Name:           name,
+			RequiresAPIKey: provider.RequiresAPIKey(),
+			Plugin:         pm.plugins[name] != nil,
+			Streaming:      streaming,
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// FetchModels fetches models for a specific provider through the interceptor
+// chain. canvasPath, when set, lets credentials come from the canvas's .env file.
+func (pm *ProviderManager) FetchModels(ctx context.Context, providerName, apiKey, canvasPath string) ([]Model, error) {
 	provider, err := pm.GetProvider(providerName)
 	if err != nil {
 		return nil, err
 	}
-	
-	return provider.FetchModels(ctx, apiKey)
+
+	credentials, err := pm.resolveCredentials(providerName, apiKey, canvasPath)
+	if err != nil {
+		return nil, err
+	}
+	apiKey = credentials.APIKey
+	ctx = WithEndpoint(ctx, credentials.Endpoint)
+
+	handler := func(ctx context.Context, req *ModelsRequest) ([]Model, error) {
+		models, err := provider.FetchModels(ctx, req.APIKey)
+		if err != nil {
+			return nil, err
+		}
+
+		pm.cacheModels(req.Provider, models)
+		return models, nil
+	}
+
+	for _, interceptor := range pm.interceptorsFor(providerName) {
+		handler = interceptor.WrapModels(handler)
+	}
+
+	return handler(ctx, &ModelsRequest{Provider: providerName, APIKey: apiKey})
 }
 
 // GetCompletion gets a completion from a specific provider
 func (pm *ProviderManager) GetCompletion(ctx context.Context, providerName, model, prompt, apiKey string) (AICompletionResponse, error) {
-	provider, err := pm.GetProvider(providerName)
+	return pm.Complete(ctx, CompletionRequest{
+		Provider: providerName,
+		Model:    model,
+		Prompt:   prompt,
+		APIKey:   apiKey,
+	})
+}
+
+// StreamCompletion gets a completion from a specific provider, calling onChunk with
+// partial output. Providers that can't stream deliver their whole response as one chunk.
+func (pm *ProviderManager) StreamCompletion(ctx context.Context, providerName, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	return pm.Complete(ctx, CompletionRequest{
+		Provider: providerName,
+		Model:    model,
+		Prompt:   prompt,
+		APIKey:   apiKey,
+		OnChunk:  onChunk,
+	})
+}
+
+// Complete runs a completion request through prompt budgeting and the interceptor chain
+func (pm *ProviderManager) Complete(ctx context.Context, req CompletionRequest) (AICompletionResponse, error) {
+	provider, err := pm.GetProvider(req.Provider)
 	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
 	}
-	
-	return provider.GetCompletion(ctx, model, prompt, apiKey)
+
+	if err := req.Options.Validate(); err != nil {
+		return AICompletionResponse{Error: err.Error()}, err
+	}
+
+	credentials, err := pm.resolveCredentials(req.Provider, req.APIKey, req.Metadata[MetadataCanvasPath])
+	if err != nil {
+		return AICompletionResponse{Error: err.Error()}, err
+	}
+	req.APIKey = credentials.APIKey
+	ctx = WithEndpoint(ctx, credentials.Endpoint)
+
+	handler := func(ctx context.Context, req *CompletionRequest) (AICompletionResponse, error) {
+		ctx = WithCompletionOptions(ctx, req.Options)
+		if streaming, ok := provider.(StreamingProvider); ok && req.OnChunk != nil {
+			return streaming.StreamCompletion(ctx, req.Model, req.Prompt, req.APIKey, req.OnChunk)
+		}
+
+		response, err := provider.GetCompletion(ctx, req.Model, req.Prompt, req.APIKey)
+		if err == nil && response.Content != "" && req.OnChunk != nil {
+			req.OnChunk(response.Content)
+		}
+		return response, err
+	}
+
+	for _, interceptor := range pm.interceptorsFor(req.Provider) {
+		handler = interceptor.WrapCompletion(handler)
+	}
+
+	fitted, report, err := pm.fitPrompt(ctx, handler, req)
+	if err != nil {
+		return AICompletionResponse{Error: err.Error(), Truncation: &report}, err
+	}
+	req.Prompt = fitted
+
+	response, err := handler(ctx, &req)
+	if err == nil && req.Options.AutoContinue && response.FinishReason == FinishReasonLength {
+		response, err = pm.continueTruncated(ctx, handler, req, response)
+	}
+	if report.Applied {
+		response.Truncation = &report
+	}
+	return response, err
+}
+
+// Use adds interceptors that wrap calls to every provider
+func (pm *ProviderManager) Use(interceptors ...Interceptor) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.interceptors = append(pm.interceptors, interceptors...)
+}
+
+// UseFor adds interceptors that only wrap calls to the named provider
+func (pm *ProviderManager) UseFor(providerName string, interceptors ...Interceptor) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.providerInterceptors[providerName] = append(pm.providerInterceptors[providerName], interceptors...)
+}
+
+// interceptorsFor returns the chain for a provider, innermost first, ready to be
+// applied by wrapping a handler in order
+func (pm *ProviderManager) interceptorsFor(providerName string) []Interceptor {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	chain := make([]Interceptor, 0, len(pm.interceptors)+len(pm.providerInterceptors[providerName]))
+	chain = append(chain, pm.interceptors...)
+	chain = append(chain, pm.providerInterceptors[providerName]...)
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
 }
 
 // ValidateProviderConfig validates configuration for a specific provider
@@ -86,7 +263,7 @@ func (pm *ProviderManager) ValidateProviderConfig(providerName string, config ma
 	if err != nil {
 		return err
 	}
-	
+
 	return provider.ValidateConfig(config)
 }
 
@@ -96,6 +273,36 @@ func (pm *ProviderManager) ProviderRequiresAPIKey(providerName string) (bool, er
 	if err != nil {
 		return false, err
 	}
-	
+
 	return provider.RequiresAPIKey(), nil
-}
\ No newline at end of file
+}
+
+// GetOllamaProvider returns the registered local Ollama provider for model management
+func (pm *ProviderManager) GetOllamaProvider() (*OllamaProvider, error) {
+	provider, err := pm.GetProvider("local")
+	if err != nil {
+		return nil, err
+	}
+
+	ollama, ok := provider.(*OllamaProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider 'local' is not an Ollama provider")
+	}
+
+	return ollama, nil
+}
+
+// GetAzureOpenAIProvider returns the registered Azure OpenAI provider for configuration
+func (pm *ProviderManager) GetAzureOpenAIProvider() (*AzureOpenAIProvider, error) {
+	provider, err := pm.GetProvider("azure")
+	if err != nil {
+		return nil, err
+	}
+
+	azure, ok := provider.(*AzureOpenAIProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider 'azure' is not an Azure OpenAI provider")
+	}
+
+	return azure, nil
+}
@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"context"
+	"time"
+)
+
+// Interceptors wrap every model listing and completion that goes through the
+// ProviderManager, so cross-cutting behaviour (logging, caching, metrics,
+// redaction, retries) lives in one place instead of in each provider.
+//
+// An interceptor receives the next handler in the chain and returns a handler
+// that runs in its place. It may inspect or modify the request before calling
+// next, inspect or modify the response afterwards, or return early without
+// calling next at all (for example to serve a cached response):
+//
+//	redact := providers.InterceptorFuncs{
+//		Label: "redact-emails",
+//		Completion: func(next providers.CompletionHandler) providers.CompletionHandler {
+//			return func(ctx context.Context, req *providers.CompletionRequest) (providers.AICompletionResponse, error) {
+//				req.Prompt = emailPattern.ReplaceAllString(req.Prompt, "[email]")
+//				return next(ctx, req)
+//			}
+//		},
+//	}
+//	pm.Use(redact)                    // every provider
+//	pm.UseFor("openrouter", redact)   // only OpenRouter
+//
+// Global interceptors run outermost in the order they were added, followed by
+// the provider's own interceptors. Prompt budgeting runs before all of them, so
+// interceptors see the prompt that will actually be sent.
+
+// Metadata keys set by the app on completion requests
+const (
+	MetadataCanvasPath = "canvasPath"
+	MetadataNodeID     = "nodeId"
+	// MetadataPurpose marks requests the manager makes on the app's behalf,
+	// such as PurposeSummarize when prompt budgeting condenses context
+	MetadataPurpose = "purpose"
+)
+
+// PurposeSummarize is the MetadataPurpose of prompt budgeting summaries
+const PurposeSummarize = "summarize"
+
+// CompletionRequest describes a completion as it passes through the interceptor chain
+type CompletionRequest struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Prompt   string `json:"prompt"`
+	APIKey   string `json:"-"`
+	// Options carries per-request settings such as reasoning effort
+	Options CompletionOptions `json:"options,omitempty"`
+	// Metadata carries caller context such as the canvas path or node ID
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// OnChunk receives partial output when the caller asked for streaming
+	OnChunk func(string) `json:"-"`
+}
+
+// ModelsRequest describes a model listing as it passes through the interceptor chain
+type ModelsRequest struct {
+	Provider string `json:"provider"`
+	APIKey   string `json:"-"`
+}
+
+// CompletionHandler performs a completion request
+type CompletionHandler func(ctx context.Context, req *CompletionRequest) (AICompletionResponse, error)
+
+// ModelsHandler performs a model listing request
+type ModelsHandler func(ctx context.Context, req *ModelsRequest) ([]Model, error)
+
+// Interceptor wraps the handlers used for completions and model listing
+type Interceptor interface {
+	// Name identifies the interceptor in logs
+	Name() string
+
+	// WrapCompletion returns a handler that runs in place of next for completions
+	WrapCompletion(next CompletionHandler) CompletionHandler
+
+	// WrapModels returns a handler that runs in place of next for model listing
+	WrapModels(next ModelsHandler) ModelsHandler
+}
+
+// InterceptorFuncs builds an Interceptor from functions; nil functions pass requests through
+type InterceptorFuncs struct {
+	Label      string
+	Completion func(next CompletionHandler) CompletionHandler
+	Models     func(next ModelsHandler) ModelsHandler
+}
+
+// Name returns the interceptor label
+func (f InterceptorFuncs) Name() string {
+	return f.Label
+}
+
+// WrapCompletion applies the Completion function, if any
+func (f InterceptorFuncs) WrapCompletion(next CompletionHandler) CompletionHandler {
+	if f.Completion == nil {
+		return next
+	}
+	return f.Completion(next)
+}
+
+// WrapModels applies the Models function, if any
+func (f InterceptorFuncs) WrapModels(next ModelsHandler) ModelsHandler {
+	if f.Models == nil {
+		return next
+	}
+	return f.Models(next)
+}
+
+// TimingSample is a single timed provider call reported by the timing interceptor
+type TimingSample struct {
+	Operation string        `json:"operation"`
+	Provider  string        `json:"provider"`
+	Model     string        `json:"model,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Err       error         `json:"-"`
+}
+
+// NewTimingInterceptor measures every call, records completion latency on the
+// response and passes each sample to observe when it isn't nil
+func NewTimingInterceptor(observe func(TimingSample)) Interceptor {
+	return InterceptorFuncs{
+		Label: "timing",
+		Completion: func(next CompletionHandler) CompletionHandler {
+			return func(ctx context.Context, req *CompletionRequest) (AICompletionResponse, error) {
+				start := time.Now()
+				response, err := next(ctx, req)
+				elapsed := time.Since(start)
+
+				response.LatencyMs = float64(elapsed) / float64(time.Millisecond)
+				if observe != nil {
+					observe(TimingSample{Operation: "completion", Provider: req.Provider, Model: req.Model, Duration: elapsed, Err: err})
+				}
+				return response, err
+			}
+		},
+		Models: func(next ModelsHandler) ModelsHandler {
+			return func(ctx context.Context, req *ModelsRequest) ([]Model, error) {
+				start := time.Now()
+				models, err := next(ctx, req)
+				if observe != nil {
+					observe(TimingSample{Operation: "models", Provider: req.Provider, Duration: time.Since(start), Err: err})
+				}
+				return models, err
+			}
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// recordingInterceptor appends its label to entered as calls pass through it
+func recordingInterceptor(label string, entered *[]string) Interceptor {
+	return InterceptorFuncs{
+		Label: label,
+		Completion: func(next CompletionHandler) CompletionHandler {
+			return func(ctx context.Context, req *CompletionRequest) (AICompletionResponse, error) {
+				*entered = append(*entered, label)
+				return next(ctx, req)
+			}
+		},
+		Models: func(next ModelsHandler) ModelsHandler {
+			return func(ctx context.Context, req *ModelsRequest) ([]Model, error) {
+				*entered = append(*entered, label)
+				return next(ctx, req)
+			}
+		},
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	pm := NewProviderManager()
+	pm.RegisterProvider(echoProvider{})
+
+	var entered []string
+	pm.UseFor("echo", recordingInterceptor("echo-1", &entered))
+	pm.Use(recordingInterceptor("global-1", &entered))
+	pm.UseFor("other", recordingInterceptor("other", &entered))
+	pm.UseFor("echo", recordingInterceptor("echo-2", &entered))
+	pm.Use(recordingInterceptor("global-2", &entered))
+
+	// Global interceptors run outermost in the order added, then the provider's own
+	want := []string{"global-1", "global-2", "echo-1", "echo-2"}
+	if _, err := pm.Complete(context.Background(), CompletionRequest{Provider: "echo", Model: "m", Prompt: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entered, want) {
+		t.Errorf("completion entered %v, want %v", entered, want)
+	}
+
+	entered = nil
+	if _, err := pm.FetchModels(context.Background(), "echo", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entered, want) {
+		t.Errorf("model listing entered %v, want %v", entered, want)
+	}
+}
@@ -1,19 +1,26 @@
 package providers
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
 // OllamaProvider implements the AIProvider interface for local Ollama models
 type OllamaProvider struct {
-	name    string
-	baseURL string
+	name           string
+	baseURL        string
+	config         OllamaConfig
+	contextLengths map[string]int
+	mu             sync.RWMutex
 }
 
 // NewOllamaProvider creates a new Ollama provider instance
@@ -36,15 +43,64 @@ func (p *OllamaProvider) RequiresAPIKey() bool {
 
 // ValidateConfig validates Ollama-specific configuration
 func (p *OllamaProvider) ValidateConfig(config map[string]interface{}) error {
-	// Ollama doesn't require specific configuration validation
-	// Could add base URL validation in the future if customizable
+	ollamaConfig, err := ParseOllamaConfig(config)
+	if err != nil {
+		return err
+	}
+	return ollamaConfig.Validate()
+}
+
+// GetConfig returns the runtime configuration used for completions
+func (p *OllamaProvider) GetConfig() OllamaConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config
+}
+
+// SetConfig validates and applies the runtime configuration used for completions
+func (p *OllamaProvider) SetConfig(config OllamaConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config
 	return nil
 }
 
+// SetHost points the provider at an Ollama server given as an OLLAMA_HOST
+// value such as "0.0.0.0:11434", "myhost" or "https://ollama.example.com"
+func (p *OllamaProvider) SetHost(host string) error {
+	baseURL, err := NormalizeOllamaHost(host)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.baseURL = baseURL
+	p.contextLengths = nil
+	return nil
+}
+
+// endpoint returns the server URL for a request: an override carried by ctx, else the configured base URL
+func (p *OllamaProvider) endpoint(ctx context.Context) string {
+	if host := endpointFrom(ctx); host != "" {
+		if baseURL, err := NormalizeOllamaHost(host); err == nil {
+			return baseURL
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.baseURL
+}
+
 // FetchModels retrieves available models from Ollama
 func (p *OllamaProvider) FetchModels(ctx context.Context, apiKey string) ([]Model, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/tags", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.endpoint(ctx)+"/api/tags", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for Ollama models: %w", err)
 	}
@@ -83,12 +139,67 @@ func (p *OllamaProvider) FetchModels(ctx context.Context, apiKey string) ([]Mode
 	return models, nil
 }
 
-// GetCompletion performs text completion using Ollama
+// GetCompletion performs text completion using Ollama's chat endpoint, or the
+// generate endpoint when raw mode is enabled
 func (p *OllamaProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, nil)
+}
+
+// StreamCompletion performs text completion using Ollama, calling onChunk as output arrives
+func (p *OllamaProvider) StreamCompletion(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, onChunk)
+}
+
+// complete sends a chat or generate request, streaming the reply when onChunk is set
+func (p *OllamaProvider) complete(ctx context.Context, model, prompt string, onChunk func(string)) (AICompletionResponse, error) {
+	config := p.GetConfig()
+	completionOptions := CompletionOptionsFrom(ctx)
+
 	reqBody := map[string]interface{}{
 		"model":  model,
-		"prompt": prompt,
-		"stream": false,
+		"stream": onChunk != nil,
+	}
+
+	endpoint := "/api/chat"
+	if config.Raw {
+		// Raw prompts bypass the model's template, so there is no system slot to fill
+		endpoint = "/api/generate"
+		reqBody["prompt"] = prompt
+		if completionOptions.SystemPrompt != "" {
+			reqBody["prompt"] = completionOptions.SystemPrompt + "\n\n" + prompt
+		}
+		reqBody["raw"] = true
+	} else {
+		reqBody["messages"] = completionOptions.chatMessages(model, prompt)
+	}
+
+	options := config.Options.toMap()
+	if options == nil {
+		options = make(map[string]interface{})
+	}
+	if completionOptions.MaxTokens > 0 {
+		options["num_predict"] = completionOptions.MaxTokens
+	}
+	if completionOptions.Temperature != nil {
+		options["temperature"] = *completionOptions.Temperature
+	}
+	if completionOptions.TopP != nil {
+		options["top_p"] = *completionOptions.TopP
+	}
+	if len(options) > 0 {
+		reqBody["options"] = options
+	}
+	// Thinking models return their reasoning separately when think is set;
+	// gpt-oss takes an effort level, the others a plain switch
+	if completionOptions.wantsReasoning() {
+		if completionOptions.ReasoningEffort != "" && strings.Contains(model, "gpt-oss") {
+			reqBody["think"] = completionOptions.ReasoningEffort
+		} else {
+			reqBody["think"] = true
+		}
+	}
+	if keepAlive := config.keepAliveValue(); keepAlive != nil {
+		reqBody["keep_alive"] = keepAlive
 	}
 	
 	jsonBody, err := json.Marshal(reqBody)
@@ -96,8 +207,8 @@ func (p *OllamaProvider) GetCompletion(ctx context.Context, model, prompt, apiKe
 		return AICompletionResponse{Error: err.Error()}, err
 	}
 	
-	client := &http.Client{Timeout: 120 * time.Second} // Longer timeout for local models
-	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/generate", bytes.NewBuffer(jsonBody))
+	client := clientFor(onChunk, 120*time.Second) // Longer timeout for local models
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint(ctx)+endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
 	}
@@ -116,18 +227,118 @@ func (p *OllamaProvider) GetCompletion(ctx context.Context, model, prompt, apiKe
 		return AICompletionResponse{Error: errMsg}, fmt.Errorf(errMsg)
 	}
 	
-	var result struct {
-		Response string `json:"response"`
-		Error    string `json:"error"`
-	}
-	
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	result, err := decodeOllamaResponse(resp.Body, onChunk)
+	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
 	}
 	
 	if result.Error != "" {
 		return AICompletionResponse{Error: result.Error}, fmt.Errorf("Ollama error: %s", result.Error)
 	}
+
+	content, thinking := result.Message.Content, result.Message.Thinking
+	if config.Raw {
+		content, thinking = result.Response, result.Thinking
+	}
+
+	timings := &CompletionTimings{
+		TotalDurationMs:      nanosToMillis(result.TotalDuration),
+		LoadDurationMs:       nanosToMillis(result.LoadDuration),
+		PromptEvalDurationMs: nanosToMillis(result.PromptEvalDuration),
+		EvalDurationMs:       nanosToMillis(result.EvalDuration),
+		PromptEvalCount:      result.PromptEvalCount,
+		EvalCount:            result.EvalCount,
+	}
+	if result.EvalDuration > 0 {
+		timings.TokensPerSecond = float64(result.EvalCount) / (float64(result.EvalDuration) / float64(time.Second))
+	}
+	
+	usage := &TokenUsage{
+		PromptTokens:     result.PromptEvalCount,
+		CompletionTokens: result.EvalCount,
+		TotalTokens:      result.PromptEvalCount + result.EvalCount,
+	}
 	
-	return AICompletionResponse{Content: result.Response}, nil
-}
\ No newline at end of file
+	response := AICompletionResponse{
+		Content:      content,
+		Timings:      timings,
+		Usage:        usage,
+		FinishReason: normalizeFinishReason(result.DoneReason),
+	}
+	return withReasoning(response, thinking), nil
+}
+
+// ollamaResponse is a chat or generate response, or one line of a streamed one
+type ollamaResponse struct {
+	Message struct {
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Response           string `json:"response"`
+	Thinking           string `json:"thinking"`
+	Done               bool   `json:"done"`
+	DoneReason         string `json:"done_reason"`
+	Error              string `json:"error"`
+	TotalDuration      int64  `json:"total_duration"`
+	LoadDuration       int64  `json:"load_duration"`
+	PromptEvalCount    int    `json:"prompt_eval_count"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration"`
+	EvalCount          int    `json:"eval_count"`
+	EvalDuration       int64  `json:"eval_duration"`
+}
+
+// decodeOllamaResponse reads a chat or generate response. When onChunk is set the
+// body holds one JSON object per line: their text is concatenated and passed on
+// as it arrives, and the final line carries the done reason and timings.
+func decodeOllamaResponse(body io.Reader, onChunk func(string)) (ollamaResponse, error) {
+	var result ollamaResponse
+	if onChunk == nil {
+		err := json.NewDecoder(body).Decode(&result)
+		return result, err
+	}
+
+	var content, thinking, response, responseThinking strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var part ollamaResponse
+		if err := json.Unmarshal(line, &part); err != nil {
+			return result, fmt.Errorf("invalid stream line: %w", err)
+		}
+		if part.Error != "" {
+			result.Error = part.Error
+			break
+		}
+
+		content.WriteString(part.Message.Content)
+		thinking.WriteString(part.Message.Thinking)
+		response.WriteString(part.Response)
+		responseThinking.WriteString(part.Thinking)
+		if text := part.Message.Content + part.Response; text != "" {
+			onChunk(text)
+		}
+		if part.Done {
+			result = part
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	result.Message.Content = content.String()
+	result.Message.Thinking = thinking.String()
+	result.Response = response.String()
+	result.Thinking = responseThinking.String()
+	return result, nil
+}
+
+// nanosToMillis converts an Ollama nanosecond duration to fractional milliseconds
+func nanosToMillis(nanos int64) float64 {
+	return float64(nanos) / float64(time.Millisecond)
+}
@@ -0,0 +1,157 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OllamaOptions mirrors the runtime "options" object accepted by Ollama.
+// Zero values are omitted so Ollama falls back to the model's defaults;
+// pointer fields are used where zero is a meaningful setting.
+type OllamaOptions struct {
+	NumCtx        int      `json:"num_ctx,omitempty"`
+	NumPredict    int      `json:"num_predict,omitempty"`
+	NumGPU        *int     `json:"num_gpu,omitempty"`
+	NumThread     int      `json:"num_thread,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopK          int      `json:"top_k,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	Stop          []string `json:"stop,omitempty"`
+}
+
+// OllamaConfig holds the runtime configuration applied to every Ollama completion
+type OllamaConfig struct {
+	Options OllamaOptions `json:"options"`
+	// KeepAlive controls how long the model stays loaded after a request:
+	// a duration such as "10m", "0" to unload immediately or "-1" to keep it loaded
+	KeepAlive string `json:"keep_alive,omitempty"`
+	// Raw sends the prompt through /api/generate without applying the model's template
+	Raw bool `json:"raw,omitempty"`
+}
+
+// ParseOllamaConfig converts a generic provider configuration map into an OllamaConfig
+func ParseOllamaConfig(config map[string]interface{}) (OllamaConfig, error) {
+	var ollamaConfig OllamaConfig
+	if len(config) == 0 {
+		return ollamaConfig, nil
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ollamaConfig, fmt.Errorf("invalid Ollama configuration: %w", err)
+	}
+	if err := json.Unmarshal(data, &ollamaConfig); err != nil {
+		return ollamaConfig, fmt.Errorf("invalid Ollama configuration: %w", err)
+	}
+
+	return ollamaConfig, nil
+}
+
+// Validate checks that the configuration only contains values Ollama accepts
+func (c OllamaConfig) Validate() error {
+	o := c.Options
+	if o.NumCtx < 0 {
+		return fmt.Errorf("num_ctx cannot be negative")
+	}
+	if o.NumPredict < -2 {
+		return fmt.Errorf("num_predict must be -1 (infinite), -2 (fill context) or a positive number")
+	}
+	if o.NumGPU != nil && *o.NumGPU < -1 {
+		return fmt.Errorf("num_gpu must be -1 (auto) or a non-negative number of layers")
+	}
+	if o.NumThread < 0 {
+		return fmt.Errorf("num_thread cannot be negative")
+	}
+	if o.Temperature != nil && *o.Temperature < 0 {
+		return fmt.Errorf("temperature cannot be negative")
+	}
+	if o.TopK < 0 {
+		return fmt.Errorf("top_k cannot be negative")
+	}
+	if o.TopP != nil && (*o.TopP < 0 || *o.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1")
+	}
+	if o.RepeatPenalty != nil && *o.RepeatPenalty < 0 {
+		return fmt.Errorf("repeat_penalty cannot be negative")
+	}
+
+	if c.KeepAlive != "" {
+		if _, err := strconv.Atoi(c.KeepAlive); err != nil {
+			if _, err := time.ParseDuration(c.KeepAlive); err != nil {
+				return fmt.Errorf("keep_alive must be a duration like \"5m\" or a number of seconds: %q", c.KeepAlive)
+			}
+		}
+	}
+
+	return nil
+}
+
+// toMap returns the options as the JSON object sent to Ollama
+func (o OllamaOptions) toMap() map[string]interface{} {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil
+	}
+
+	var options map[string]interface{}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil
+	}
+	return options
+}
+
+// keepAliveValue returns keep_alive in the form Ollama expects: plain numbers
+// are sent as seconds, anything else as a duration string
+func (c OllamaConfig) keepAliveValue() interface{} {
+	if c.KeepAlive == "" {
+		return nil
+	}
+	if seconds, err := strconv.Atoi(c.KeepAlive); err == nil {
+		return seconds
+	}
+	return c.KeepAlive
+}
+
+// defaultOllamaPort is used when an OLLAMA_HOST value has no port
+const defaultOllamaPort = "11434"
+
+// NormalizeOllamaHost turns an OLLAMA_HOST value into a base URL the way the
+// Ollama CLI does: the scheme defaults to http, the port to 11434 and the
+// listen-anywhere address 0.0.0.0 is reached through localhost
+func NormalizeOllamaHost(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", fmt.Errorf("ollama host is empty")
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+
+	parsed, err := url.Parse(host)
+	if err != nil || parsed.Hostname() == "" {
+		return "", fmt.Errorf("invalid ollama host %q", host)
+	}
+
+	hostname := parsed.Hostname()
+	if hostname == "0.0.0.0" {
+		hostname = "localhost"
+	}
+	port := parsed.Port()
+	if port == "" {
+		switch parsed.Scheme {
+		case "https":
+			port = "443"
+		default:
+			port = defaultOllamaPort
+		}
+	}
+
+	return fmt.Sprintf("%s://%s%s", parsed.Scheme, net.JoinHostPort(hostname, port), strings.TrimSuffix(parsed.Path, "/")), nil
+}
@@ -0,0 +1,261 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaPullProgress represents a single progress update streamed by /api/pull
+type OllamaPullProgress struct {
+	Model     string `json:"model"`
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Done      bool   `json:"done"`
+	Error     string `json:"error,omitempty"`
+}
+
+// OllamaModelDetails holds the summary details Ollama reports for a model
+type OllamaModelDetails struct {
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families,omitempty"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// OllamaModelInfo represents the result of inspecting a model with /api/show
+type OllamaModelInfo struct {
+	Name       string                 `json:"name"`
+	Modelfile  string                 `json:"modelfile"`
+	Parameters string                 `json:"parameters"`
+	Template   string                 `json:"template"`
+	License    string                 `json:"license"`
+	Details    OllamaModelDetails     `json:"details"`
+	ModelInfo  map[string]interface{} `json:"model_info,omitempty"`
+	ModifiedAt time.Time              `json:"modified_at"`
+}
+
+// PullModel downloads a model into the local Ollama library, calling onProgress
+// for every status line Ollama streams back
+func (p *OllamaProvider) PullModel(ctx context.Context, model string, onProgress func(OllamaPullProgress)) error {
+	if model == "" {
+		return fmt.Errorf("model name cannot be empty")
+	}
+
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model":  model,
+		"stream": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	// No client timeout: large models can take a long time, the context controls cancellation
+	client := &http.Client{}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint(ctx)+"/api/pull", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create pull request for Ollama: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to pull Ollama model (is Ollama running?): %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var progress OllamaPullProgress
+		if err := json.Unmarshal(line, &progress); err != nil {
+			return fmt.Errorf("failed to parse Ollama pull progress: %w", err)
+		}
+		progress.Model = model
+		progress.Done = progress.Status == "success"
+
+		if onProgress != nil {
+			onProgress(progress)
+		}
+
+		if progress.Error != "" {
+			return fmt.Errorf("Ollama pull error: %s", progress.Error)
+		}
+		if progress.Done {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read Ollama pull progress: %w", err)
+	}
+
+	return fmt.Errorf("Ollama pull of '%s' ended before completing", model)
+}
+
+// DeleteModel removes a model from the local Ollama library
+func (p *OllamaProvider) DeleteModel(ctx context.Context, model string) error {
+	if model == "" {
+		return fmt.Errorf("model name cannot be empty")
+	}
+
+	_, err := p.doModelRequest(ctx, "DELETE", "/api/delete", map[string]interface{}{"model": model})
+	return err
+}
+
+// CopyModel creates a copy of a local model under a new name
+func (p *OllamaProvider) CopyModel(ctx context.Context, source, destination string) error {
+	if source == "" || destination == "" {
+		return fmt.Errorf("source and destination model names cannot be empty")
+	}
+
+	_, err := p.doModelRequest(ctx, "POST", "/api/copy", map[string]interface{}{
+		"source":      source,
+		"destination": destination,
+	})
+	return err
+}
+
+// ShowModel returns the parameters, template, license and details of a local model
+func (p *OllamaProvider) ShowModel(ctx context.Context, model string) (OllamaModelInfo, error) {
+	if model == "" {
+		return OllamaModelInfo{}, fmt.Errorf("model name cannot be empty")
+	}
+
+	body, err := p.doModelRequest(ctx, "POST", "/api/show", map[string]interface{}{"model": model})
+	if err != nil {
+		return OllamaModelInfo{}, err
+	}
+
+	var info OllamaModelInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return OllamaModelInfo{}, fmt.Errorf("failed to parse Ollama model info: %w", err)
+	}
+	info.Name = model
+
+	return info, nil
+}
+
+// FindMissingModels returns the subset of the given model names that are not installed locally
+func (p *OllamaProvider) FindMissingModels(ctx context.Context, names []string) ([]string, error) {
+	installed, err := p.FetchModels(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	available := make(map[string]bool, len(installed)*2)
+	for _, model := range installed {
+		available[model.ID] = true
+		// Ollama treats "llama3" and "llama3:latest" as the same model
+		if strings.HasSuffix(model.ID, ":latest") {
+			available[strings.TrimSuffix(model.ID, ":latest")] = true
+		}
+	}
+
+	missing := []string{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if !available[name] {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing, nil
+}
+
+// doModelRequest performs a short-lived model management request and returns the response body
+func (p *OllamaProvider) doModelRequest(ctx context.Context, method, path string, payload map[string]interface{}) ([]byte, error) {
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, method, p.endpoint(ctx)+path, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Ollama connection failed (is Ollama running?): %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Ollama response: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("Ollama API error (%d): %s", resp.StatusCode, apiErr.Error)
+		}
+		return nil, fmt.Errorf("Ollama API error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// ContextLength returns the context window a local model reports in its
+// model_info, or 0 if Ollama can't be reached or doesn't say. Lookups are
+// cached per model, since prompt budgeting asks before every completion.
+func (p *OllamaProvider) ContextLength(ctx context.Context, model string) int {
+	p.mu.RLock()
+	length, ok := p.contextLengths[model]
+	p.mu.RUnlock()
+	if ok {
+		return length
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	info, err := p.ShowModel(ctx, model)
+	if err != nil {
+		return 0
+	}
+	for key, value := range info.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if number, ok := value.(float64); ok && number > 0 {
+			length = int(number)
+			break
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.contextLengths == nil {
+		p.contextLengths = make(map[string]int)
+	}
+	p.contextLengths[model] = length
+	return length
+}
@@ -0,0 +1,37 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFindMissingModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"models": []map[string]string{{"name": "llama3:latest"}, {"name": "nomic-embed-text:v1.5"}},
+		})
+	}))
+	defer server.Close()
+
+	ollama := NewOllamaProvider()
+	if err := ollama.SetHost(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	// The story, chat and embedding models and a preset's model, as the frontend sends them
+	missing, err := ollama.FindMissingModels(context.Background(), []string{"llama3", "qwen2.5:7b", "nomic-embed-text:v1.5", "", "qwen2.5:7b", "mistral"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"qwen2.5:7b", "mistral"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing = %q, want %q", missing, want)
+	}
+}
@@ -85,8 +85,9 @@ func (p *OpenAIProvider) FetchModels(ctx context.Context, apiKey string) ([]Mode
 		// Only include GPT models that support chat completions
 		if strings.HasPrefix(model.ID, "gpt") && !strings.Contains(model.ID, "instruct") && !strings.Contains(model.ID, "vision") {
 			models = append(models, Model{
-				ID:   model.ID,
-				Name: model.ID, // Use ID as name as friendly names aren't always distinct or present
+				ID:            model.ID,
+				Name:          model.ID, // Use ID as name as friendly names aren't always distinct or present
+				ContextLength: KnownContextLength(model.ID),
 			})
 		}
 	}
@@ -101,12 +102,30 @@ func (p *OpenAIProvider) FetchModels(ctx context.Context, apiKey string) ([]Mode
 
 // GetCompletion performs text completion using OpenAI
 func (p *OpenAIProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, apiKey, nil)
+}
+
+// StreamCompletion performs text completion using OpenAI, calling onChunk as output arrives
+func (p *OpenAIProvider) StreamCompletion(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, apiKey, onChunk)
+}
+
+// complete sends a chat completion request, streaming the reply when onChunk is set
+func (p *OpenAIProvider) complete(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	options := CompletionOptionsFrom(ctx)
+
 	reqBody := map[string]interface{}{
 		"model": model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"max_completion_tokens": 1000,
+		"messages": options.chatMessages(model, prompt),
+		"max_completion_tokens": options.maxTokens(model),
+	}
+	if options.ReasoningEffort != "" && IsReasoningModel(model) {
+		reqBody["reasoning_effort"] = options.ReasoningEffort
+	}
+	options.applySampling(reqBody, model)
+	if onChunk != nil {
+		reqBody["stream"] = true
+		reqBody["stream_options"] = map[string]interface{}{"include_usage": true}
 	}
 	
 	jsonBody, err := json.Marshal(reqBody)
@@ -114,7 +133,7 @@ func (p *OpenAIProvider) GetCompletion(ctx context.Context, model, prompt, apiKe
 		return AICompletionResponse{Error: err.Error()}, err
 	}
 	
-	client := &http.Client{Timeout: 60 * time.Second}
+	client := clientFor(onChunk, 60*time.Second)
 	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
@@ -135,18 +154,8 @@ func (p *OpenAIProvider) GetCompletion(ctx context.Context, model, prompt, apiKe
 		return AICompletionResponse{Error: errMsg}, fmt.Errorf(errMsg)
 	}
 	
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Error struct {
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-	
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	result, err := decodeChatCompletion(resp.Body, onChunk)
+	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
 	}
 	
@@ -158,5 +167,36 @@ func (p *OpenAIProvider) GetCompletion(ctx context.Context, model, prompt, apiKe
 		return AICompletionResponse{Error: "No response from OpenAI"}, fmt.Errorf("no response from OpenAI")
 	}
 	
-	return AICompletionResponse{Content: result.Choices[0].Message.Content}, nil
-}
\ No newline at end of file
+	response := AICompletionResponse{
+		Content:      result.Choices[0].Message.Content,
+		Usage:        result.Usage.toTokenUsage(),
+		FinishReason: normalizeFinishReason(result.Choices[0].FinishReason),
+	}
+	return withReasoning(response, ""), nil
+}
+
+// openAIUsage is the usage block returned by OpenAI-compatible chat completion APIs
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// CompletionTokensDetails breaks down completion tokens for reasoning models
+	CompletionTokensDetails *struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+func (u *openAIUsage) toTokenUsage() *TokenUsage {
+	if u == nil {
+		return nil
+	}
+	usage := &TokenUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if u.CompletionTokensDetails != nil {
+		usage.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+	return usage
+}
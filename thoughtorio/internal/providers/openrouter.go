@@ -67,8 +67,13 @@ func (p *OpenRouterProvider) FetchModels(ctx context.Context, apiKey string) ([]
 
 	var result struct {
 		Data []struct {
-			ID   string `json:"id"`
-			Name string `json:"name"`
+			ID            string `json:"id"`
+			Name          string `json:"name"`
+			ContextLength int    `json:"context_length"`
+			Pricing       *struct {
+				Prompt     string `json:"prompt"`
+				Completion string `json:"completion"`
+			} `json:"pricing"`
 		} `json:"data"`
 	}
 
@@ -79,8 +84,15 @@ func (p *OpenRouterProvider) FetchModels(ctx context.Context, apiKey string) ([]
 	models := make([]Model, len(result.Data))
 	for i, model := range result.Data {
 		models[i] = Model{
-			ID:   model.ID,
-			Name: model.Name,
+			ID:            model.ID,
+			Name:          model.Name,
+			ContextLength: model.ContextLength,
+		}
+		if model.Pricing != nil {
+			models[i].Pricing = &ModelPricing{
+				PromptPerMillion:     perTokenToPerMillion(model.Pricing.Prompt),
+				CompletionPerMillion: perTokenToPerMillion(model.Pricing.Completion),
+			}
 		}
 	}
 
@@ -89,12 +101,40 @@ func (p *OpenRouterProvider) FetchModels(ctx context.Context, apiKey string) ([]
 
 // GetCompletion performs text completion using OpenRouter
 func (p *OpenRouterProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, apiKey, nil)
+}
+
+// StreamCompletion performs text completion using OpenRouter, calling onChunk as output arrives
+func (p *OpenRouterProvider) StreamCompletion(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	return p.complete(ctx, model, prompt, apiKey, onChunk)
+}
+
+// complete sends a chat completion request, streaming the reply when onChunk is
+// set; OpenRouter reports usage in the final event of a stream
+func (p *OpenRouterProvider) complete(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	options := CompletionOptionsFrom(ctx)
+
 	reqBody := map[string]interface{}{
 		"model": model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"max_tokens": 1000,
+		"messages": options.chatMessages(model, prompt),
+		"max_tokens": options.maxTokens(model),
+	}
+	// OpenRouter normalizes reasoning controls across upstream providers and
+	// returns the thinking in message.reasoning
+	if options.ThinkingBudget > 0 {
+		reqBody["reasoning"] = map[string]interface{}{"max_tokens": options.ThinkingBudget}
+	} else if options.ReasoningEffort != "" {
+		reqBody["reasoning"] = map[string]interface{}{"effort": options.ReasoningEffort}
+	}
+	// OpenRouter drops parameters the upstream model doesn't support
+	if options.Temperature != nil {
+		reqBody["temperature"] = *options.Temperature
+	}
+	if options.TopP != nil {
+		reqBody["top_p"] = *options.TopP
+	}
+	if onChunk != nil {
+		reqBody["stream"] = true
 	}
 	
 	jsonBody, err := json.Marshal(reqBody)
@@ -102,7 +142,7 @@ func (p *OpenRouterProvider) GetCompletion(ctx context.Context, model, prompt, a
 		return AICompletionResponse{Error: err.Error()}, err
 	}
 	
-	client := &http.Client{Timeout: 60 * time.Second}
+	client := clientFor(onChunk, 60*time.Second)
 	req, err := http.NewRequestWithContext(ctx, "POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
@@ -125,18 +165,8 @@ func (p *OpenRouterProvider) GetCompletion(ctx context.Context, model, prompt, a
 		return AICompletionResponse{Error: errMsg}, fmt.Errorf(errMsg)
 	}
 	
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Error struct {
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-	
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	result, err := decodeChatCompletion(resp.Body, onChunk)
+	if err != nil {
 		return AICompletionResponse{Error: err.Error()}, err
 	}
 	
@@ -148,5 +178,11 @@ func (p *OpenRouterProvider) GetCompletion(ctx context.Context, model, prompt, a
 		return AICompletionResponse{Error: "No response from OpenRouter"}, fmt.Errorf("no response from OpenRouter")
 	}
 	
-	return AICompletionResponse{Content: result.Choices[0].Message.Content}, nil
+	message := result.Choices[0].Message
+	response := AICompletionResponse{
+		Content:      message.Content,
+		Usage:        result.Usage.toTokenUsage(),
+		FinishReason: normalizeFinishReason(result.Choices[0].FinishReason),
+	}
+	return withReasoning(response, message.Reasoning), nil
 }
\ No newline at end of file
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CompletionOptions are per-request settings that providers apply when supported
+type CompletionOptions struct {
+	// SystemPrompt is sent as the system message, or the provider's equivalent,
+	// ahead of the prompt
+	SystemPrompt string `json:"systemPrompt,omitempty"`
+	// ReasoningEffort asks reasoning models to think less or more: low, medium or high
+	ReasoningEffort string `json:"reasoningEffort,omitempty"`
+	// ThinkingBudget caps the tokens a reasoning model may spend thinking; it
+	// takes precedence over ReasoningEffort for providers that accept a budget
+	ThinkingBudget int `json:"thinkingBudget,omitempty"`
+	// MaxTokens overrides the provider's default output token limit
+	MaxTokens int `json:"maxTokens,omitempty"`
+	// Temperature and TopP override the provider's sampling defaults; reasoning
+	// models that reject sampling parameters ignore them
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"topP,omitempty"`
+	// AutoContinue issues follow-up requests when a reply stops on the output limit
+	AutoContinue bool `json:"autoContinue,omitempty"`
+	// ContinueBudget caps the completion tokens spent across the original reply
+	// and its continuations; zero uses the default
+	ContinueBudget int `json:"continueBudget,omitempty"`
+	// MaxContinuations caps the number of follow-up requests; zero uses the default
+	MaxContinuations int `json:"maxContinuations,omitempty"`
+}
+
+// Validate checks the option values
+func (o CompletionOptions) Validate() error {
+	switch o.ReasoningEffort {
+	case "", ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh:
+	default:
+		return fmt.Errorf("reasoning effort must be low, medium or high, got %q", o.ReasoningEffort)
+	}
+	if o.ThinkingBudget < 0 {
+		return fmt.Errorf("thinking budget cannot be negative")
+	}
+	if o.MaxTokens < 0 {
+		return fmt.Errorf("max tokens cannot be negative")
+	}
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if o.TopP != nil && (*o.TopP <= 0 || *o.TopP > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1")
+	}
+	if o.ContinueBudget < 0 || o.MaxContinuations < 0 {
+		return fmt.Errorf("auto-continue limits cannot be negative")
+	}
+	return nil
+}
+
+type completionOptionsKey struct{}
+
+// WithCompletionOptions attaches per-request options to ctx. The ProviderManager
+// does this for every CompletionRequest, so providers can read them without the
+// AIProvider interface changing.
+func WithCompletionOptions(ctx context.Context, options CompletionOptions) context.Context {
+	return context.WithValue(ctx, completionOptionsKey{}, options)
+}
+
+// CompletionOptionsFrom returns the options attached to ctx, or zero options
+func CompletionOptionsFrom(ctx context.Context) CompletionOptions {
+	options, _ := ctx.Value(completionOptionsKey{}).(CompletionOptions)
+	return options
+}
+
+// chatMessages builds an OpenAI-style message list with the system prompt, if
+// any, ahead of the user prompt. o1-mini and o1-preview reject system messages,
+// so for them the system prompt leads the user message instead.
+func (o CompletionOptions) chatMessages(model, prompt string) []map[string]string {
+	if o.SystemPrompt == "" {
+		return []map[string]string{{"role": "user", "content": prompt}}
+	}
+
+	id := model
+	if slash := strings.LastIndex(id, "/"); slash >= 0 {
+		id = id[slash+1:]
+	}
+	if strings.HasPrefix(id, "o1-mini") || strings.HasPrefix(id, "o1-preview") {
+		return []map[string]string{{"role": "user", "content": o.SystemPrompt + "\n\n" + prompt}}
+	}
+
+	return []map[string]string{
+		{"role": "system", "content": o.SystemPrompt},
+		{"role": "user", "content": prompt},
+	}
+}
+
+// applySampling adds temperature and top_p to an OpenAI-style request body.
+// OpenAI reasoning models reject them, so they are left out for those models.
+func (o CompletionOptions) applySampling(body map[string]interface{}, model string) {
+	if IsReasoningModel(model) {
+		return
+	}
+	if o.Temperature != nil {
+		body["temperature"] = *o.Temperature
+	}
+	if o.TopP != nil {
+		body["top_p"] = *o.TopP
+	}
+}
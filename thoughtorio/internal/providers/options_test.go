@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestChatMessages(t *testing.T) {
+	user := map[string]string{"role": "user", "content": "Hi"}
+
+	tests := []struct {
+		name    string
+		options CompletionOptions
+		model   string
+		want    []map[string]string
+	}{
+		{"no system prompt", CompletionOptions{}, "gpt-4o", []map[string]string{user}},
+		{"system message", CompletionOptions{SystemPrompt: "Be brief."}, "gpt-4o", []map[string]string{
+			{"role": "system", "content": "Be brief."}, user,
+		}},
+		{"o1-mini folds it into the prompt", CompletionOptions{SystemPrompt: "Be brief."}, "openai/o1-mini", []map[string]string{
+			{"role": "user", "content": "Be brief.\n\nHi"},
+		}},
+	}
+
+	for _, test := range tests {
+		if got := test.options.chatMessages(test.model, "Hi"); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v", test.name, got)
+		}
+	}
+}
+
+func TestOllamaSendsSystemMessage(t *testing.T) {
+	var body struct {
+		Messages []map[string]string `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"message":{"content":"ok"},"done":true,"done_reason":"stop"}`))
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider()
+	if err := provider.SetHost(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithCompletionOptions(context.Background(), CompletionOptions{SystemPrompt: "You are terse."})
+	if _, err := provider.GetCompletion(ctx, "llama3", "Hi", ""); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{
+		{"role": "system", "content": "You are terse."},
+		{"role": "user", "content": "Hi"},
+	}
+	if !reflect.DeepEqual(body.Messages, want) {
+		t.Errorf("sent %v", body.Messages)
+	}
+}
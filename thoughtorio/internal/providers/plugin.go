@@ -0,0 +1,538 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Plugin providers are executables in the plugin directory that speak JSON-RPC 2.0
+// over stdio, one JSON message per line. The app calls these methods:
+//
+//	name            -> {"name": "acme-gateway", "requiresApiKey": true}
+//	listModels      {"apiKey"}                                 -> [{"id", "name", "contextLength"}]
+//	complete        {"model", "prompt", "apiKey", "options"}   -> {"content", "reasoning", "finishReason", "error"}
+//	stream          {"model", "prompt", "apiKey", "options"}   -> {"content", "reasoning", "finishReason", "error"}
+//	validateConfig  {"config"}                                 -> null, or a JSON-RPC error
+//
+// "options" holds the request's CompletionOptions (systemPrompt, reasoningEffort,
+// thinkingBudget, maxTokens, temperature, topP); plugins may ignore fields they
+// don't support.
+//
+// While handling "stream" a plugin sends partial output as notifications:
+//
+//	{"jsonrpc": "2.0", "method": "stream.chunk", "params": {"id": <request id>, "content": "..."}}
+//
+// Anything the plugin writes to stderr is copied to the app log. A plugin that
+// exits is restarted with exponential backoff, and one that misses a call's
+// deadline is killed so that it restarts too.
+
+const (
+	pluginStartTimeout = 10 * time.Second
+	pluginCallTimeout  = 30 * time.Second
+	// pluginCompletionTimeout matches the longest built-in provider timeout
+	pluginCompletionTimeout = 120 * time.Second
+
+	pluginMinBackoff = 1 * time.Second
+	pluginMaxBackoff = 30 * time.Second
+	// pluginStableAfter resets the restart backoff once a process has stayed up this long
+	pluginStableAfter = time.Minute
+)
+
+// DefaultPluginDir returns the directory provider plugins are discovered in
+func DefaultPluginDir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "thoughtorio", "providers")
+}
+
+// DiscoverPlugins starts every executable in dir and returns the plugins that
+// answered the name handshake, along with errors for the ones that didn't
+func DiscoverPlugins(dir string) ([]*PluginProvider, []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{fmt.Errorf("failed to read plugin directory: %w", err)}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var plugins []*PluginProvider
+	var errs []error
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !isExecutable(entry) {
+			continue
+		}
+
+		plugin, err := StartPluginProvider(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", entry.Name(), err))
+			continue
+		}
+		plugins = append(plugins, plugin)
+	}
+
+	return plugins, errs
+}
+
+// isExecutable reports whether a directory entry looks like a plugin executable
+func isExecutable(entry os.DirEntry) bool {
+	if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(entry.Name()), ".exe")
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return false
+	}
+	return info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
+
+// PluginProvider implements the AIProvider interface by delegating to a supervised plugin process
+type PluginProvider struct {
+	path           string
+	name           string
+	requiresAPIKey bool
+
+	proc      *pluginProcess
+	restarts  int
+	startedAt time.Time
+	closed    bool
+	mu        sync.Mutex
+	startMu   sync.Mutex
+}
+
+// StartPluginProvider launches a plugin executable and performs the name handshake
+func StartPluginProvider(path string) (*PluginProvider, error) {
+	p := &PluginProvider{path: path}
+
+	if _, err := p.ensureRunning(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginStartTimeout)
+	defer cancel()
+
+	var info struct {
+		Name           string `json:"name"`
+		RequiresAPIKey bool   `json:"requiresApiKey"`
+	}
+	if err := p.call(ctx, "name", nil, &info, nil); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("name handshake failed: %w", err)
+	}
+	if strings.TrimSpace(info.Name) == "" {
+		p.Close()
+		return nil, fmt.Errorf("plugin returned an empty name")
+	}
+
+	p.name = info.Name
+	p.requiresAPIKey = info.RequiresAPIKey
+	return p, nil
+}
+
+// GetName returns the name the plugin reported during the handshake
+func (p *PluginProvider) GetName() string {
+	return p.name
+}
+
+// RequiresAPIKey returns whether the plugin reported that it needs an API key
+func (p *PluginProvider) RequiresAPIKey() bool {
+	return p.requiresAPIKey
+}
+
+// Path returns the plugin executable path
+func (p *PluginProvider) Path() string {
+	return p.path
+}
+
+// ValidateConfig asks the plugin to validate its configuration
+func (p *PluginProvider) ValidateConfig(config map[string]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginCallTimeout)
+	defer cancel()
+
+	return p.call(ctx, "validateConfig", map[string]interface{}{"config": config}, nil, nil)
+}
+
+// FetchModels retrieves the models the plugin offers
+func (p *PluginProvider) FetchModels(ctx context.Context, apiKey string) ([]Model, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginCallTimeout)
+	defer cancel()
+
+	var models []Model
+	if err := p.call(ctx, "listModels", map[string]interface{}{"apiKey": apiKey}, &models, nil); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// GetCompletion performs text completion through the plugin
+func (p *PluginProvider) GetCompletion(ctx context.Context, model, prompt, apiKey string) (AICompletionResponse, error) {
+	return p.complete(ctx, "complete", model, prompt, apiKey, nil)
+}
+
+// StreamCompletion performs text completion through the plugin, forwarding partial output to onChunk
+func (p *PluginProvider) StreamCompletion(ctx context.Context, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	return p.complete(ctx, "stream", model, prompt, apiKey, onChunk)
+}
+
+func (p *PluginProvider) complete(ctx context.Context, method, model, prompt, apiKey string, onChunk func(string)) (AICompletionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginCompletionTimeout)
+	defer cancel()
+
+	params := map[string]interface{}{
+		"model":   model,
+		"prompt":  prompt,
+		"apiKey":  apiKey,
+		"options": CompletionOptionsFrom(ctx),
+	}
+
+	var result AICompletionResponse
+	if err := p.call(ctx, method, params, &result, onChunk); err != nil {
+		return AICompletionResponse{Error: err.Error()}, err
+	}
+	result.FinishReason = normalizeFinishReason(result.FinishReason)
+	if result.Error != "" {
+		return result, fmt.Errorf("%s error: %s", p.name, result.Error)
+	}
+	return result, nil
+}
+
+// Close stops the plugin process and disables restarts
+func (p *PluginProvider) Close() {
+	p.mu.Lock()
+	p.closed = true
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+
+	if proc != nil {
+		proc.kill()
+	}
+}
+
+// ensureRunning returns the current process, launching a new one if it is down.
+// startMu serializes launches so a crash restart and a caller can't both start one.
+func (p *PluginProvider) ensureRunning() (*pluginProcess, error) {
+	p.startMu.Lock()
+	defer p.startMu.Unlock()
+
+	p.mu.Lock()
+	proc, closed := p.proc, p.closed
+	p.mu.Unlock()
+
+	if closed {
+		return nil, fmt.Errorf("plugin %s is closed", filepath.Base(p.path))
+	}
+	if proc != nil {
+		return proc, nil
+	}
+
+	proc, err := launchPlugin(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		proc.kill()
+		return nil, fmt.Errorf("plugin %s is closed", filepath.Base(p.path))
+	}
+	p.proc = proc
+	p.startedAt = time.Now()
+	p.mu.Unlock()
+
+	go p.supervise(proc)
+	return proc, nil
+}
+
+// supervise waits for the process to exit and schedules a restart with exponential backoff
+func (p *PluginProvider) supervise(proc *pluginProcess) {
+	err := proc.wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proc == proc {
+		p.proc = nil
+	}
+	if p.closed {
+		return
+	}
+
+	if time.Since(p.startedAt) > pluginStableAfter {
+		p.restarts = 0
+	}
+	backoff := pluginBackoff(p.restarts)
+	p.restarts++
+
+	log.Printf("provider plugin %s exited (%v), restarting in %s", filepath.Base(p.path), err, backoff)
+	time.AfterFunc(backoff, func() {
+		if _, err := p.ensureRunning(); err != nil {
+			log.Printf("provider plugin %s failed to restart: %v", filepath.Base(p.path), err)
+		}
+	})
+}
+
+// pluginBackoff returns the wait before a restart that follows the given
+// number of restarts in a row, doubling from pluginMinBackoff up to pluginMaxBackoff
+func pluginBackoff(restarts int) time.Duration {
+	backoff := pluginMinBackoff
+	for i := 0; i < restarts && backoff < pluginMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > pluginMaxBackoff {
+		backoff = pluginMaxBackoff
+	}
+	return backoff
+}
+
+// call sends a request to the current process, starting it if it is down
+func (p *PluginProvider) call(ctx context.Context, method string, params interface{}, result interface{}, onChunk func(string)) error {
+	proc, err := p.ensureRunning()
+	if err != nil {
+		return fmt.Errorf("plugin %s is not running: %w", filepath.Base(p.path), err)
+	}
+
+	return proc.call(ctx, method, params, result, onChunk)
+}
+
+// rpcMessage covers requests, responses and notifications in either direction
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcCall struct {
+	done    chan rpcMessage
+	onChunk func(string)
+}
+
+// pluginProcess is a single running instance of a plugin executable
+type pluginProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	name   string
+	nextID int64
+	calls  map[int64]*rpcCall
+	exited chan struct{}
+	err    error
+	mu     sync.Mutex
+	// readers tracks the stdout and stderr goroutines, which must finish
+	// reading before cmd.Wait closes the pipes
+	readers sync.WaitGroup
+	// writeMu serializes messages written to stdin
+	writeMu sync.Mutex
+}
+
+func launchPlugin(path string) (*pluginProcess, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start: %w", err)
+	}
+
+	proc := &pluginProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		name:   filepath.Base(path),
+		calls:  make(map[int64]*rpcCall),
+		exited: make(chan struct{}),
+	}
+
+	proc.readers.Add(2)
+	go func() {
+		defer proc.readers.Done()
+		proc.readMessages(stdout)
+	}()
+	go func() {
+		defer proc.readers.Done()
+		proc.copyStderr(stderr)
+	}()
+
+	return proc, nil
+}
+
+// readMessages dispatches responses and stream notifications until stdout closes
+func (pp *pluginProcess) readMessages(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Printf("provider plugin %s sent invalid JSON: %v", pp.name, err)
+			continue
+		}
+
+		if msg.ID == nil && msg.Method == "stream.chunk" {
+			var chunk struct {
+				ID      int64  `json:"id"`
+				Content string `json:"content"`
+			}
+			if err := json.Unmarshal(msg.Params, &chunk); err != nil {
+				continue
+			}
+			pp.mu.Lock()
+			call := pp.calls[chunk.ID]
+			pp.mu.Unlock()
+			if call != nil && call.onChunk != nil {
+				call.onChunk(chunk.Content)
+			}
+			continue
+		}
+
+		if msg.ID == nil {
+			continue
+		}
+
+		pp.mu.Lock()
+		call := pp.calls[*msg.ID]
+		delete(pp.calls, *msg.ID)
+		pp.mu.Unlock()
+		if call != nil {
+			call.done <- msg
+		}
+	}
+}
+
+func (pp *pluginProcess) copyStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("[plugin %s] %s", pp.name, scanner.Text())
+	}
+}
+
+// wait blocks until the process exits and fails all outstanding calls
+func (pp *pluginProcess) wait() error {
+	// Wait closes stdout and stderr, so the readers get everything the plugin wrote first
+	pp.readers.Wait()
+	err := pp.cmd.Wait()
+
+	pp.mu.Lock()
+	pp.err = err
+	calls := pp.calls
+	pp.calls = make(map[int64]*rpcCall)
+	pp.mu.Unlock()
+
+	close(pp.exited)
+	for _, call := range calls {
+		call.done <- rpcMessage{Error: &rpcError{Code: -32000, Message: "plugin process exited"}}
+	}
+
+	return err
+}
+
+func (pp *pluginProcess) kill() {
+	pp.stdin.Close()
+	if pp.cmd.Process != nil {
+		pp.cmd.Process.Kill()
+	}
+}
+
+// call writes a request and waits for its response, the context deadline or process exit
+func (pp *pluginProcess) call(ctx context.Context, method string, params interface{}, result interface{}, onChunk func(string)) error {
+	pp.mu.Lock()
+	pp.nextID++
+	id := pp.nextID
+	call := &rpcCall{done: make(chan rpcMessage, 1), onChunk: onChunk}
+	pp.calls[id] = call
+	pp.mu.Unlock()
+
+	forget := func() {
+		pp.mu.Lock()
+		delete(pp.calls, id)
+		pp.mu.Unlock()
+	}
+
+	request := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+	}
+	if params != nil {
+		request["params"] = params
+	}
+	line, err := json.Marshal(request)
+	if err != nil {
+		forget()
+		return err
+	}
+
+	pp.writeMu.Lock()
+	_, err = pp.stdin.Write(append(line, '\n'))
+	pp.writeMu.Unlock()
+	if err != nil {
+		forget()
+		return fmt.Errorf("failed to write to plugin: %w", err)
+	}
+
+	select {
+	case msg := <-call.done:
+		if msg.Error != nil {
+			return fmt.Errorf("%s", msg.Error.Message)
+		}
+		if result != nil && len(msg.Result) > 0 && string(msg.Result) != "null" {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("invalid %s result from plugin: %w", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		forget()
+		if ctx.Err() == context.DeadlineExceeded {
+			// A plugin that misses a deadline may be hung and would time out
+			// every later call too, so it is killed and supervise restarts it
+			log.Printf("provider plugin %s timed out on %s, restarting it", pp.name, method)
+			pp.kill()
+			return fmt.Errorf("plugin %s timed out", method)
+		}
+		return ctx.Err()
+	case <-pp.exited:
+		forget()
+		return fmt.Errorf("plugin process exited")
+	}
+}
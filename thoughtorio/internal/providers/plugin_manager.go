@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"fmt"
+	"log"
+)
+
+// LoadPlugins discovers provider plugins in dir and registers each one as a normal
+// AIProvider. Plugins loaded earlier are stopped first, so this also reloads them.
+// Plugins may not replace built-in providers.
+func (pm *ProviderManager) LoadPlugins(dir string) []error {
+	pm.closePlugins()
+
+	if dir == "" {
+		return nil
+	}
+
+	plugins, errs := DiscoverPlugins(dir)
+	for _, plugin := range plugins {
+		name := plugin.GetName()
+
+		pm.mu.Lock()
+		closed := pm.closed
+		_, exists := pm.providers[name]
+		if !exists && !closed {
+			pm.providers[name] = plugin
+			pm.plugins[name] = plugin
+		}
+		pm.mu.Unlock()
+
+		if closed {
+			plugin.Close()
+			continue
+		}
+		if exists {
+			plugin.Close()
+			errs = append(errs, fmt.Errorf("plugin %s: provider name '%s' is already registered", plugin.Path(), name))
+			continue
+		}
+
+		log.Printf("registered provider plugin '%s' from %s", name, plugin.Path())
+	}
+
+	return errs
+}
+
+// Close stops all plugin processes; plugins found by a discovery still in
+// progress are stopped instead of registered
+func (pm *ProviderManager) Close() {
+	pm.mu.Lock()
+	pm.closed = true
+	pm.mu.Unlock()
+	pm.closePlugins()
+}
+
+// closePlugins stops and unregisters every loaded plugin
+func (pm *ProviderManager) closePlugins() {
+	pm.mu.Lock()
+	plugins := pm.plugins
+	pm.plugins = make(map[string]*PluginProvider)
+	for name := range plugins {
+		delete(pm.providers, name)
+	}
+	pm.mu.Unlock()
+
+	for _, plugin := range plugins {
+		plugin.Close()
+	}
+}
@@ -0,0 +1,260 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helperPluginEnv makes the test binary act as a provider plugin when set,
+// so tests can launch it as one
+const helperPluginEnv = "THOUGHTORIO_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if name := os.Getenv(helperPluginEnv); name != "" {
+		runHelperPlugin(name)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runHelperPlugin answers plugin requests on stdin. listModels reports the
+// process ID as its one model, and completions of "crash" and "hang" do just that.
+func runHelperPlugin(name string) {
+	out := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request struct {
+			ID     int64  `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Prompt string `json:"prompt"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			continue
+		}
+
+		var result interface{}
+		switch request.Method {
+		case "name":
+			result = map[string]interface{}{"name": name, "requiresApiKey": false}
+		case "listModels":
+			result = []Model{{ID: strconv.Itoa(os.Getpid())}}
+		case "complete", "stream":
+			switch request.Params.Prompt {
+			case "crash":
+				os.Exit(1)
+			case "hang":
+				continue
+			}
+			if request.Method == "stream" {
+				for _, word := range strings.Fields(request.Params.Prompt) {
+					out.Encode(map[string]interface{}{
+						"jsonrpc": "2.0",
+						"method":  "stream.chunk",
+						"params":  map[string]interface{}{"id": request.ID, "content": word},
+					})
+				}
+			}
+			result = map[string]interface{}{"content": "echo: " + request.Params.Prompt, "finishReason": "stop"}
+		}
+		out.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}
+}
+
+// startHelperPlugin launches the test binary as a plugin named "helper"
+func startHelperPlugin(t *testing.T) *PluginProvider {
+	t.Helper()
+	t.Setenv(helperPluginEnv, "helper")
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin, err := StartPluginProvider(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(plugin.Close)
+	return plugin
+}
+
+// pluginPID returns the process ID the helper plugin reports
+func pluginPID(t *testing.T, plugin *PluginProvider) string {
+	t.Helper()
+	models, err := plugin.FetchModels(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models[0].ID
+}
+
+// waitForRestart waits until the helper plugin answers from a process other than pid
+func waitForRestart(t *testing.T, plugin *PluginProvider, pid string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		models, err := plugin.FetchModels(context.Background(), "")
+		if err == nil && models[0].ID != pid {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("plugin was not restarted")
+}
+
+func TestPluginCompletions(t *testing.T) {
+	plugin := startHelperPlugin(t)
+	if plugin.GetName() != "helper" || plugin.RequiresAPIKey() {
+		t.Fatalf("handshake gave %q requiring a key %v", plugin.GetName(), plugin.RequiresAPIKey())
+	}
+
+	result, err := plugin.GetCompletion(context.Background(), "model", "hello there", "")
+	if err != nil || result.Content != "echo: hello there" || result.FinishReason != FinishReasonStop {
+		t.Errorf("GetCompletion = %+v, %v", result, err)
+	}
+
+	var chunks []string
+	result, err = plugin.StreamCompletion(context.Background(), "model", "hello there", "", collect(&chunks))
+	if err != nil || result.Content != "echo: hello there" {
+		t.Errorf("StreamCompletion = %+v, %v", result, err)
+	}
+	if strings.Join(chunks, "|") != "hello|there" {
+		t.Errorf("chunks = %q, want the words streamed", chunks)
+	}
+}
+
+func TestPluginRestartsAfterCrash(t *testing.T) {
+	plugin := startHelperPlugin(t)
+	pid := pluginPID(t, plugin)
+
+	if _, err := plugin.GetCompletion(context.Background(), "model", "crash", ""); err == nil {
+		t.Fatal("GetCompletion succeeded though the plugin exited")
+	}
+	waitForRestart(t, plugin, pid)
+
+	plugin.mu.Lock()
+	restarts := plugin.restarts
+	plugin.mu.Unlock()
+	if restarts != 1 {
+		t.Errorf("restarts = %d, want 1", restarts)
+	}
+	if result, err := plugin.GetCompletion(context.Background(), "model", "again", ""); err != nil || result.Content != "echo: again" {
+		t.Errorf("GetCompletion after the restart = %+v, %v", result, err)
+	}
+}
+
+func TestPluginRestartsAfterTimeout(t *testing.T) {
+	plugin := startHelperPlugin(t)
+	pid := pluginPID(t, plugin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := plugin.GetCompletion(ctx, "model", "hang", "")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("GetCompletion of a hung call = %v, want a timeout", err)
+	}
+	waitForRestart(t, plugin, pid)
+
+	// A call the caller cancels leaves the process running
+	pid = pluginPID(t, plugin)
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := plugin.GetCompletion(ctx, "model", "hang", ""); err != context.Canceled {
+		t.Fatalf("GetCompletion of a cancelled call = %v, want context.Canceled", err)
+	}
+	if again := pluginPID(t, plugin); again != pid {
+		t.Errorf("process %s replaced by %s after a cancelled call", pid, again)
+	}
+}
+
+func TestPluginBackoff(t *testing.T) {
+	tests := []struct {
+		restarts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{4, 16 * time.Second},
+		{5, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+	for _, test := range tests {
+		if got := pluginBackoff(test.restarts); got != test.want {
+			t.Errorf("pluginBackoff(%d) = %s, want %s", test.restarts, got, test.want)
+		}
+	}
+}
+
+func TestDiscoverPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by extension on Windows")
+	}
+	t.Setenv(helperPluginEnv, "helper")
+	dir := t.TempDir()
+
+	// A copy of the test binary is a working plugin
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyFile(t, executable, filepath.Join(dir, "b-helper"), 0755)
+	// An executable that can't run is reported
+	if err := os.WriteFile(filepath.Join(dir, "a-broken"), []byte("not a program"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Files that aren't executable, hidden files and directories are skipped
+	for _, name := range []string{"notes.txt", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	copyFile(t, executable, filepath.Join(dir, ".hidden-helper"), 0755)
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins, errs := DiscoverPlugins(dir)
+	for _, plugin := range plugins {
+		defer plugin.Close()
+	}
+	if len(plugins) != 1 || plugins[0].GetName() != "helper" || plugins[0].Path() != filepath.Join(dir, "b-helper") {
+		t.Errorf("plugins = %+v, want the helper alone", plugins)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "a-broken") {
+		t.Errorf("errors = %v, want one for a-broken", errs)
+	}
+
+	if plugins, errs := DiscoverPlugins(filepath.Join(dir, "missing")); plugins != nil || errs != nil {
+		t.Errorf("DiscoverPlugins of a missing directory = %v, %v, want nothing", plugins, errs)
+	}
+}
+
+func copyFile(t *testing.T, from, to string, perm os.FileMode) {
+	t.Helper()
+	in, err := os.Open(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The copy is closed before it runs, which would otherwise fail as busy
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ModelPricing is what a model costs in US dollars per million tokens
+type ModelPricing struct {
+	PromptPerMillion     float64 `json:"promptPerMillion"`
+	CompletionPerMillion float64 `json:"completionPerMillion"`
+}
+
+// Cost returns the price of a completion with the given token counts
+func (p ModelPricing) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.PromptPerMillion + float64(completionTokens)*p.CompletionPerMillion) / 1e6
+}
+
+// knownPricing lists list prices for models whose APIs don't report them.
+// Entries are matched by prefix, so more specific prefixes must come first.
+var knownPricing = []struct {
+	prefix  string
+	pricing ModelPricing
+}{
+	{"gpt-5-nano", ModelPricing{0.05, 0.40}},
+	{"gpt-5-mini", ModelPricing{0.25, 2.00}},
+	{"gpt-5", ModelPricing{1.25, 10.00}},
+	{"gpt-4.1-nano", ModelPricing{0.10, 0.40}},
+	{"gpt-4.1-mini", ModelPricing{0.40, 1.60}},
+	{"gpt-4.1", ModelPricing{2.00, 8.00}},
+	{"gpt-4o-mini", ModelPricing{0.15, 0.60}},
+	{"gpt-4o", ModelPricing{2.50, 10.00}},
+	{"gpt-4-turbo", ModelPricing{10.00, 30.00}},
+	{"gpt-4", ModelPricing{30.00, 60.00}},
+	{"gpt-3.5-turbo", ModelPricing{0.50, 1.50}},
+	{"o1-mini", ModelPricing{1.10, 4.40}},
+	{"o1", ModelPricing{15.00, 60.00}},
+	{"o3-mini", ModelPricing{1.10, 4.40}},
+	{"o3", ModelPricing{2.00, 8.00}},
+	{"o4-mini", ModelPricing{1.10, 4.40}},
+	{"gemini-2.5-flash-lite", ModelPricing{0.10, 0.40}},
+	{"gemini-2.5-flash", ModelPricing{0.30, 2.50}},
+	{"gemini-2.5-pro", ModelPricing{1.25, 10.00}},
+	{"gemini-2.0-flash-lite", ModelPricing{0.075, 0.30}},
+	{"gemini-2.0-flash", ModelPricing{0.10, 0.40}},
+	{"gemini-1.5-flash", ModelPricing{0.075, 0.30}},
+	{"gemini-1.5-pro", ModelPricing{1.25, 5.00}},
+}
+
+// KnownPricing returns the list price for a well-known model ID. Prefixes such
+// as Gemini's "models/" and OpenRouter's vendor ("openai/gpt-4o") are ignored.
+func KnownPricing(modelID string) (ModelPricing, bool) {
+	id := strings.ToLower(modelID)
+	if slash := strings.LastIndex(id, "/"); slash >= 0 {
+		id = id[slash+1:]
+	}
+	for _, known := range knownPricing {
+		if strings.HasPrefix(id, known.prefix) {
+			return known.pricing, true
+		}
+	}
+	return ModelPricing{}, false
+}
+
+// ModelPricing returns the price of a model. Local models are free; otherwise
+// prices reported by the provider's model listing win over the known table.
+func (pm *ProviderManager) ModelPricing(providerName, model string) (ModelPricing, bool) {
+	if providerName == "local" {
+		return ModelPricing{}, true
+	}
+
+	pm.mu.RLock()
+	cached, ok := pm.modelCache[providerName][model]
+	pm.mu.RUnlock()
+	if ok && cached.Pricing != nil {
+		return *cached.Pricing, true
+	}
+
+	return KnownPricing(model)
+}
+
+// perTokenToPerMillion converts a per-token USD price string, as OpenRouter reports it
+func perTokenToPerMillion(price string) float64 {
+	value, err := strconv.ParseFloat(price, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value * 1e6
+}
@@ -0,0 +1,27 @@
+package providers
+
+import "testing"
+
+func TestKnownPricing(t *testing.T) {
+	tests := []struct {
+		model string
+		want  ModelPricing
+		known bool
+	}{
+		{"gpt-4o", ModelPricing{2.50, 10.00}, true},
+		{"gpt-4o-mini-2024-07-18", ModelPricing{0.15, 0.60}, true},
+		{"openai/gpt-4o", ModelPricing{2.50, 10.00}, true},
+		{"OpenAI/GPT-4o-mini", ModelPricing{0.15, 0.60}, true},
+		{"models/gemini-1.5-pro", ModelPricing{1.25, 5.00}, true},
+		{"google/gemini-2.5-flash", ModelPricing{0.30, 2.50}, true},
+		{"anthropic/claude-3.5-sonnet", ModelPricing{}, false},
+		{"llama3", ModelPricing{}, false},
+	}
+
+	for _, tt := range tests {
+		got, known := KnownPricing(tt.model)
+		if known != tt.known || got != tt.want {
+			t.Errorf("KnownPricing(%q) = %+v, %v; want %+v, %v", tt.model, got, known, tt.want, tt.known)
+		}
+	}
+}
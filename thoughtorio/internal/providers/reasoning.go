@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Reasoning effort levels accepted by CompletionOptions.ReasoningEffort
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
+// Output token limits used when the caller doesn't set MaxTokens. Reasoning
+// models spend part of the output allowance thinking, so they get far more room.
+const (
+	defaultMaxTokens          = 1000
+	defaultReasoningMaxTokens = 25000
+)
+
+// reasoningBudgets maps effort levels to thinking-token budgets for providers
+// that take a budget rather than an effort level
+var reasoningBudgets = map[string]int{
+	ReasoningEffortLow:    1024,
+	ReasoningEffortMedium: 8192,
+	ReasoningEffortHigh:   24576,
+}
+
+// wantsReasoning reports whether the caller asked for a specific amount of thinking
+func (o CompletionOptions) wantsReasoning() bool {
+	return o.ReasoningEffort != "" || o.ThinkingBudget > 0
+}
+
+// thinkingBudget returns the explicit budget or the one implied by the effort level
+func (o CompletionOptions) thinkingBudget() int {
+	if o.ThinkingBudget > 0 {
+		return o.ThinkingBudget
+	}
+	return reasoningBudgets[o.ReasoningEffort]
+}
+
+// maxTokens returns the output token limit for model
+func (o CompletionOptions) maxTokens(model string) int {
+	if o.MaxTokens > 0 {
+		return o.MaxTokens
+	}
+	if IsReasoningModel(model) {
+		return defaultReasoningMaxTokens
+	}
+	return defaultMaxTokens
+}
+
+// reasoningModelPatterns match model IDs of models that think before answering.
+// IDs may carry a vendor prefix (openai/o3-mini) or a tag (deepseek-r1:14b).
+var reasoningModelPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(^|/)o[1-9](-|$)`),
+	regexp.MustCompile(`(^|/)gpt-5`),
+	regexp.MustCompile(`(^|/)gpt-oss`),
+	regexp.MustCompile(`deepseek-r1`),
+	regexp.MustCompile(`(^|/)qwq`),
+	regexp.MustCompile(`(^|/)qwen3`),
+	regexp.MustCompile(`(^|/)gemini-2\.5`),
+	regexp.MustCompile(`thinking`),
+	regexp.MustCompile(`reasoner`),
+}
+
+// IsReasoningModel reports whether a model ID belongs to a known reasoning model family
+func IsReasoningModel(model string) bool {
+	id := strings.ToLower(model)
+	for _, pattern := range reasoningModelPatterns {
+		if pattern.MatchString(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// thinkTagPattern matches a <think> or <thinking> block and its contents
+var thinkTagPattern = regexp.MustCompile(`(?s)<(think|thinking)>(.*?)</(think|thinking)>`)
+
+// SplitThinking separates <think> blocks from the answer in model output. A
+// block left open at the end (a truncated reply) counts as reasoning, and a
+// closing tag with no opening tag (templates that pre-fill <think>) marks
+// everything before it as reasoning.
+func SplitThinking(content string) (answer, reasoning string) {
+	var thoughts []string
+	// Empty blocks, which some models emit when thinking is off, are still removed
+	found := false
+
+	answer = thinkTagPattern.ReplaceAllStringFunc(content, func(block string) string {
+		found = true
+		match := thinkTagPattern.FindStringSubmatch(block)
+		if thought := strings.TrimSpace(match[2]); thought != "" {
+			thoughts = append(thoughts, thought)
+		}
+		return ""
+	})
+
+	for _, tag := range []string{"</think>", "</thinking>"} {
+		if index := strings.Index(answer, tag); index >= 0 {
+			found = true
+			if thought := strings.TrimSpace(answer[:index]); thought != "" {
+				thoughts = append([]string{thought}, thoughts...)
+			}
+			answer = answer[index+len(tag):]
+		}
+	}
+
+	for _, tag := range []string{"<think>", "<thinking>"} {
+		if index := strings.Index(answer, tag); index >= 0 {
+			found = true
+			if thought := strings.TrimSpace(answer[index+len(tag):]); thought != "" {
+				thoughts = append(thoughts, thought)
+			}
+			answer = answer[:index]
+		}
+	}
+
+	if !found {
+		return content, ""
+	}
+	return strings.TrimSpace(answer), strings.Join(thoughts, "\n\n")
+}
+
+// withReasoning fills Reasoning on a response, preferring the provider's native
+// reasoning text and otherwise parsing think tags out of the content
+func withReasoning(response AICompletionResponse, native string) AICompletionResponse {
+	answer, parsed := SplitThinking(response.Content)
+	response.Content = answer
+
+	native = strings.TrimSpace(native)
+	switch {
+	case native != "" && parsed != "":
+		response.Reasoning = native + "\n\n" + parsed
+	case native != "":
+		response.Reasoning = native
+	default:
+		response.Reasoning = parsed
+	}
+	return response
+}
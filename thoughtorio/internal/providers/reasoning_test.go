@@ -0,0 +1,164 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		wantAnswer    string
+		wantReasoning string
+	}{
+		{name: "no tags", content: "Just the answer.", wantAnswer: "Just the answer."},
+		{name: "think block", content: "<think>\nWeigh it.\n</think>\n\nThe answer.", wantAnswer: "The answer.", wantReasoning: "Weigh it."},
+		{name: "thinking block", content: "<thinking>Plan</thinking>Done", wantAnswer: "Done", wantReasoning: "Plan"},
+		{name: "several blocks", content: "<think>one</think>A<think>two</think>B", wantAnswer: "AB", wantReasoning: "one\n\ntwo"},
+		{name: "pre-filled opening tag", content: "Reason first.</think>Answer", wantAnswer: "Answer", wantReasoning: "Reason first."},
+		{name: "truncated block", content: "Answer so far<think>still going", wantAnswer: "Answer so far", wantReasoning: "still going"},
+		{name: "empty block", content: "<think> </think>Answer", wantAnswer: "Answer"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			answer, reasoning := SplitThinking(test.content)
+			if answer != test.wantAnswer || reasoning != test.wantReasoning {
+				t.Errorf("SplitThinking(%q) = %q, %q, want %q, %q", test.content, answer, reasoning, test.wantAnswer, test.wantReasoning)
+			}
+		})
+	}
+}
+
+func TestReasoningModelLimits(t *testing.T) {
+	for model, want := range map[string]bool{
+		"o3-mini":              true,
+		"openai/o1":            true,
+		"deepseek-r1:14b":      true,
+		"gemini-2.5-pro":       true,
+		"qwen3:8b":             true,
+		"gpt-4o":               false,
+		"llama3:latest":        false,
+		"anthropic/claude-3.5": false,
+	} {
+		if got := IsReasoningModel(model); got != want {
+			t.Errorf("IsReasoningModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+
+	if got := (CompletionOptions{}).maxTokens("gpt-4o"); got != defaultMaxTokens {
+		t.Errorf("maxTokens for gpt-4o = %d, want %d", got, defaultMaxTokens)
+	}
+	if got := (CompletionOptions{}).maxTokens("o3-mini"); got != defaultReasoningMaxTokens {
+		t.Errorf("maxTokens for o3-mini = %d, want %d", got, defaultReasoningMaxTokens)
+	}
+	if got := (CompletionOptions{MaxTokens: 50}).maxTokens("o3-mini"); got != 50 {
+		t.Errorf("maxTokens with an explicit limit = %d, want 50", got)
+	}
+	if got := (CompletionOptions{ReasoningEffort: ReasoningEffortMedium}).thinkingBudget(); got != 8192 {
+		t.Errorf("thinkingBudget for medium effort = %d, want 8192", got)
+	}
+	if got := (CompletionOptions{ReasoningEffort: ReasoningEffortLow, ThinkingBudget: 300}).thinkingBudget(); got != 300 {
+		t.Errorf("thinkingBudget with an explicit budget = %d, want 300", got)
+	}
+}
+
+// ollamaServer serves /api/chat with handle, recording each request body
+func ollamaServer(t *testing.T, handle func(w http.ResponseWriter, r *http.Request)) (*OllamaProvider, *[]map[string]interface{}) {
+	t.Helper()
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		handle(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	ollama := NewOllamaProvider()
+	if err := ollama.SetHost(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	return ollama, &requests
+}
+
+func TestOllamaStreamsReasoningSeparately(t *testing.T) {
+	ollama, requests := ollamaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		for _, line := range []string{
+			`{"message":{"content":"","thinking":"Native thought."},"done":false}`,
+			`{"message":{"content":"<think>Tagged thought.</think>"},"done":false}`,
+			`{"message":{"content":"The answer."},"done":false}`,
+			`{"message":{"content":""},"done":true,"done_reason":"stop","eval_count":3}`,
+		} {
+			fmt.Fprintln(w, line)
+		}
+	})
+
+	ctx := WithCompletionOptions(context.Background(), CompletionOptions{ReasoningEffort: ReasoningEffortHigh})
+	var chunks []string
+	response, err := ollama.StreamCompletion(ctx, "deepseek-r1:14b", "Question?", "", collect(&chunks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Content != "The answer." || response.Reasoning != "Native thought.\n\nTagged thought." {
+		t.Errorf("response = %q with reasoning %q, want the answer and both thoughts apart", response.Content, response.Reasoning)
+	}
+	if response.FinishReason != FinishReasonStop {
+		t.Errorf("finish reason = %q, want stop", response.FinishReason)
+	}
+	if strings.Join(chunks, "") != "<think>Tagged thought.</think>The answer." {
+		t.Errorf("chunks = %q, want the content as it arrived", chunks)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("server saw %d requests, want 1", len(*requests))
+	}
+	request := (*requests)[0]
+	if request["think"] != true || request["stream"] != true {
+		t.Errorf("request = %v, want streaming with thinking on", request)
+	}
+}
+
+func TestOllamaStreamCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	ollama, _ := ollamaServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"content":"Partial"},"done":false}`)
+		w.(http.Flusher).Flush()
+		// The rest never comes
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var chunks []string
+	done := make(chan error, 1)
+	go func() {
+		_, err := ollama.StreamCompletion(ctx, "llama3", "Question?", "", func(chunk string) {
+			chunks = append(chunks, chunk)
+			cancel()
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("a cancelled stream succeeded")
+		}
+		if strings.Join(chunks, "") != "Partial" {
+			t.Errorf("chunks = %q, want the output sent before the cancel", chunks)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelling did not end the stream")
+	}
+}
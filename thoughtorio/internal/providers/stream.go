@@ -0,0 +1,152 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// streamClient sends streamed completions. A streamed reply can take minutes,
+// so only the wait for response headers is bounded; callers cancel through ctx.
+var streamClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: 120 * time.Second,
+	},
+}
+
+// clientFor returns the client for a completion: the shared streaming client when
+// output is streamed, otherwise one that bounds the whole request by timeout
+func clientFor(onChunk func(string), timeout time.Duration) *http.Client {
+	if onChunk != nil {
+		return streamClient
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// errStreamDone stops reading a server-sent event stream at its [DONE] marker
+var errStreamDone = errors.New("stream done")
+
+// readServerSentEvents calls onData with the data of every event in an SSE
+// stream until the stream ends or sends OpenAI's [DONE] marker. Comments, such
+// as the keep-alives OpenRouter sends while a model is queued, are skipped.
+func readServerSentEvents(body io.Reader, onData func(data []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var data []byte
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		event := data
+		data = nil
+		if string(event) == "[DONE]" {
+			return errStreamDone
+		}
+		return onData(event)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			if err := dispatch(); err != nil {
+				return ignoreStreamDone(err)
+			}
+			continue
+		}
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		if len(data) > 0 {
+			data = append(data, '\n')
+		}
+		data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return ignoreStreamDone(dispatch())
+}
+
+func ignoreStreamDone(err error) error {
+	if errors.Is(err, errStreamDone) {
+		return nil
+	}
+	return err
+}
+
+// chatCompletion is an OpenAI-compatible chat completion body; OpenRouter adds
+// the model's thinking as reasoning
+type chatCompletion struct {
+	Choices []chatChoice `json:"choices"`
+	Usage   *openAIUsage `json:"usage"`
+	Error   struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type chatChoice struct {
+	Message      chatMessage `json:"message"`
+	Delta        chatMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type chatMessage struct {
+	Content   string `json:"content"`
+	Reasoning string `json:"reasoning"`
+}
+
+// decodeChatCompletion reads a chat completion response. When onChunk is set the
+// body is a stream of deltas, which are passed on as they arrive and assembled
+// into a single completion.
+func decodeChatCompletion(body io.Reader, onChunk func(string)) (chatCompletion, error) {
+	var result chatCompletion
+	if onChunk == nil {
+		err := json.NewDecoder(body).Decode(&result)
+		return result, err
+	}
+
+	var content, reasoning strings.Builder
+	var finishReason string
+	err := readServerSentEvents(body, func(data []byte) error {
+		var chunk chatCompletion
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("invalid stream event: %w", err)
+		}
+		if chunk.Error.Message != "" {
+			result.Error = chunk.Error
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onChunk(choice.Delta.Content)
+			}
+			reasoning.WriteString(choice.Delta.Reasoning)
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if content.Len() > 0 || reasoning.Len() > 0 || finishReason != "" {
+		result.Choices = []chatChoice{{
+			Message:      chatMessage{Content: content.String(), Reasoning: reasoning.String()},
+			FinishReason: finishReason,
+		}}
+	}
+	return result, nil
+}
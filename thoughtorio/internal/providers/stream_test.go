@@ -0,0 +1,118 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+func collect(chunks *[]string) func(string) {
+	return func(chunk string) { *chunks = append(*chunks, chunk) }
+}
+
+func TestDecodeChatCompletionStream(t *testing.T) {
+	body := strings.Join([]string{
+		": OPENROUTER PROCESSING",
+		"",
+		`data: {"choices":[{"delta":{"reasoning":"Think"}}]}`,
+		"",
+		`data: {"choices":[{"delta":{"content":"Hel"}}]}`,
+		"",
+		`data: {"choices":[{"delta":{"content":"lo"},"finish_reason":"length"}]}`,
+		"",
+		`data: {"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+		"",
+		"data: [DONE]",
+		"",
+		`data: {"choices":[{"delta":{"content":"ignored"}}]}`,
+		"",
+	}, "\n")
+
+	var chunks []string
+	result, err := decodeChatCompletion(strings.NewReader(body), collect(&chunks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(chunks, "|") != "Hel|lo" {
+		t.Errorf("chunks %q", chunks)
+	}
+	if len(result.Choices) != 1 {
+		t.Fatalf("got %d choices", len(result.Choices))
+	}
+	choice := result.Choices[0]
+	if choice.Message.Content != "Hello" || choice.Message.Reasoning != "Think" || choice.FinishReason != "length" {
+		t.Errorf("unexpected choice %+v", choice)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 5 {
+		t.Errorf("unexpected usage %+v", result.Usage)
+	}
+}
+
+func TestDecodeChatCompletionStreamError(t *testing.T) {
+	body := "data: {\"error\":{\"message\":\"overloaded\"}}\n\n"
+	result, err := decodeChatCompletion(strings.NewReader(body), func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error.Message != "overloaded" || len(result.Choices) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestDecodeGeminiResponseStream(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"candidates":[{"content":{"parts":[{"text":"plan","thought":true}]}}]}`,
+		"",
+		`data: {"candidates":[{"content":{"parts":[{"text":"Hi "}]}}]}`,
+		"",
+		`data: {"candidates":[{"content":{"parts":[{"text":"there"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":2,"totalTokenCount":6}}`,
+		"",
+	}, "\n")
+
+	var chunks []string
+	result, err := decodeGeminiResponse(strings.NewReader(body), collect(&chunks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(chunks, "|") != "Hi |there" {
+		t.Errorf("chunks %q", chunks)
+	}
+	candidate := result.Candidates[0]
+	if len(candidate.Content.Parts) != 3 || candidate.FinishReason != "STOP" {
+		t.Errorf("unexpected candidate %+v", candidate)
+	}
+	if result.UsageMetadata == nil || result.UsageMetadata.TotalTokenCount != 6 {
+		t.Errorf("unexpected usage %+v", result.UsageMetadata)
+	}
+}
+
+func TestDecodeOllamaResponseStream(t *testing.T) {
+	body := strings.Join([]string{
+		`{"message":{"content":"","thinking":"hmm"},"done":false}`,
+		`{"message":{"content":"Hel"},"done":false}`,
+		`{"message":{"content":"lo"},"done":false}`,
+		`{"message":{"content":""},"done":true,"done_reason":"stop","prompt_eval_count":7,"eval_count":2}`,
+	}, "\n")
+
+	var chunks []string
+	result, err := decodeOllamaResponse(strings.NewReader(body), collect(&chunks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(chunks, "|") != "Hel|lo" {
+		t.Errorf("chunks %q", chunks)
+	}
+	if result.Message.Content != "Hello" || result.Message.Thinking != "hmm" || result.DoneReason != "stop" || result.EvalCount != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestDecodeOllamaResponseStreamError(t *testing.T) {
+	body := `{"message":{"content":"Hel"},"done":false}` + "\n" + `{"error":"model crashed"}` + "\n"
+	result, err := decodeOllamaResponse(strings.NewReader(body), func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Error != "model crashed" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
@@ -0,0 +1,213 @@
+package secrets
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Secret Service D-Bus names (org.freedesktop.secrets), implemented by GNOME
+// Keyring, KWallet and KeePassXC
+const (
+	secretServiceName      = "org.freedesktop.secrets"
+	secretServicePath      = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceInterface = "org.freedesktop.Secret.Service"
+	secretItemInterface    = "org.freedesktop.Secret.Item"
+	secretPromptInterface  = "org.freedesktop.Secret.Prompt"
+	defaultCollectionPath  = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+
+	// keyringApplication tags every item so the app only sees its own secrets;
+	// items are also tagged with the profile they belong to
+	keyringApplication = "thoughtorio"
+)
+
+// noPrompt is the object path returned when an operation needs no user prompt
+const noPrompt = dbus.ObjectPath("/")
+
+// secretValue mirrors the Secret Service (oayays) secret struct
+type secretValue struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// Keyring stores secrets in the desktop keyring through the Secret Service API
+type Keyring struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+	profile string
+}
+
+// OpenKeyring connects to the Secret Service on the session bus, scoping
+// secrets to profile. It fails when there is no session bus or no keyring
+// daemon, so callers can fall back.
+func OpenKeyring(profile string) (*Keyring, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("no session bus: %w", err)
+	}
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	service := conn.Object(secretServiceName, secretServicePath)
+	if err := service.Call(secretServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("secret service not available: %w", err)
+	}
+
+	return &Keyring{conn: conn, session: session, profile: profile}, nil
+}
+
+// Close ends the keyring session and closes its bus connection
+func (k *Keyring) Close() error {
+	return k.conn.Close()
+}
+
+// Get returns the named secret
+func (k *Keyring) Get(name string) (string, error) {
+	item, err := k.find(name)
+	if err != nil {
+		return "", err
+	}
+	if err := k.unlock(item); err != nil {
+		return "", err
+	}
+
+	var secret secretValue
+	if err := k.conn.Object(secretServiceName, item).Call(secretItemInterface+".GetSecret", 0, k.session).Store(&secret); err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return string(secret.Value), nil
+}
+
+// Set stores a secret in the default collection, replacing any previous value
+func (k *Keyring) Set(name, value string) error {
+	if err := k.unlock(defaultCollectionPath); err != nil {
+		return err
+	}
+
+	properties := map[string]dbus.Variant{
+		secretItemInterface + ".Label":      dbus.MakeVariant(fmt.Sprintf("Thoughtorio (%s): %s", k.profile, name)),
+		secretItemInterface + ".Attributes": dbus.MakeVariant(k.attributes(name)),
+	}
+	secret := secretValue{
+		Session:     k.session,
+		Parameters:  []byte{},
+		Value:       []byte(value),
+		ContentType: "text/plain",
+	}
+
+	var item, prompt dbus.ObjectPath
+	collection := k.conn.Object(secretServiceName, defaultCollectionPath)
+	if err := collection.Call("org.freedesktop.Secret.Collection.CreateItem", 0, properties, secret, true).Store(&item, &prompt); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	return k.prompt(prompt)
+}
+
+// Delete removes the named secret
+func (k *Keyring) Delete(name string) error {
+	item, err := k.find(name)
+	if err != nil {
+		return err
+	}
+
+	var prompt dbus.ObjectPath
+	if err := k.conn.Object(secretServiceName, item).Call(secretItemInterface+".Delete", 0).Store(&prompt); err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return k.prompt(prompt)
+}
+
+// List returns the names of the app's secrets, sorted
+func (k *Keyring) List() ([]string, error) {
+	items, err := k.search(map[string]string{"application": keyringApplication, "profile": k.profile})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, item := range items {
+		variant, err := k.conn.Object(secretServiceName, item).GetProperty(secretItemInterface + ".Attributes")
+		if err != nil {
+			continue
+		}
+		if attributes, ok := variant.Value().(map[string]string); ok && attributes["name"] != "" {
+			names = append(names, attributes["name"])
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// attributes identifies the item holding a secret
+func (k *Keyring) attributes(name string) map[string]string {
+	return map[string]string{"application": keyringApplication, "profile": k.profile, "name": name}
+}
+
+// find returns the item holding the named secret
+func (k *Keyring) find(name string) (dbus.ObjectPath, error) {
+	items, err := k.search(k.attributes(name))
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", ErrNotFound
+	}
+	return items[0], nil
+}
+
+// search returns unlocked and locked items matching attributes
+func (k *Keyring) search(attributes map[string]string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	service := k.conn.Object(secretServiceName, secretServicePath)
+	if err := service.Call(secretServiceInterface+".SearchItems", 0, attributes).Store(&unlocked, &locked); err != nil {
+		return nil, fmt.Errorf("failed to search keyring: %w", err)
+	}
+	return append(unlocked, locked...), nil
+}
+
+// unlock asks the keyring to unlock an item or collection, prompting the user if needed
+func (k *Keyring) unlock(object dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	service := k.conn.Object(secretServiceName, secretServicePath)
+	if err := service.Call(secretServiceInterface+".Unlock", 0, []dbus.ObjectPath{object}).Store(&unlocked, &prompt); err != nil {
+		return fmt.Errorf("failed to unlock keyring: %w", err)
+	}
+	return k.prompt(prompt)
+}
+
+// prompt shows a keyring prompt and waits for the user to complete or dismiss it
+func (k *Keyring) prompt(prompt dbus.ObjectPath) error {
+	if prompt == noPrompt || prompt == "" {
+		return nil
+	}
+
+	signals := make(chan *dbus.Signal, 1)
+	k.conn.Signal(signals)
+	defer k.conn.RemoveSignal(signals)
+
+	match := []dbus.MatchOption{dbus.WithMatchObjectPath(prompt), dbus.WithMatchInterface(secretPromptInterface)}
+	if err := k.conn.AddMatchSignal(match...); err != nil {
+		return fmt.Errorf("failed to watch keyring prompt: %w", err)
+	}
+	defer k.conn.RemoveMatchSignal(match...)
+
+	if err := k.conn.Object(secretServiceName, prompt).Call(secretPromptInterface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("failed to show keyring prompt: %w", err)
+	}
+
+	for signal := range signals {
+		if signal.Path != prompt || signal.Name != secretPromptInterface+".Completed" {
+			continue
+		}
+		if len(signal.Body) > 0 && signal.Body[0] == true {
+			return fmt.Errorf("keyring prompt was dismissed")
+		}
+		return nil
+	}
+	return fmt.Errorf("keyring connection closed")
+}
@@ -0,0 +1,237 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RefPrefix marks a value as a reference to a stored secret rather than the secret itself
+const RefPrefix = "secret:"
+
+// Backend names reported by Status
+const (
+	BackendKeyring = "keyring"
+	BackendVault   = "vault"
+)
+
+var (
+	// ErrNotFound is returned when no secret is stored under a name
+	ErrNotFound = errors.New("secret not found")
+	// ErrLocked is returned by the vault until it is unlocked with its passphrase
+	ErrLocked = errors.New("secret vault is locked")
+)
+
+// namePattern allows names like "openai" or "openai_embedding"
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// Store is a place secrets can be kept
+type Store interface {
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Delete(name string) error
+	// List returns the names of the stored secrets
+	List() ([]string, error)
+}
+
+// Status describes the secrets backend for the frontend
+type Status struct {
+	Backend string `json:"backend"`
+	Locked  bool   `json:"locked"`
+	// Initialized is false until the vault's passphrase has been set
+	Initialized bool     `json:"initialized"`
+	Stored      []string `json:"stored"`
+	// Pending names secrets held in memory until the vault is unlocked
+	Pending []string `json:"pending"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Ref returns the reference the frontend keeps in place of the named secret
+func Ref(name string) string {
+	return RefPrefix + name
+}
+
+// ParseRef returns the secret name a reference points to
+func ParseRef(value string) (string, bool) {
+	if !strings.HasPrefix(value, RefPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(value, RefPrefix), true
+}
+
+// ValidateName checks a secret name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("secret name %q must be 1-64 lower-case letters, digits, '.', '-' or '_'", name)
+	}
+	return nil
+}
+
+// Service stores secrets in the OS keyring, falling back to a
+// passphrase-encrypted vault in dir when no keyring is available
+type Service struct {
+	store   Store
+	backend string
+	vault   *Vault
+	// pending holds secrets set while the vault is locked; they are usable
+	// right away and written to the vault when it is unlocked
+	pending map[string]string
+	mu      sync.RWMutex
+}
+
+// NewService opens the profile's secrets in the OS keyring, or the encrypted
+// vault in dir when the keyring can't be reached
+func NewService(dir, profile string) *Service {
+	keyring, err := OpenKeyring(profile)
+	if err == nil {
+		return &Service{store: keyring, backend: BackendKeyring, pending: map[string]string{}}
+	}
+	log.Printf("OS keyring not available, using encrypted vault: %v", err)
+
+	vault := NewVault(filepath.Join(dir, "secrets.vault"))
+	return &Service{store: vault, backend: BackendVault, vault: vault, pending: map[string]string{}}
+}
+
+// Close releases the backend's connection, such as the keyring's session bus.
+// Secrets held while the vault was locked are forgotten.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = map[string]string{}
+	if closer, ok := s.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	if s.vault != nil {
+		s.vault.Lock()
+	}
+	return nil
+}
+
+// Backend returns the name of the backend in use
+func (s *Service) Backend() string {
+	return s.backend
+}
+
+// Unlock opens the vault with its passphrase, creating the vault on first use,
+// and saves the secrets set while it was locked. It is a no-op for the keyring
+// backend.
+func (s *Service) Unlock(passphrase string) error {
+	if s.vault == nil {
+		return nil
+	}
+	if err := s.vault.Unlock(passphrase); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, value := range s.pending {
+		if err := s.store.Set(name, value); err != nil {
+			return fmt.Errorf("failed to save secret %q: %w", name, err)
+		}
+		delete(s.pending, name)
+	}
+	return nil
+}
+
+// Lock forgets the vault key until the next Unlock
+func (s *Service) Lock() {
+	if s.vault != nil {
+		s.vault.Lock()
+	}
+}
+
+// Get returns the named secret
+func (s *Service) Get(name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if value, ok := s.pending[name]; ok {
+		return value, nil
+	}
+	return s.store.Get(name)
+}
+
+// Set stores a secret and returns the reference to use in its place. While the
+// vault is locked the secret is held in memory until Unlock saves it.
+func (s *Service) Set(name, value string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("secret %q is empty", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.store.Set(name, value)
+	if errors.Is(err, ErrLocked) {
+		s.pending[name] = value
+		return Ref(name), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	delete(s.pending, name)
+	return Ref(name), nil
+}
+
+// Delete removes the named secret
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, held := s.pending[name]
+	delete(s.pending, name)
+	err := s.store.Delete(name)
+	if held && (errors.Is(err, ErrNotFound) || errors.Is(err, ErrLocked)) {
+		return nil
+	}
+	return err
+}
+
+// Resolve returns the secret a reference points to, or value unchanged when it isn't a reference
+func (s *Service) Resolve(value string) (string, error) {
+	name, ok := ParseRef(value)
+	if !ok {
+		return value, nil
+	}
+	secret, err := s.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %q: %w", name, err)
+	}
+	return secret, nil
+}
+
+// Status reports the backend, whether it is locked and which secrets it holds
+func (s *Service) Status() Status {
+	status := Status{Backend: s.backend, Initialized: true, Stored: []string{}, Pending: []string{}}
+	if s.vault != nil {
+		status.Locked = s.vault.Locked()
+		status.Initialized = s.vault.Exists()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for name := range s.pending {
+		status.Pending = append(status.Pending, name)
+	}
+	sort.Strings(status.Pending)
+	if status.Locked {
+		return status
+	}
+
+	names, err := s.store.List()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Stored = names
+	return status
+}
@@ -0,0 +1,56 @@
+package secrets
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestServiceHoldsSecretsWhileLocked(t *testing.T) {
+	dir := t.TempDir()
+	vault := NewVault(filepath.Join(dir, "secrets.vault"))
+	service := &Service{store: vault, backend: BackendVault, vault: vault, pending: map[string]string{}}
+
+	ref, err := service.Set("openai", "sk-test")
+	if err != nil {
+		t.Fatalf("Set while locked: %v", err)
+	}
+	if ref != Ref("openai") {
+		t.Errorf("ref = %q, want %q", ref, Ref("openai"))
+	}
+	if got, err := service.Resolve(ref); err != nil || got != "sk-test" {
+		t.Errorf("Resolve while locked = %q, %v; want the held key", got, err)
+	}
+	if status := service.Status(); !status.Locked || len(status.Pending) != 1 {
+		t.Errorf("status = %+v, want locked with one pending key", status)
+	}
+
+	if err := service.Unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if status := service.Status(); len(status.Pending) != 0 || len(status.Stored) != 1 {
+		t.Errorf("status after unlock = %+v, want the key stored", status)
+	}
+
+	reopened := NewVault(filepath.Join(dir, "secrets.vault"))
+	if err := reopened.Unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.Get("openai"); err != nil || got != "sk-test" {
+		t.Errorf("vault holds %q, %v; want sk-test", got, err)
+	}
+}
+
+func TestServiceDeletePendingSecret(t *testing.T) {
+	vault := NewVault(filepath.Join(t.TempDir(), "secrets.vault"))
+	service := &Service{store: vault, backend: BackendVault, vault: vault, pending: map[string]string{}}
+
+	if _, err := service.Set("gemini", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Delete("gemini"); err != nil {
+		t.Fatalf("Delete of a held key: %v", err)
+	}
+	if _, err := service.Get("gemini"); err == nil {
+		t.Error("Get after Delete succeeded")
+	}
+}
@@ -0,0 +1,266 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// vaultVersion is written to the vault file so future formats can be told apart
+const vaultVersion = 1
+
+// scrypt parameters for deriving the vault key from the passphrase
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	vaultKeySize = 32
+	vaultSaltLen = 16
+)
+
+// vaultFile is the on-disk vault: the secrets map sealed with AES-256-GCM
+type vaultFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Vault is a passphrase-encrypted secrets file used when no OS keyring exists
+type Vault struct {
+	path    string
+	key     []byte
+	salt    []byte
+	secrets map[string]string
+	mu      sync.Mutex
+}
+
+// NewVault returns a locked vault stored at path
+func NewVault(path string) *Vault {
+	return &Vault{path: path}
+}
+
+// Exists reports whether the vault file has been created, that is whether
+// Unlock checks a passphrase rather than setting one
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.path)
+	return err == nil
+}
+
+// Locked reports whether the vault still needs its passphrase
+func (v *Vault) Locked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.key == nil
+}
+
+// Unlock derives the key from passphrase and decrypts the vault. When the vault
+// file doesn't exist yet an empty vault protected by passphrase is created.
+func (v *Vault) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase is required")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	data, err := os.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		salt := make([]byte, vaultSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		key, err := deriveKey(passphrase, salt)
+		if err != nil {
+			return err
+		}
+		v.key, v.salt, v.secrets = key, salt, map[string]string{}
+		return v.save()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %w", err)
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse vault: %w", err)
+	}
+	if file.Version > vaultVersion {
+		return fmt.Errorf("vault version %d is newer than this app supports", file.Version)
+	}
+
+	key, err := deriveKey(passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("wrong passphrase or corrupted vault")
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("failed to parse vault contents: %w", err)
+	}
+
+	v.key, v.salt, v.secrets = key, file.Salt, secrets
+	return nil
+}
+
+// Lock forgets the key and decrypted secrets
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.key, v.secrets = nil, nil
+}
+
+// Get returns the named secret
+func (v *Vault) Get(name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return "", ErrLocked
+	}
+	secret, ok := v.secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set stores a secret and rewrites the vault
+func (v *Vault) Set(name, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.secrets[name] = value
+	return v.save()
+}
+
+// Delete removes a secret and rewrites the vault
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return ErrLocked
+	}
+	if _, ok := v.secrets[name]; !ok {
+		return ErrNotFound
+	}
+	delete(v.secrets, name)
+	return v.save()
+}
+
+// List returns the stored secret names, sorted
+func (v *Vault) List() ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return nil, ErrLocked
+	}
+	names := make([]string, 0, len(v.secrets))
+	for name := range v.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// save encrypts the secrets with a fresh nonce and writes the vault file
+func (v *Vault) save() error {
+	plaintext, err := json.Marshal(v.secrets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	gcm, err := newGCM(v.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	data, err := json.MarshalIndent(vaultFile{
+		Version:    vaultVersion,
+		Salt:       v.salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
+	}
+
+	return writeFileAtomic(v.path, data, 0600)
+}
+
+// writeFileAtomic writes data to a temporary file beside path, flushes it and
+// renames it over path, so a crash mid-write can't leave a truncated vault
+// and lose every stored key
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary vault: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if err = tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set vault permissions: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to flush vault: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close vault: %w", err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace vault: %w", err)
+	}
+	return nil
+}
+
+// deriveKey stretches the passphrase into an AES-256 key
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, vaultKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault key: %w", err)
+	}
+	return key, nil
+}
+
+// newGCM returns an AES-GCM cipher for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestVaultRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.vault")
+
+	vault := NewVault(path)
+	if vault.Exists() || !vault.Locked() {
+		t.Fatal("new vault should be missing and locked")
+	}
+	if err := vault.Set("openai", "sk-test"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Set on a locked vault = %v, want ErrLocked", err)
+	}
+
+	if err := vault.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if !vault.Exists() {
+		t.Fatal("first Unlock should create the vault file")
+	}
+	if err := vault.Set("openai", "sk-test"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the vault", len(entries))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); runtime.GOOS != "windows" && perm != 0600 {
+		t.Errorf("vault permissions = %v, want 0600", perm)
+	}
+
+	reopened := NewVault(path)
+	if err := reopened.Unlock("wrong"); err == nil {
+		t.Error("Unlock with the wrong passphrase succeeded")
+	}
+	if err := reopened.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.Get("openai"); err != nil || got != "sk-test" {
+		t.Errorf("Get = %q, %v; want sk-test", got, err)
+	}
+
+	reopened.Lock()
+	if _, err := reopened.Get("openai"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Get after Lock = %v, want ErrLocked", err)
+	}
+}
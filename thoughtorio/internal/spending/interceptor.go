@@ -0,0 +1,81 @@
+package spending
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"thoughtorio/internal/providers"
+)
+
+// NewInterceptor returns a provider interceptor that blocks completions which
+// would break a hard cap and records the cost of those that run. Costs come
+// from reported token usage and the model's pricing; onWarning, when not nil,
+// receives soft warnings as spend approaches a cap.
+func NewInterceptor(tracker *Tracker, pm *providers.ProviderManager, onWarning func(Warning)) providers.Interceptor {
+	return providers.InterceptorFuncs{
+		Label: "spending",
+		Completion: func(next providers.CompletionHandler) providers.CompletionHandler {
+			return func(ctx context.Context, req *providers.CompletionRequest) (providers.AICompletionResponse, error) {
+				config := tracker.GetConfig()
+				if !config.Enabled {
+					return next(ctx, req)
+				}
+
+				canvasPath := req.Metadata[providers.MetadataCanvasPath]
+				pricing, priced := pm.ModelPricing(req.Provider, req.Model)
+				if !priced && !config.AllowUnpriced && tracker.Capped(req.Provider, canvasPath) {
+					err := fmt.Errorf("%w: %s model %q has no known price, so it can't be held to the spending cap", ErrUnpricedModel, req.Provider, req.Model)
+					return providers.AICompletionResponse{Error: err.Error()}, err
+				}
+
+				sent := req.Prompt
+				if req.Options.SystemPrompt != "" {
+					sent = req.Options.SystemPrompt + "\n\n" + req.Prompt
+				}
+
+				// The reply's length isn't known up front, so the reservation
+				// assumes it uses the whole output limit, or the budgeting reserve
+				var estimated float64
+				if priced {
+					expectedOutput := req.Options.MaxTokens
+					if expectedOutput <= 0 {
+						expectedOutput = pm.GetBudgetConfig().ReserveOutputTokens
+					}
+					estimated = pricing.Cost(pm.EstimatePrompt(ctx, req.Provider, req.Model, sent).Tokens, expectedOutput)
+				}
+				reservation, err := tracker.Reserve(req.Provider, canvasPath, estimated)
+				if err != nil {
+					return providers.AICompletionResponse{Error: err.Error()}, err
+				}
+
+				response, err := next(ctx, req)
+
+				var cost float64
+				if priced {
+					promptTokens, completionTokens := 0, 0
+					if response.Usage != nil {
+						promptTokens, completionTokens = response.Usage.PromptTokens, response.Usage.CompletionTokens
+					} else if err == nil {
+						promptTokens = pm.EstimatePrompt(ctx, req.Provider, req.Model, sent).Tokens
+						completionTokens = pm.EstimatePrompt(ctx, req.Provider, req.Model, response.Content+response.Reasoning).Tokens
+					}
+					cost = pricing.Cost(promptTokens, completionTokens)
+					response.Cost = cost
+				}
+
+				warnings, settleErr := tracker.Settle(reservation, cost)
+				if settleErr != nil {
+					log.Printf("failed to record spend: %v", settleErr)
+				}
+				if onWarning != nil {
+					for _, warning := range warnings {
+						onWarning(warning)
+					}
+				}
+
+				return response, err
+			}
+		},
+	}
+}
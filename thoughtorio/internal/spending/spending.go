@@ -0,0 +1,512 @@
+package spending
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	configFileName = "config.json"
+	ledgerFileName = "ledger.json"
+
+	// DefaultWarnRatio is the share of a cap at which a soft warning is raised
+	DefaultWarnRatio = 0.8
+
+	// ledgerRetentionDays is how many days of per-day spend are kept
+	ledgerRetentionDays = 90
+)
+
+// Scopes a budget applies to
+const (
+	ScopeDaily    = "daily"
+	ScopeProvider = "provider"
+	ScopeCanvas   = "canvas"
+)
+
+// ErrBudgetExceeded is matched by every BudgetExceededError
+var ErrBudgetExceeded = errors.New("spending budget exceeded")
+
+// ErrUnpricedModel is returned for models without a known price while a hard
+// cap applies, since their spend couldn't be held to it
+var ErrUnpricedModel = errors.New("model price unknown")
+
+// BudgetExceededError reports a completion blocked by a hard cap
+type BudgetExceededError struct {
+	Scope string `json:"scope"`
+	// Key is the provider name or canvas path for those scopes
+	Key       string  `json:"key,omitempty"`
+	Limit     float64 `json:"limit"`
+	Spent     float64 `json:"spent"`
+	Estimated float64 `json:"estimated"`
+}
+
+func (e *BudgetExceededError) Error() string {
+	target := e.Scope
+	if e.Key != "" {
+		target = fmt.Sprintf("%s %q", e.Scope, e.Key)
+	}
+	return fmt.Sprintf("%s budget exceeded: spent $%.4f of $%.2f (this request ~$%.4f)", target, e.Spent, e.Limit, e.Estimated)
+}
+
+// Is makes errors.Is(err, ErrBudgetExceeded) true for budget errors
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// Config sets the spending caps in US dollars; a zero limit means no cap
+type Config struct {
+	// Enabled turns tracking and enforcement on
+	Enabled bool `json:"enabled"`
+	// DailyLimit caps total spend per calendar day
+	DailyLimit float64 `json:"dailyLimit"`
+	// ProviderDailyLimits caps spend per provider per calendar day
+	ProviderDailyLimits map[string]float64 `json:"providerDailyLimits,omitempty"`
+	// CanvasLimit caps the total spend of any single canvas
+	CanvasLimit float64 `json:"canvasLimit"`
+	// WarnRatio raises a soft warning once this share of a cap is spent
+	WarnRatio float64 `json:"warnRatio"`
+	// AllowUnpriced runs models without a known price even when a cap applies;
+	// their cost then counts as nothing
+	AllowUnpriced bool `json:"allowUnpriced,omitempty"`
+}
+
+// DefaultConfig returns the configuration used until budgets are set
+func DefaultConfig() Config {
+	return Config{
+		Enabled:             false,
+		ProviderDailyLimits: map[string]float64{},
+		WarnRatio:           DefaultWarnRatio,
+	}
+}
+
+// Validate checks the limits
+func (c Config) Validate() error {
+	if c.DailyLimit < 0 || c.CanvasLimit < 0 {
+		return fmt.Errorf("spending limits cannot be negative")
+	}
+	for provider, limit := range c.ProviderDailyLimits {
+		if limit < 0 {
+			return fmt.Errorf("spending limit for %s cannot be negative", provider)
+		}
+	}
+	if c.WarnRatio < 0 || c.WarnRatio > 1 {
+		return fmt.Errorf("warning ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Budget is the spend against one cap
+type Budget struct {
+	Scope     string  `json:"scope"`
+	Key       string  `json:"key,omitempty"`
+	Limit     float64 `json:"limit"`
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"`
+	// Capped is false when no limit is set; Remaining is then meaningless
+	Capped bool `json:"capped"`
+}
+
+// Status reports today's spend and remaining budget
+type Status struct {
+	Enabled   bool              `json:"enabled"`
+	Day       string            `json:"day"`
+	Daily     Budget            `json:"daily"`
+	Providers map[string]Budget `json:"providers"`
+	Canvas    *Budget           `json:"canvas,omitempty"`
+}
+
+// Warning is raised once per cap and day when spend crosses the warning ratio
+type Warning struct {
+	Budget
+	Ratio float64 `json:"ratio"`
+}
+
+// daySpend is the spend recorded on one calendar day
+type daySpend struct {
+	Total     float64            `json:"total"`
+	Providers map[string]float64 `json:"providers"`
+}
+
+// ledger is the persisted spend record
+type ledger struct {
+	Days     map[string]*daySpend `json:"days"`
+	Canvases map[string]float64   `json:"canvases"`
+}
+
+// Reservation is the estimated cost of a running completion, held against the
+// caps it falls under until Settle records what it actually cost
+type Reservation struct {
+	provider   string
+	canvasPath string
+	amount     float64
+}
+
+// Tracker records spend and enforces the configured caps
+type Tracker struct {
+	dir    string
+	config Config
+	ledger ledger
+	warned map[string]bool
+	// pending holds the reserved amounts of running completions by budget key
+	pending map[string]float64
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewTracker creates a tracker storing its configuration and ledger in dir
+func NewTracker(dir string) (*Tracker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spending directory: %w", err)
+	}
+
+	t := &Tracker{
+		dir:     dir,
+		config:  DefaultConfig(),
+		ledger:  ledger{Days: map[string]*daySpend{}, Canvases: map[string]float64{}},
+		warned:  map[string]bool{},
+		pending: map[string]float64{},
+		now:     time.Now,
+	}
+
+	if err := readJSON(filepath.Join(dir, configFileName), &t.config); err != nil {
+		return nil, fmt.Errorf("failed to load spending config: %w", err)
+	}
+	if err := readJSON(filepath.Join(dir, ledgerFileName), &t.ledger); err != nil {
+		return nil, fmt.Errorf("failed to load spending ledger: %w", err)
+	}
+	if t.ledger.Days == nil {
+		t.ledger.Days = map[string]*daySpend{}
+	}
+	if t.ledger.Canvases == nil {
+		t.ledger.Canvases = map[string]float64{}
+	}
+
+	return t, nil
+}
+
+// SwitchDir loads the configuration and ledger kept in dir, as when the active
+// profile changes. On error the tracker keeps its current state.
+func (t *Tracker) SwitchDir(dir string) error {
+	next, err := NewTracker(dir)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dir, t.config, t.ledger = next.dir, next.config, next.ledger
+	t.warned = map[string]bool{}
+	return nil
+}
+
+// GetConfig returns the current spending configuration
+func (t *Tracker) GetConfig() Config {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.config
+}
+
+// SetConfig validates, applies and persists the spending configuration
+func (t *Tracker) SetConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if config.ProviderDailyLimits == nil {
+		config.ProviderDailyLimits = map[string]float64{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := writeJSON(filepath.Join(t.dir, configFileName), config); err != nil {
+		return fmt.Errorf("failed to save spending config: %w", err)
+	}
+	t.config = config
+	t.warned = map[string]bool{}
+	return nil
+}
+
+// Capped reports whether any hard cap applies to a request
+func (t *Tracker) Capped(provider, canvasPath string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.config.Enabled {
+		return false
+	}
+	for _, budget := range t.budgets(provider, canvasPath) {
+		if budget.Capped {
+			return true
+		}
+	}
+	return false
+}
+
+// Reserve holds estimated against every cap a request falls under, counting
+// the reservations of completions still running. Checking and holding happen
+// together, so parallel completions can't each pass the check and then overrun
+// the cap between them. It returns a BudgetExceededError when a cap would be
+// broken; otherwise the reservation must be passed to Settle once the
+// completion finishes.
+func (t *Tracker) Reserve(provider, canvasPath string, estimated float64) (*Reservation, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reservation := &Reservation{provider: provider, canvasPath: canvasPath}
+	if !t.config.Enabled {
+		return reservation, nil
+	}
+
+	for _, budget := range t.budgets(provider, canvasPath) {
+		spent := budget.Spent + t.pending[budgetKey(budget.Scope, budget.Key)]
+		if budget.Capped && spent+estimated > budget.Limit {
+			return nil, &BudgetExceededError{
+				Scope:     budget.Scope,
+				Key:       budget.Key,
+				Limit:     budget.Limit,
+				Spent:     spent,
+				Estimated: estimated,
+			}
+		}
+	}
+
+	reservation.amount = estimated
+	t.hold(reservation, estimated)
+	return reservation, nil
+}
+
+// Settle releases a reservation and records the actual cost of its completion,
+// returning any soft warnings it triggered
+func (t *Tracker) Settle(reservation *Reservation, cost float64) ([]Warning, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hold(reservation, -reservation.amount)
+	reservation.amount = 0
+	return t.record(reservation.provider, reservation.canvasPath, cost)
+}
+
+// hold adds amount to the pending spend of every budget a reservation falls under
+func (t *Tracker) hold(reservation *Reservation, amount float64) {
+	if amount == 0 {
+		return
+	}
+	keys := []string{budgetKey(ScopeDaily, ""), budgetKey(ScopeProvider, reservation.provider)}
+	if reservation.canvasPath != "" {
+		keys = append(keys, budgetKey(ScopeCanvas, reservation.canvasPath))
+	}
+	for _, key := range keys {
+		t.pending[key] += amount
+		if t.pending[key] <= 1e-12 {
+			delete(t.pending, key)
+		}
+	}
+}
+
+func budgetKey(scope, key string) string {
+	return scope + "|" + key
+}
+
+// Record adds the cost of a completion and returns any soft warnings it triggered
+func (t *Tracker) Record(provider, canvasPath string, cost float64) ([]Warning, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.record(provider, canvasPath, cost)
+}
+
+// record adds the cost of a completion; callers hold t.mu
+func (t *Tracker) record(provider, canvasPath string, cost float64) ([]Warning, error) {
+	if !t.config.Enabled || cost <= 0 {
+		return nil, nil
+	}
+
+	today := t.today()
+	day := t.ledger.Days[today]
+	if day == nil {
+		day = &daySpend{Providers: map[string]float64{}}
+		t.ledger.Days[today] = day
+	}
+	day.Total += cost
+	day.Providers[provider] += cost
+	if canvasPath != "" {
+		t.ledger.Canvases[canvasPath] += cost
+	}
+	t.pruneDays()
+
+	if err := writeJSON(filepath.Join(t.dir, ledgerFileName), t.ledger); err != nil {
+		return nil, fmt.Errorf("failed to save spending ledger: %w", err)
+	}
+
+	var warnings []Warning
+	ratio := t.config.WarnRatio
+	for _, budget := range t.budgets(provider, canvasPath) {
+		if !budget.Capped || ratio <= 0 || budget.Spent < budget.Limit*ratio {
+			continue
+		}
+		key := budget.Scope + "|" + budget.Key + "|" + today
+		if budget.Scope == ScopeCanvas {
+			key = budget.Scope + "|" + budget.Key
+		}
+		if t.warned[key] {
+			continue
+		}
+		t.warned[key] = true
+		warnings = append(warnings, Warning{Budget: budget, Ratio: ratio})
+	}
+	return warnings, nil
+}
+
+// Status reports today's spend against every cap, including the canvas cap when canvasPath is set
+func (t *Tracker) Status(canvasPath string) Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := Status{
+		Enabled:   t.config.Enabled,
+		Day:       t.today(),
+		Daily:     t.budget(ScopeDaily, "", t.config.DailyLimit),
+		Providers: map[string]Budget{},
+	}
+
+	providers := map[string]bool{}
+	for provider := range t.config.ProviderDailyLimits {
+		providers[provider] = true
+	}
+	if day := t.ledger.Days[status.Day]; day != nil {
+		for provider := range day.Providers {
+			providers[provider] = true
+		}
+	}
+	for provider := range providers {
+		status.Providers[provider] = t.budget(ScopeProvider, provider, t.config.ProviderDailyLimits[provider])
+	}
+
+	if canvasPath != "" {
+		canvas := t.budget(ScopeCanvas, canvasPath, t.config.CanvasLimit)
+		status.Canvas = &canvas
+	}
+	return status
+}
+
+// budgets returns the budgets that apply to a request
+func (t *Tracker) budgets(provider, canvasPath string) []Budget {
+	budgets := []Budget{
+		t.budget(ScopeDaily, "", t.config.DailyLimit),
+		t.budget(ScopeProvider, provider, t.config.ProviderDailyLimits[provider]),
+	}
+	if canvasPath != "" {
+		budgets = append(budgets, t.budget(ScopeCanvas, canvasPath, t.config.CanvasLimit))
+	}
+	return budgets
+}
+
+// budget computes spend against one cap
+func (t *Tracker) budget(scope, key string, limit float64) Budget {
+	var spent float64
+	day := t.ledger.Days[t.today()]
+	switch scope {
+	case ScopeDaily:
+		if day != nil {
+			spent = day.Total
+		}
+	case ScopeProvider:
+		if day != nil {
+			spent = day.Providers[key]
+		}
+	case ScopeCanvas:
+		spent = t.ledger.Canvases[key]
+	}
+
+	budget := Budget{Scope: scope, Key: key, Limit: limit, Spent: spent, Capped: limit > 0}
+	if budget.Capped {
+		budget.Remaining = limit - spent
+		if budget.Remaining < 0 {
+			budget.Remaining = 0
+		}
+	}
+	return budget
+}
+
+// today returns the local calendar day used to bucket spend
+func (t *Tracker) today() string {
+	return t.now().Format("2006-01-02")
+}
+
+// pruneDays drops per-day records older than the retention window
+func (t *Tracker) pruneDays() {
+	if len(t.ledger.Days) <= ledgerRetentionDays {
+		return
+	}
+	days := make([]string, 0, len(t.ledger.Days))
+	for day := range t.ledger.Days {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	for _, day := range days[:len(days)-ledgerRetentionDays] {
+		delete(t.ledger.Days, day)
+	}
+}
+
+// readJSON decodes path into value, leaving value untouched when the file doesn't exist
+func readJSON(path string, value interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// writeJSON encodes value to path. It writes a temporary file beside path,
+// flushes it to disk and renames it over path, so a crash mid-write leaves the
+// previous ledger rather than a truncated one.
+func writeJSON(path string, value interface{}) (err error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// Flush the rename too; not every platform can open a directory for syncing
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
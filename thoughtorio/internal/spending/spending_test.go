@@ -0,0 +1,185 @@
+package spending
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestTracker(t *testing.T, config Config) *Tracker {
+	t.Helper()
+	tracker, err := NewTracker(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+	if err := tracker.SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	return tracker
+}
+
+func TestReserveHoldsParallelCompletionsToTheCap(t *testing.T) {
+	tracker := newTestTracker(t, Config{Enabled: true, DailyLimit: 1})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var reserved []*Reservation
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := tracker.Reserve("openai", "", 0.3)
+			if err != nil {
+				if !errors.Is(err, ErrBudgetExceeded) {
+					t.Errorf("Reserve: %v", err)
+				}
+				return
+			}
+			mu.Lock()
+			reserved = append(reserved, reservation)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(reserved) != 3 {
+		t.Fatalf("got %d reservations, want 3 within a $1 cap", len(reserved))
+	}
+	for _, reservation := range reserved {
+		if _, err := tracker.Settle(reservation, 0.3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if spent := tracker.Status("").Daily.Spent; spent > 1 {
+		t.Errorf("spent $%.2f, over the $1 cap", spent)
+	}
+	if len(tracker.pending) != 0 {
+		t.Errorf("pending = %v after settling, want empty", tracker.pending)
+	}
+}
+
+func TestSettleRecordsActualCost(t *testing.T) {
+	tracker := newTestTracker(t, Config{Enabled: true, DailyLimit: 1, WarnRatio: 0.5})
+
+	reservation, err := tracker.Reserve("openai", "/canvas.json", 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracker.Reserve("openai", "", 0.2); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Reserve with $0.90 held = %v, want ErrBudgetExceeded", err)
+	}
+
+	warnings, err := tracker.Settle(reservation, 0.6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Scope != ScopeDaily {
+		t.Errorf("warnings = %+v, want one daily warning", warnings)
+	}
+
+	status := tracker.Status("/canvas.json")
+	if status.Daily.Spent != 0.6 || status.Providers["openai"].Spent != 0.6 || status.Canvas.Spent != 0.6 {
+		t.Errorf("status = %+v, want $0.60 spent everywhere", status)
+	}
+	if _, err := tracker.Reserve("openai", "", 0.2); err != nil {
+		t.Errorf("Reserve after settling: %v", err)
+	}
+}
+
+func TestReserveEnforcesEachScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     Config
+		provider   string
+		canvasPath string
+		wantScope  string
+	}{
+		{
+			name:      "daily",
+			config:    Config{Enabled: true, DailyLimit: 0.5},
+			provider:  "openai",
+			wantScope: ScopeDaily,
+		},
+		{
+			name:      "provider",
+			config:    Config{Enabled: true, ProviderDailyLimits: map[string]float64{"openai": 0.5}},
+			provider:  "openai",
+			wantScope: ScopeProvider,
+		},
+		{
+			name:       "canvas",
+			config:     Config{Enabled: true, CanvasLimit: 0.5},
+			provider:   "openai",
+			canvasPath: "/canvas.json",
+			wantScope:  ScopeCanvas,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newTestTracker(t, tt.config)
+			if !tracker.Capped(tt.provider, tt.canvasPath) {
+				t.Error("Capped = false, want true")
+			}
+
+			_, err := tracker.Reserve(tt.provider, tt.canvasPath, 0.6)
+			var exceeded *BudgetExceededError
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("Reserve = %v, want a BudgetExceededError", err)
+			}
+			if exceeded.Scope != tt.wantScope {
+				t.Errorf("scope = %q, want %q", exceeded.Scope, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestCapped(t *testing.T) {
+	tracker := newTestTracker(t, Config{Enabled: true, ProviderDailyLimits: map[string]float64{"openai": 1}})
+	if tracker.Capped("gemini", "/canvas.json") {
+		t.Error("Capped(gemini) = true with only an openai cap")
+	}
+	if !tracker.Capped("openai", "") {
+		t.Error("Capped(openai) = false")
+	}
+
+	tracker = newTestTracker(t, Config{Enabled: false, DailyLimit: 1})
+	if tracker.Capped("openai", "") {
+		t.Error("Capped = true while tracking is disabled")
+	}
+}
+
+func TestLedgerWritesReplaceTheFile(t *testing.T) {
+	tracker := newTestTracker(t, Config{Enabled: true, DailyLimit: 10})
+	for i := 0; i < 3; i++ {
+		if _, err := tracker.Record("openai", "", 0.5); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(tracker.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{configFileName, ledgerFileName}) {
+		t.Errorf("files = %v, want the config and ledger without temporary files", names)
+	}
+
+	// A new tracker reads back what was written
+	reopened, err := NewTracker(tracker.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.now = tracker.now
+	if spent := reopened.Status("").Daily.Spent; spent != 1.5 {
+		t.Errorf("spent $%.2f after reopening, want $1.50", spent)
+	}
+}
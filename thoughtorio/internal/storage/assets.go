@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"thoughtorio/internal/models"
+)
+
+// AssetDir is the directory assets are kept under in a canvas pack
+const AssetDir = "assets"
+
+// assetRefPattern matches asset references: "assets/" followed by the hex
+// SHA-256 of the file's contents and its lowercase extension, if any
+var assetRefPattern = regexp.MustCompile(`assets/[0-9a-f]{64}(?:\.[a-z0-9]{1,16})?`)
+
+// AssetRefs returns the distinct asset references found anywhere in canvas data, sorted
+func AssetRefs(canvasData []byte) []string {
+	seen := map[string]bool{}
+	refs := []string{}
+	for _, ref := range assetRefPattern.FindAll(canvasData, -1) {
+		if !seen[string(ref)] {
+			seen[string(ref)] = true
+			refs = append(refs, string(ref))
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// assetRef names an asset by the hash of its contents, keeping the extension
+// of the file it came from so viewers can tell what it is
+func assetRef(digest, name string) string {
+	return path.Join(AssetDir, digest+strings.ToLower(filepath.Ext(name)))
+}
+
+// validAssetRef reports whether ref is a whole asset reference and nothing else
+func validAssetRef(ref string) bool {
+	return assetRefPattern.FindString(ref) == ref
+}
+
+// refDigest returns the content hash an asset reference names
+func refDigest(ref string) string {
+	name := path.Base(ref)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// referencesFile is where an asset store records the assets each canvas refers to
+const referencesFile = "references.json"
+
+// assetGracePeriod is how long an asset no canvas refers to is kept. An asset
+// attached to a canvas that hasn't been saved yet has no reference until the
+// first save, so it is only collected once it is older than this.
+const assetGracePeriod = 24 * time.Hour
+
+// AssetStore keeps the assets of open canvases in a content-addressed
+// directory. Identical files share one entry whichever canvas they came from.
+//
+// Canvases register the assets they refer to when they are read or saved, and
+// Collect removes the assets none of them refer to. Canvases saved as plain
+// JSON refer to the store from wherever they live on disk, so a file that
+// hasn't been opened since references were first recorded can't protect its
+// assets; assets stored before then are only removed once a registered canvas
+// drops them.
+type AssetStore struct {
+	dir string
+	mu  sync.Mutex
+	// index is loaded from referencesFile on first use
+	index *assetIndex
+	// released holds assets a canvas dropped since the last collection
+	released map[string]bool
+}
+
+// assetIndex is the references file
+type assetIndex struct {
+	// Since is when references were first recorded, in milliseconds
+	Since int64 `json:"since"`
+	// Canvases maps each registered canvas path to the assets it refers to
+	Canvases map[string][]string `json:"canvases"`
+}
+
+// NewAssetStore creates an asset store in dir
+func NewAssetStore(dir string) (*AssetStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create asset directory: %w", err)
+	}
+	return &AssetStore{dir: dir, released: map[string]bool{}}, nil
+}
+
+// loadIndex returns the references index, reading it on first use. Callers hold s.mu.
+func (s *AssetStore) loadIndex() (*assetIndex, error) {
+	if s.index != nil {
+		return s.index, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, referencesFile))
+	if os.IsNotExist(err) {
+		s.index = &assetIndex{Since: time.Now().UnixMilli(), Canvases: map[string][]string{}}
+		return s.index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset references: %w", err)
+	}
+	var index assetIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("asset references are corrupt: %w", err)
+	}
+	if index.Canvases == nil {
+		index.Canvases = map[string][]string{}
+	}
+	s.index = &index
+	return s.index, nil
+}
+
+// SetReferences records the assets the canvas at canvasPath refers to,
+// replacing what it referred to before
+func (s *AssetStore) SetReferences(canvasPath string, refs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.loadIndex()
+	if err != nil {
+		return err
+	}
+	key := filepath.Clean(canvasPath)
+	kept := map[string]bool{}
+	for _, ref := range refs {
+		kept[ref] = true
+	}
+	for _, ref := range index.Canvases[key] {
+		if !kept[ref] {
+			s.released[ref] = true
+		}
+	}
+	if len(refs) == 0 {
+		delete(index.Canvases, key)
+	} else {
+		index.Canvases[key] = append([]string(nil), refs...)
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, referencesFile), data, 0644, nil); err != nil {
+		return fmt.Errorf("failed to write asset references: %w", err)
+	}
+	return nil
+}
+
+// Collect removes the assets no registered canvas refers to: those a canvas
+// dropped, and those stored since references were first recorded that are
+// older than the grace period. It returns the references removed.
+func (s *AssetStore) Collect() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	referenced := map[string]bool{}
+	for _, refs := range index.Canvases {
+		for _, ref := range refs {
+			referenced[ref] = true
+		}
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset directory: %w", err)
+	}
+	since := time.UnixMilli(index.Since)
+	cutoff := time.Now().Add(-assetGracePeriod)
+	removed := []string{}
+	for _, entry := range entries {
+		ref := path.Join(AssetDir, entry.Name())
+		if entry.IsDir() || !validAssetRef(ref) || referenced[ref] {
+			continue
+		}
+		if !s.released[ref] {
+			info, err := entry.Info()
+			if err != nil || info.ModTime().Before(since) || info.ModTime().After(cutoff) {
+				continue
+			}
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove asset %s: %w", ref, err)
+		}
+		removed = append(removed, ref)
+	}
+	s.released = map[string]bool{}
+	return removed, nil
+}
+
+// Path returns the file an asset is stored in
+func (s *AssetStore) Path(ref string) (string, error) {
+	if !validAssetRef(ref) {
+		return "", fmt.Errorf("invalid asset reference %q", ref)
+	}
+	return filepath.Join(s.dir, path.Base(ref)), nil
+}
+
+// Has reports whether an asset is stored
+func (s *AssetStore) Has(ref string) bool {
+	filePath, err := s.Path(ref)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filePath)
+	return err == nil
+}
+
+// Import copies a file into the store and returns its reference. Importing
+// the same contents again returns the same reference without a second copy.
+func (s *AssetStore) Import(sourcePath string) (models.CanvasAsset, error) {
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return models.CanvasAsset{}, fmt.Errorf("failed to read asset: %w", err)
+	}
+	asset, err := s.Put(filepath.Base(sourcePath), data)
+	if err != nil {
+		return models.CanvasAsset{}, err
+	}
+	asset.Name = filepath.Base(sourcePath)
+	return asset, nil
+}
+
+// Put stores asset contents under their hash; name supplies the extension
+func (s *AssetStore) Put(name string, data []byte) (models.CanvasAsset, error) {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	ref := assetRef(digest, name)
+	asset := models.CanvasAsset{Ref: ref, SHA256: digest, Size: int64(len(data)), MediaType: AssetMediaType(ref)}
+
+	filePath, err := s.Path(ref)
+	if err != nil {
+		return models.CanvasAsset{}, err
+	}
+
+	// Storing an asset again restarts its grace period, as it may be going
+	// into a canvas that hasn't been saved yet
+	s.mu.Lock()
+	delete(s.released, ref)
+	s.mu.Unlock()
+	if s.Has(ref) {
+		now := time.Now()
+		if err := os.Chtimes(filePath, now, now); err != nil {
+			return models.CanvasAsset{}, fmt.Errorf("failed to store asset: %w", err)
+		}
+		return asset, nil
+	}
+	if err := writeFileAtomic(filePath, data, 0644, nil); err != nil {
+		return models.CanvasAsset{}, fmt.Errorf("failed to store asset: %w", err)
+	}
+	return asset, nil
+}
+
+// Read returns a stored asset's contents
+func (s *AssetStore) Read(ref string) ([]byte, error) {
+	filePath, err := s.Path(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("asset %s is missing", ref)
+	}
+	return data, err
+}
+
+// Open opens a stored asset for reading
+func (s *AssetStore) Open(ref string) (io.ReadCloser, error) {
+	filePath, err := s.Path(ref)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("asset %s is missing", ref)
+	}
+	return f, err
+}
+
+// AssetMediaType guesses an asset's MIME type from its extension
+func AssetMediaType(ref string) string {
+	if t := mime.TypeByExtension(path.Ext(ref)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"thoughtorio/internal/canvas"
+	"thoughtorio/internal/models"
+)
+
+// putAsset stores contents in store and returns the asset
+func putAsset(t *testing.T, store *AssetStore, name, contents string) models.CanvasAsset {
+	t.Helper()
+	asset, err := store.Put(name, []byte(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return asset
+}
+
+// ageAsset sets an asset's modification time to age ago
+func ageAsset(t *testing.T, store *AssetStore, ref string, age time.Duration) {
+	t.Helper()
+	filePath, err := store.Path(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	then := time.Now().Add(-age)
+	if err := os.Chtimes(filePath, then, then); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSavingRemovesDroppedAssets(t *testing.T) {
+	store := newTestAssetStore(t)
+	cs := NewCanvasStorage(context.Background())
+	cs.SetBackupGenerations(0)
+	cs.SetAssetStore(store)
+	dir := t.TempDir()
+	plan, notes := filepath.Join(dir, "plan.thoughtorio"), filepath.Join(dir, "notes.thoughtorio")
+
+	photo := putAsset(t, store, "photo.png", "photo")
+	chart := putAsset(t, store, "chart.png", "chart")
+	shared := putAsset(t, store, "shared.txt", "shared")
+	if _, err := cs.WriteDocument(plan, historyDocument(photo.Ref+" "+chart.Ref+" "+shared.Ref)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.WriteDocument(notes, historyDocument(shared.Ref)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropping the chart and the shared file removes only the chart, which
+	// no other canvas refers to
+	if _, err := cs.WriteDocument(plan, historyDocument(photo.Ref)); err != nil {
+		t.Fatal(err)
+	}
+	if store.Has(chart.Ref) {
+		t.Error("dropped asset kept after saving")
+	}
+	if !store.Has(photo.Ref) || !store.Has(shared.Ref) {
+		t.Error("asset still referred to was removed")
+	}
+
+	// Another store on the directory knows what each canvas refers to
+	reopened, err := NewAssetStore(store.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ageAsset(t, reopened, photo.Ref, 2*assetGracePeriod)
+	if removed, err := reopened.Collect(); err != nil || len(removed) != 0 {
+		t.Errorf("Collect = %q, %v, want the referred assets kept", removed, err)
+	}
+
+	// Attaching a dropped asset again, as undo does, keeps it for the canvas
+	// that hasn't been saved with it yet
+	if _, err := cs.WriteDocument(notes, historyDocument("nothing attached")); err != nil {
+		t.Fatal(err)
+	}
+	if store.Has(shared.Ref) {
+		t.Fatal("asset dropped by the last canvas referring to it kept")
+	}
+	putAsset(t, store, "shared.txt", "shared")
+	if _, err := cs.WriteDocument(plan, historyDocument(photo.Ref)); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Has(shared.Ref) {
+		t.Error("asset stored again was removed before its canvas was saved")
+	}
+}
+
+func TestCollectKeepsUnsavedAndEarlierAssets(t *testing.T) {
+	store := newTestAssetStore(t)
+	earlier := putAsset(t, store, "earlier.png", "from before references were kept")
+	ageAsset(t, store, earlier.Ref, 30*24*time.Hour)
+
+	if err := store.SetReferences("/work/plan.thoughtorio", nil); err != nil {
+		t.Fatal(err)
+	}
+	store.index.Since = time.Now().Add(-7 * 24 * time.Hour).UnixMilli()
+
+	unsaved := putAsset(t, store, "unsaved.png", "attached a moment ago")
+	abandoned := putAsset(t, store, "abandoned.png", "attached and never saved")
+	ageAsset(t, store, abandoned.Ref, 2*assetGracePeriod)
+
+	// Opening a canvas saved elsewhere registers what it refers to
+	opened := putAsset(t, store, "opened.png", "attached to a canvas saved elsewhere")
+	ageAsset(t, store, opened.Ref, 2*assetGracePeriod)
+	data, err := canvas.MarshalCanonical(historyDocument(opened.Ref))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "shared.thoughtorio")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cs := NewCanvasStorage(context.Background())
+	cs.SetAssetStore(store)
+	if result := cs.LoadCanvasFromPath(path); !result.Success {
+		t.Fatalf("LoadCanvasFromPath failed: %s", result.Error)
+	}
+
+	removed, err := store.Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != abandoned.Ref {
+		t.Errorf("Collect removed %q, want only the abandoned asset", removed)
+	}
+	if !store.Has(earlier.Ref) || !store.Has(unsaved.Ref) || !store.Has(opened.Ref) {
+		t.Error("Collect removed an asset stored before references were kept, within the grace period or referred to")
+	}
+	// The references file itself is never taken for an asset
+	if _, err := os.Stat(filepath.Join(store.dir, referencesFile)); err != nil {
+		t.Errorf("references file = %v, want it kept", err)
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes data to a temporary file beside path, flushes it to
+// disk and renames it over path, so a crash or full disk leaves either the old
+// file or the new one and never a truncated mix. Before the rename, replace
+// (when not nil) is called while path still holds the previous contents.
+func writeFileAtomic(path string, data []byte, perm os.FileMode, replace func() error) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to flush temporary file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err = os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+
+	if replace != nil {
+		if err = replace(); err != nil {
+			return err
+		}
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+// fileMode returns the permissions of the file at path, or perm when there is
+// no file yet, so rewriting a file keeps the mode its owner gave it
+func fileMode(path string, perm os.FileMode) os.FileMode {
+	info, err := os.Stat(path)
+	if err != nil {
+		return perm
+	}
+	return info.Mode().Perm()
+}
+
+// syncDir flushes a directory entry change such as a rename. Not every platform
+// can open a directory for syncing, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// copyFile copies src to dst, flushing dst to disk. The copy keeps the
+// modification time of src, so it still shows when that version was written.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+
+	if err := writeFileAtomic(path, []byte("first"), 0600, nil); err != nil {
+		t.Fatal(err)
+	}
+	// replace sees the file as it was before the write
+	var before []byte
+	replace := func() error {
+		var err error
+		before, err = os.ReadFile(path)
+		return err
+	}
+	if err := writeFileAtomic(path, []byte("second"), 0600, replace); err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != "first" {
+		t.Errorf("replace saw %q, want the previous contents", before)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "second" {
+		t.Errorf("file = %q, %v, want the new contents", data, err)
+	}
+
+	// A failing replace leaves the file alone and no temporary file behind
+	failed := errors.New("replace failed")
+	if err := writeFileAtomic(path, []byte("third"), 0600, func() error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("writeFileAtomic = %v, want the replace error", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "second" {
+		t.Errorf("file after a failed write = %q, %v, want it unchanged", data, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want the file alone", len(entries))
+	}
+}
+
+func TestFileModeKeepsExistingMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permission bits to keep")
+	}
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+
+	if mode := fileMode(path, 0644); mode != 0644 {
+		t.Errorf("mode of a new file = %o, want the default", mode)
+	}
+	if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if mode := fileMode(path, 0644); mode != 0600 {
+		t.Errorf("mode of an existing file = %o, want 0600", mode)
+	}
+}
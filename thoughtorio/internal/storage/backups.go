@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"thoughtorio/internal/models"
+)
+
+// DefaultBackupGenerations is how many earlier versions of a canvas are kept beside it
+const DefaultBackupGenerations = 5
+
+// generationBackupPath names the backup a given number of saves back
+func generationBackupPath(canvasPath string, generation int) string {
+	return fmt.Sprintf("%s.%d.bak", canvasPath, generation)
+}
+
+// formatBackupPath names the copy of a canvas kept before upgrading it from an older format
+func formatBackupPath(canvasPath string, formatVersion int) string {
+	return fmt.Sprintf("%s.v%d.bak", canvasPath, formatVersion)
+}
+
+// rotateBackups shifts the existing backups of a canvas back one generation,
+// dropping the oldest and any beyond the count kept, and copies the file as it
+// is now into generation 1
+func rotateBackups(canvasPath string, generations int) error {
+	if generations <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(canvasPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	// Backups from when more generations were kept go too
+	existing, err := generationBackups(canvasPath)
+	if err != nil {
+		return err
+	}
+	for _, generation := range existing {
+		if generation < generations {
+			continue
+		}
+		if err := os.Remove(generationBackupPath(canvasPath, generation)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove oldest backup: %w", err)
+		}
+	}
+	for generation := generations - 1; generation >= 1; generation-- {
+		from := generationBackupPath(canvasPath, generation)
+		if _, err := os.Stat(from); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(from, generationBackupPath(canvasPath, generation+1)); err != nil {
+			return fmt.Errorf("failed to rotate backups: %w", err)
+		}
+	}
+
+	// Copy rather than hard-link, so an editor rewriting the canvas in place can't change its backup
+	if err := copyFile(canvasPath, generationBackupPath(canvasPath, 1)); err != nil {
+		return fmt.Errorf("failed to back up canvas: %w", err)
+	}
+	return nil
+}
+
+// generationBackups returns the generations of the backups kept beside a canvas
+func generationBackups(canvasPath string) ([]int, error) {
+	entries, err := os.ReadDir(filepath.Dir(canvasPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read canvas directory: %w", err)
+	}
+
+	prefix := filepath.Base(canvasPath) + "."
+	generations := []int{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")); err == nil && n > 0 {
+			generations = append(generations, n)
+		}
+	}
+	return generations, nil
+}
+
+// ListBackups returns the backups kept beside a canvas: save generations
+// newest first, then the originals of format upgrades
+func (cs *CanvasStorage) ListBackups(canvasPath string) ([]models.CanvasBackup, error) {
+	entries, err := os.ReadDir(filepath.Dir(canvasPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read canvas directory: %w", err)
+	}
+
+	prefix := filepath.Base(canvasPath) + "."
+	backups := []models.CanvasBackup{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+
+		backup := models.CanvasBackup{Path: filepath.Join(filepath.Dir(canvasPath), name)}
+		label := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")
+		if n, err := strconv.Atoi(label); err == nil && n > 0 {
+			backup.Kind, backup.Generation = models.BackupKindGeneration, n
+		} else if n, err := strconv.Atoi(strings.TrimPrefix(label, "v")); err == nil && strings.HasPrefix(label, "v") && n > 0 {
+			backup.Kind, backup.FormatVersion = models.BackupKindFormat, n
+		} else {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backup.Size = info.Size()
+		backup.ModifiedAt = info.ModTime().UnixMilli()
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].Kind != backups[j].Kind {
+			return backups[i].Kind == models.BackupKindGeneration
+		}
+		if backups[i].Kind == models.BackupKindGeneration {
+			return backups[i].Generation < backups[j].Generation
+		}
+		return backups[i].FormatVersion > backups[j].FormatVersion
+	})
+	return backups, nil
+}
+
+// RestoreBackup replaces a canvas with one of its backups. The backup is
+// validated (and upgraded if it predates the current format) first, and the
+// canvas being replaced becomes the newest backup, so a restore can be undone.
+func (cs *CanvasStorage) RestoreBackup(canvasPath, backupPath string) models.CanvasFileResult {
+	backups, err := cs.ListBackups(canvasPath)
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Path: canvasPath, Error: err.Error()}
+	}
+	known := false
+	for _, backup := range backups {
+		if backup.Path == filepath.Clean(backupPath) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return models.CanvasFileResult{Success: false, Path: canvasPath, Error: "Not a backup of this canvas"}
+	}
+
+	loaded, report, err := cs.readDocument(backupPath)
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Path: canvasPath, Error: err.Error(), Validation: &report}
+	}
+	note := fmt.Sprintf("Restored backup %s", filepath.Base(backupPath))
+	if _, err := cs.writeDocument(canvasPath, loaded.Document, note); err != nil {
+		return models.CanvasFileResult{Success: false, Path: canvasPath, Error: err.Error()}
+	}
+
+	return cs.LoadCanvasFromPath(canvasPath)
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"thoughtorio/internal/models"
+)
+
+// savedContents returns the content of each generation backup of path, newest first
+func savedContents(t *testing.T, cs *CanvasStorage, path string) []string {
+	t.Helper()
+	backups, err := cs.ListBackups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := []string{}
+	for _, backup := range backups {
+		if backup.Kind != models.BackupKindGeneration {
+			continue
+		}
+		doc, _, err := cs.readDocument(backup.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, doc.Document.Nodes[0].Content)
+	}
+	return contents
+}
+
+func TestSavesRotateBackups(t *testing.T) {
+	cs := NewCanvasStorage(context.Background())
+	cs.SetBackupGenerations(3)
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+
+	for i := 1; i <= 5; i++ {
+		if _, err := cs.WriteDocument(path, historyDocument(fmt.Sprintf("save %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Generation 1 is the save before the current one, and only three are kept
+	got := savedContents(t, cs, path)
+	want := []string{"save 4", "save 3", "save 2"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("backups = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(generationBackupPath(path, 4)); !os.IsNotExist(err) {
+		t.Errorf("generation 4 = %v, want it pruned", err)
+	}
+
+	// Lowering the count prunes the oldest on the next save
+	cs.SetBackupGenerations(1)
+	if _, err := cs.WriteDocument(path, historyDocument("save 6")); err != nil {
+		t.Fatal(err)
+	}
+	if got := savedContents(t, cs, path); fmt.Sprint(got) != fmt.Sprint([]string{"save 5"}) {
+		t.Errorf("backups after lowering the count = %q", got)
+	}
+
+	// None are kept when backups are off
+	cs.SetBackupGenerations(0)
+	if _, err := cs.WriteDocument(path, historyDocument("save 7")); err != nil {
+		t.Fatal(err)
+	}
+	if got := savedContents(t, cs, path); got[0] != "save 5" {
+		t.Errorf("newest backup with backups off = %q, want it untouched", got[0])
+	}
+}
+
+func TestSavesKeepFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permission bits to keep")
+	}
+	cs := NewCanvasStorage(context.Background())
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+
+	if _, err := cs.WriteDocument(path, historyDocument("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.WriteDocument(path, historyDocument("second")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("mode after saving = %o, want the 0600 it was given", mode)
+	}
+	// The backup is a copy of the file and so has its mode too
+	if info, err := os.Stat(generationBackupPath(path, 1)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("backup = %v, %v, want mode 0600", info, err)
+	}
+}
+
+func TestBackupGenerationsChangeDuringSaves(t *testing.T) {
+	cs := NewCanvasStorage(context.Background())
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			cs.SetBackupGenerations(i % 4)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if _, err := cs.WriteDocument(path, historyDocument(fmt.Sprintf("save %d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
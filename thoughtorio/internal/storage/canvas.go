@@ -2,30 +2,93 @@ package storage
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"sync"
 
+	"thoughtorio/internal/canvas"
 	"thoughtorio/internal/models"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // CanvasStorage handles canvas file operations
 type CanvasStorage struct {
-	ctx context.Context
+	ctx               context.Context
+	backupGenerations int
+	// backupMu guards backupGenerations, which settings change while saves run
+	backupMu sync.RWMutex
+	// assets holds the attachments of canvases saved as packs
+	assets *AssetStore
+	// history keeps a version of each canvas every time it is saved
+	history *HistoryStore
+	// views keeps each canvas's viewport, which canonical files leave out
+	views *ViewStore
 }
 
 // NewCanvasStorage creates a new canvas storage instance
 func NewCanvasStorage(ctx context.Context) *CanvasStorage {
 	return &CanvasStorage{
-		ctx: ctx,
+		ctx:               ctx,
+		backupGenerations: DefaultBackupGenerations,
 	}
 }
 
-// SaveCanvas opens a save dialog and saves the canvas data to the selected file
+// SetBackupGenerations sets how many earlier versions of a canvas each save keeps; zero keeps none
+func (cs *CanvasStorage) SetBackupGenerations(generations int) {
+	if generations < 0 {
+		generations = 0
+	}
+	cs.backupMu.Lock()
+	cs.backupGenerations = generations
+	cs.backupMu.Unlock()
+}
+
+// generations returns how many backups each save keeps
+func (cs *CanvasStorage) generations() int {
+	cs.backupMu.RLock()
+	defer cs.backupMu.RUnlock()
+	return cs.backupGenerations
+}
+
+// SetAssetStore sets where the assets of canvas packs are unpacked to and packed from
+func (cs *CanvasStorage) SetAssetStore(store *AssetStore) {
+	cs.assets = store
+}
+
+// SetHistoryStore sets where versions of saved canvases are kept
+func (cs *CanvasStorage) SetHistoryStore(history *HistoryStore) {
+	cs.history = history
+}
+
+// SetViewStore sets where the viewports of saved canvases are kept
+func (cs *CanvasStorage) SetViewStore(views *ViewStore) {
+	cs.views = views
+}
+
+// Assets returns the asset store, or nil when there is none
+func (cs *CanvasStorage) Assets() *AssetStore {
+	return cs.assets
+}
+
+// SaveCanvas validates the canvas data, opens a save dialog and saves the canvas
+// to the selected file. Canvases with validation errors aren't written.
 func (cs *CanvasStorage) SaveCanvas(canvasData string) models.CanvasFileResult {
-	// Open save file dialog
-	filePath, err := runtime.SaveFileDialog(cs.ctx, runtime.SaveDialogOptions{
+	return cs.SaveCanvasAs(canvasData, "")
+}
+
+// SaveCanvasAs is SaveCanvas with the dialog starting at suggestedPath, such as
+// the file the canvas was opened from
+func (cs *CanvasStorage) SaveCanvasAs(canvasData, suggestedPath string) models.CanvasFileResult {
+	doc, report := canvas.Check([]byte(canvasData))
+	if err := report.Err(); err != nil {
+		return models.CanvasFileResult{Success: false, Error: err.Error(), Validation: &report}
+	}
+
+	options := runtime.SaveDialogOptions{
 		Title:           "Save Canvas",
 		DefaultFilename: "canvas.thoughtorio",
 		Filters: []runtime.FileFilter{
@@ -33,12 +96,23 @@ func (cs *CanvasStorage) SaveCanvas(canvasData string) models.CanvasFileResult {
 				DisplayName: "Thoughtorio Canvas Files (*.thoughtorio)",
 				Pattern:     "*.thoughtorio",
 			},
+			{
+				DisplayName: "Thoughtorio Canvas Packs with Assets (*.thoughtorio-pack)",
+				Pattern:     "*" + PackExtension,
+			},
 			{
 				DisplayName: "JSON Files (*.json)",
 				Pattern:     "*.json",
 			},
 		},
-	})
+	}
+	if suggestedPath != "" {
+		options.DefaultDirectory = filepath.Dir(suggestedPath)
+		options.DefaultFilename = filepath.Base(suggestedPath)
+	}
+
+	// Open save file dialog
+	filePath, err := runtime.SaveFileDialog(cs.ctx, options)
 	
 	if err != nil {
 		return models.CanvasFileResult{Success: false, Error: fmt.Sprintf("Failed to open save dialog: %v", err)}
@@ -48,13 +122,41 @@ func (cs *CanvasStorage) SaveCanvas(canvasData string) models.CanvasFileResult {
 		return models.CanvasFileResult{Success: false, Error: "Save cancelled by user"}
 	}
 	
-	// Write canvas data to file
-	err = os.WriteFile(filePath, []byte(canvasData), 0644)
+	return cs.writeCanvas(filePath, doc, report)
+}
+
+// SaveCanvasToPath validates the canvas data and saves it to filePath without a dialog
+func (cs *CanvasStorage) SaveCanvasToPath(filePath, canvasData string) models.CanvasFileResult {
+	doc, report := canvas.Check([]byte(canvasData))
+	if err := report.Err(); err != nil {
+		return models.CanvasFileResult{Success: false, Path: filePath, Error: err.Error(), Validation: &report}
+	}
+	return cs.writeCanvas(filePath, doc, report)
+}
+
+// writeCanvas writes a validated canvas and reports the revision it saved
+func (cs *CanvasStorage) writeCanvas(filePath string, doc *canvas.Document, report canvas.Report) models.CanvasFileResult {
+	revision, err := cs.WriteDocument(filePath, doc)
 	if err != nil {
-		return models.CanvasFileResult{Success: false, Error: fmt.Sprintf("Failed to write file: %v", err)}
+		return models.CanvasFileResult{Success: false, Path: filePath, Error: err.Error(), Validation: &report}
 	}
-	
-	return models.CanvasFileResult{Success: true, Path: filePath}
+	return models.CanvasFileResult{Success: true, Path: filePath, Revision: revision, Validation: &report, Document: doc}
+}
+
+// FileRevision fingerprints a canvas file's contents, so a later save can tell
+// whether the file changed on disk in the meantime
+func FileRevision(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return revisionOf(data), nil
+}
+
+// revisionOf fingerprints file contents
+func revisionOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 // LoadCanvas opens a file dialog and loads canvas data from the selected file
@@ -64,8 +166,8 @@ func (cs *CanvasStorage) LoadCanvas() models.CanvasFileResult {
 		Title: "Load Canvas",
 		Filters: []runtime.FileFilter{
 			{
-				DisplayName: "Thoughtorio Canvas Files (*.thoughtorio)",
-				Pattern:     "*.thoughtorio",
+				DisplayName: "Thoughtorio Canvas Files (*.thoughtorio, *.thoughtorio-pack)",
+				Pattern:     "*.thoughtorio;*" + PackExtension,
 			},
 			{
 				DisplayName: "JSON Files (*.json)",
@@ -85,24 +187,180 @@ func (cs *CanvasStorage) LoadCanvas() models.CanvasFileResult {
 	return cs.LoadCanvasFromPath(filePath)
 }
 
-// LoadCanvasFromPath loads canvas data from a specific file path
+// LoadCanvasFromPath loads and validates canvas data from a specific file path.
+// Canvases with validation errors are refused; warnings are reported alongside the data.
 func (cs *CanvasStorage) LoadCanvasFromPath(filePath string) models.CanvasFileResult {
+	loaded, report, err := cs.ReadDocument(filePath)
+	if err != nil {
+		result := models.CanvasFileResult{Success: false, Path: filePath, Error: err.Error()}
+		if len(report.Errors) > 0 {
+			result.Validation = &report
+		}
+		return result
+	}
+
+	data, err := canvas.Marshal(loaded.Document)
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Path: filePath, Error: fmt.Sprintf("Failed to encode canvas: %v", err)}
+	}
+
+	return models.CanvasFileResult{
+		Success:    true,
+		Path:       filePath,
+		Data:       string(data),
+		Revision:   loaded.Revision,
+		Validation: &report,
+		Migration:  loaded.Migration,
+		Document:   loaded.Document,
+	}
+}
+
+// LoadedCanvas is a canvas read from disk
+type LoadedCanvas struct {
+	Document *canvas.Document
+	// Migration is set when the file was in an older format
+	Migration *canvas.Migration
+	// Revision fingerprints the file as read; see FileRevision
+	Revision string
+}
+
+// ReadDocument reads the canvas at path, upgrading older formats, and validates
+// it. When the canvas was upgraded the original file is first copied alongside
+// it. The report is returned even when validation fails so callers can show
+// what is wrong.
+func (cs *CanvasStorage) ReadDocument(filePath string) (*LoadedCanvas, canvas.Report, error) {
+	loaded, report, err := cs.readDocument(filePath)
+	if err != nil || loaded.Migration == nil {
+		return loaded, report, err
+	}
+
+	backupPath, err := backupOriginal(filePath, loaded.Migration.From)
+	if err != nil {
+		log.Printf("failed to back up %s before upgrading it: %v", filePath, err)
+	}
+	loaded.Migration.BackupPath = backupPath
+	return loaded, report, nil
+}
+
+// readDocument reads, upgrades and validates the canvas at path, registering
+// the assets it refers to, without touching any other file
+func (cs *CanvasStorage) readDocument(filePath string) (*LoadedCanvas, canvas.Report, error) {
 	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return models.CanvasFileResult{Success: false, Error: "File does not exist"}
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, canvas.Report{}, fmt.Errorf("File does not exist")
 	}
-	
+
 	// Read canvas data from file
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return models.CanvasFileResult{Success: false, Error: fmt.Sprintf("Failed to read file: %v", err)}
+		return nil, canvas.Report{}, fmt.Errorf("Failed to read file: %v", err)
 	}
-	
-	// Validate JSON
-	var temp interface{}
-	if err := json.Unmarshal(data, &temp); err != nil {
-		return models.CanvasFileResult{Success: false, Error: "Invalid canvas file format"}
+
+	// Packs are unpacked whatever the file is called, so their backups load too
+	canvasData := data
+	if isPackData(data) {
+		canvasData, _, err = decodePack(data, cs.assets)
+		if err != nil {
+			return nil, canvas.Report{}, err
+		}
 	}
-	
-	return models.CanvasFileResult{Success: true, Path: filePath, Data: string(data)}
-}
\ No newline at end of file
+	cs.registerAssets(filePath, canvasData)
+
+	doc, migration, report := canvas.Load(canvasData)
+	if err := report.Err(); err != nil {
+		return nil, report, err
+	}
+
+	// Canonical files leave out the save time and viewport
+	if doc.Modified == 0 && info != nil {
+		doc.Modified = info.ModTime().UnixMilli()
+	}
+	if cs.views != nil {
+		if viewport, err := cs.views.Viewport(filePath); err != nil {
+			log.Printf("failed to read the viewport of %s: %v", filePath, err)
+		} else if viewport != nil {
+			doc.Viewport = viewport
+		}
+	}
+	return &LoadedCanvas{Document: doc, Migration: migration, Revision: revisionOf(data)}, report, nil
+}
+
+// backupOriginal copies a canvas in an older format next to it, once per format
+// version, so upgrading it never loses the file as it was
+func backupOriginal(filePath string, formatVersion int) (string, error) {
+	backupPath := formatBackupPath(filePath, formatVersion)
+	if _, err := os.Stat(backupPath); err == nil {
+		return backupPath, nil
+	}
+	if err := copyFile(filePath, backupPath); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// WriteDocument writes a canvas to path in the current format, refreshing its
+// metadata counts. The write is atomic, and the file it replaces is kept as the
+// newest of the canvas's rotating backups. Paths ending in PackExtension are
+// written as a pack with the assets the canvas references. Each write is
+// recorded as a version in the canvas's history. It returns the revision written.
+func (cs *CanvasStorage) WriteDocument(filePath string, doc *canvas.Document) (string, error) {
+	return cs.writeDocument(filePath, doc, "")
+}
+
+// writeDocument is WriteDocument with a note on where the history version came from
+func (cs *CanvasStorage) writeDocument(filePath string, doc *canvas.Document, note string) (string, error) {
+	doc.FormatVersion = canvas.FormatVersion
+	doc.UpdateCounts()
+	// The canonical form keeps saves diffable and mergeable under version control
+	data, err := canvas.MarshalCanonical(doc)
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode canvas: %v", err)
+	}
+	canvasData := data
+	if IsPackPath(filePath) {
+		if data, err = encodePack(data, doc.FormatVersion, cs.assets); err != nil {
+			return "", fmt.Errorf("Failed to pack canvas: %v", err)
+		}
+	}
+
+	generations := cs.generations()
+	rotate := func() error { return rotateBackups(filePath, generations) }
+	if err := writeFileAtomic(filePath, data, fileMode(filePath, 0644), rotate); err != nil {
+		return "", fmt.Errorf("Failed to write file: %v", err)
+	}
+
+	// The file is saved either way; a lost viewport only recentres the canvas
+	// and a missed version only shortens its history. Versions have no viewport
+	// of their own, so restoring one keeps the last.
+	if cs.views != nil && doc.Viewport != nil {
+		if err := cs.views.SetViewport(filePath, doc.Viewport); err != nil {
+			log.Printf("failed to keep the viewport of %s: %v", filePath, err)
+		}
+	}
+	if cs.history != nil {
+		if _, _, err := cs.history.Record(filePath, doc, note); err != nil {
+			log.Printf("failed to record history of %s: %v", filePath, err)
+		}
+	}
+	// Assets this save dropped go now, unless another canvas still refers to them
+	if cs.registerAssets(filePath, canvasData) {
+		if _, err := cs.assets.Collect(); err != nil {
+			log.Printf("failed to remove unused assets: %v", err)
+		}
+	}
+	return revisionOf(data), nil
+}
+
+// registerAssets records the assets the canvas at filePath refers to,
+// reporting whether they were recorded
+func (cs *CanvasStorage) registerAssets(filePath string, canvasData []byte) bool {
+	if cs.assets == nil {
+		return false
+	}
+	if err := cs.assets.SetReferences(filePath, AssetRefs(canvasData)); err != nil {
+		log.Printf("failed to record the assets of %s: %v", filePath, err)
+		return false
+	}
+	return true
+}
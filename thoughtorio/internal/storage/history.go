@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"thoughtorio/internal/canvas"
+	"thoughtorio/internal/models"
+)
+
+// HistoryStore keeps a version of each canvas every time it is saved. Versions
+// are split into the document and each node and connection, stored as
+// content-addressed objects, so the parts a save leaves unchanged take no
+// extra space.
+type HistoryStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// snapshotTree lists the objects a version is assembled from
+type snapshotTree struct {
+	// Document is the canvas without its nodes and connections
+	Document    string   `json:"document"`
+	Nodes       []string `json:"nodes"`
+	Connections []string `json:"connections"`
+}
+
+// historyIndex is the list of versions kept for one canvas
+type historyIndex struct {
+	Path     string                 `json:"path"`
+	Versions []models.CanvasVersion `json:"versions"`
+}
+
+// NewHistoryStore creates a history store in dir
+func NewHistoryStore(dir string) (*HistoryStore, error) {
+	for _, sub := range []string{"objects", "canvases"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create history directory: %w", err)
+		}
+	}
+	return &HistoryStore{dir: dir}, nil
+}
+
+// indexPath returns the file a canvas's versions are listed in, keyed by its path
+func (h *HistoryStore) indexPath(canvasPath string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(canvasPath)))
+	return filepath.Join(h.dir, "canvases", hex.EncodeToString(sum[:])[:16]+".json")
+}
+
+// Record adds the canvas as saved to canvasPath as its newest version, unless
+// it is the same as the newest version already. It reports whether a version was added.
+func (h *HistoryStore) Record(canvasPath string, doc *canvas.Document, note string) (models.CanvasVersion, bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Versions hold what the file holds, without run state or list order churn
+	doc, err := canvas.Canonical(doc)
+	if err != nil {
+		return models.CanvasVersion{}, false, err
+	}
+	tree, err := h.putTree(doc)
+	if err != nil {
+		return models.CanvasVersion{}, false, err
+	}
+	treeData, err := json.Marshal(tree)
+	if err != nil {
+		return models.CanvasVersion{}, false, err
+	}
+	snapshot, err := h.putObject(treeData)
+	if err != nil {
+		return models.CanvasVersion{}, false, err
+	}
+
+	index, err := h.readIndex(canvasPath)
+	if err != nil {
+		return models.CanvasVersion{}, false, err
+	}
+	var previous *canvas.Document
+	if n := len(index.Versions); n > 0 {
+		latest := index.Versions[n-1]
+		if latest.Snapshot == snapshot {
+			return latest, false, nil
+		}
+		if previous, err = h.document(latest.Snapshot); err != nil {
+			previous = nil
+		}
+	}
+
+	version := models.CanvasVersion{
+		Number:          len(index.Versions) + 1,
+		SavedAt:         time.Now().UnixMilli(),
+		Snapshot:        snapshot,
+		NodeCount:       len(doc.Nodes),
+		ConnectionCount: len(doc.Connections),
+		Changes:         canvas.Compare(previous, doc).Summary(),
+		Note:            note,
+	}
+	index.Path = canvasPath
+	index.Versions = append(index.Versions, version)
+	if err := h.writeIndex(canvasPath, index); err != nil {
+		return models.CanvasVersion{}, false, err
+	}
+	return version, true, nil
+}
+
+// Versions returns the versions kept for a canvas, newest first
+func (h *HistoryStore) Versions(canvasPath string) ([]models.CanvasVersion, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	index, err := h.readIndex(canvasPath)
+	if err != nil {
+		return nil, err
+	}
+	versions := append([]models.CanvasVersion{}, index.Versions...)
+	sort.Slice(versions, func(a, b int) bool { return versions[a].Number > versions[b].Number })
+	return versions, nil
+}
+
+// Document assembles one version of a canvas. Versions saved in an older
+// format are upgraded as if loaded from a file. Versions don't keep the
+// creation time, save time or viewport.
+func (h *HistoryStore) Document(canvasPath string, number int) (*canvas.Document, models.CanvasVersion, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	index, err := h.readIndex(canvasPath)
+	if err != nil {
+		return nil, models.CanvasVersion{}, err
+	}
+	for _, version := range index.Versions {
+		if version.Number == number {
+			doc, err := h.document(version.Snapshot)
+			return doc, version, err
+		}
+	}
+	return nil, models.CanvasVersion{}, fmt.Errorf("version %d of this canvas not found", number)
+}
+
+// putTree stores the parts of a document and returns the tree listing them
+func (h *HistoryStore) putTree(doc *canvas.Document) (snapshotTree, error) {
+	tree := snapshotTree{Nodes: []string{}, Connections: []string{}}
+	for _, node := range doc.Nodes {
+		id, err := h.putJSON(node)
+		if err != nil {
+			return tree, err
+		}
+		tree.Nodes = append(tree.Nodes, id)
+	}
+	for _, conn := range doc.Connections {
+		id, err := h.putJSON(conn)
+		if err != nil {
+			return tree, err
+		}
+		tree.Connections = append(tree.Connections, id)
+	}
+
+	rest := *doc
+	rest.Nodes, rest.Connections = []canvas.Node{}, []canvas.Connection{}
+	// Counts and the creation time would otherwise make versions' documents
+	// differ; canonical documents have no save time
+	rest.Created = 0
+	rest.Metadata.NodeCount, rest.Metadata.ConnectionCount = 0, 0
+	id, err := h.putJSON(rest)
+	if err != nil {
+		return tree, err
+	}
+	tree.Document = id
+	return tree, nil
+}
+
+// document assembles the document a tree object lists
+func (h *HistoryStore) document(snapshot string) (*canvas.Document, error) {
+	var tree snapshotTree
+	if err := h.getJSON(snapshot, &tree); err != nil {
+		return nil, err
+	}
+	doc := &canvas.Document{}
+	if err := h.getJSON(tree.Document, doc); err != nil {
+		return nil, err
+	}
+	doc.Nodes = make([]canvas.Node, len(tree.Nodes))
+	for i, id := range tree.Nodes {
+		if err := h.getJSON(id, &doc.Nodes[i]); err != nil {
+			return nil, err
+		}
+	}
+	doc.Connections = make([]canvas.Connection, len(tree.Connections))
+	for i, id := range tree.Connections {
+		if err := h.getJSON(id, &doc.Connections[i]); err != nil {
+			return nil, err
+		}
+	}
+	doc.UpdateCounts()
+
+	data, err := canvas.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	loaded, _, report := canvas.Load(data)
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+func (h *HistoryStore) putJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return h.putObject(data)
+}
+
+func (h *HistoryStore) getJSON(id string, v interface{}) error {
+	data, err := h.getObject(id)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// objectPath returns the file an object is kept in, fanned out by the first two hex digits
+func (h *HistoryStore) objectPath(id string) (string, error) {
+	if len(id) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid history object %q", id)
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", fmt.Errorf("invalid history object %q", id)
+	}
+	return filepath.Join(h.dir, "objects", id[:2], id[2:]), nil
+}
+
+// putObject stores data compressed under its hash, once however often it is stored
+func (h *HistoryStore) putObject(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])
+	objectPath, err := h.objectPath(id)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(objectPath); err == nil {
+		return id, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0700); err != nil {
+		return "", fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := writeFileAtomic(objectPath, buf.Bytes(), 0600, nil); err != nil {
+		return "", fmt.Errorf("failed to write history object: %w", err)
+	}
+	return id, nil
+}
+
+func (h *HistoryStore) getObject(id string) ([]byte, error) {
+	objectPath, err := h.objectPath(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(objectPath)
+	if err != nil {
+		return nil, fmt.Errorf("history object %s is missing", id[:12])
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("history object %s is corrupt: %w", id[:12], err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("history object %s is corrupt: %w", id[:12], err)
+	}
+	return data, nil
+}
+
+func (h *HistoryStore) readIndex(canvasPath string) (historyIndex, error) {
+	data, err := os.ReadFile(h.indexPath(canvasPath))
+	if os.IsNotExist(err) {
+		return historyIndex{Path: canvasPath, Versions: []models.CanvasVersion{}}, nil
+	}
+	if err != nil {
+		return historyIndex{}, fmt.Errorf("failed to read canvas history: %w", err)
+	}
+	var index historyIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return historyIndex{}, fmt.Errorf("canvas history is corrupt: %w", err)
+	}
+	return index, nil
+}
+
+func (h *HistoryStore) writeIndex(canvasPath string, index historyIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(h.indexPath(canvasPath), data, 0600, nil); err != nil {
+		return fmt.Errorf("failed to write canvas history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"thoughtorio/internal/canvas"
+)
+
+func newTestHistoryStore(t *testing.T) *HistoryStore {
+	t.Helper()
+	history, err := NewHistoryStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+func historyDocument(content string) *canvas.Document {
+	return &canvas.Document{
+		FormatVersion: canvas.FormatVersion,
+		Version:       "1.0",
+		Created:       1000,
+		Modified:      2000,
+		Nodes: []canvas.Node{
+			{ID: "node-1", Type: canvas.NodeTypeStatic, Width: 200, Height: 120, Content: content},
+		},
+	}
+}
+
+func TestHistoryRecordsVersions(t *testing.T) {
+	history := newTestHistoryStore(t)
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+
+	first, added, err := history.Record(path, historyDocument("first"), "")
+	if err != nil || !added {
+		t.Fatalf("Record = %+v, %v, %v, want a new version", first, added, err)
+	}
+	if first.Number != 1 || first.NodeCount != 1 || first.Changes.NodesAdded != 1 {
+		t.Errorf("first version = %+v, want number 1 adding one node", first)
+	}
+
+	second, added, err := history.Record(path, historyDocument("second"), "Restored version 1")
+	if err != nil || !added {
+		t.Fatalf("Record = %+v, %v, %v, want a new version", second, added, err)
+	}
+	if second.Number != 2 || second.Changes.NodesChanged != 1 || second.Note != "Restored version 1" {
+		t.Errorf("second version = %+v, want number 2 changing one node with the note", second)
+	}
+
+	versions, err := history.Versions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Number != 2 || versions[1].Number != 1 {
+		t.Fatalf("Versions = %+v, want 2 then 1", versions)
+	}
+
+	doc, version, err := history.Document(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.Snapshot != first.Snapshot || len(doc.Nodes) != 1 || doc.Nodes[0].Content != "first" {
+		t.Errorf("Document(1) = %+v, %+v, want the first content", doc, version)
+	}
+	if doc.Metadata.NodeCount != 1 {
+		t.Errorf("NodeCount = %d, want counts restored", doc.Metadata.NodeCount)
+	}
+	if _, _, err := history.Document(path, 3); err == nil {
+		t.Error("Document(3) succeeded for a version that doesn't exist")
+	}
+}
+
+func TestHistorySkipsUnchangedSaves(t *testing.T) {
+	history := newTestHistoryStore(t)
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+
+	first, _, err := history.Record(path, historyDocument("same"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving again stamps new times and may list nodes in another order
+	again := historyDocument("same")
+	again.Created, again.Modified = 5000, 6000
+	again.Nodes = append(again.Nodes, canvas.Node{ID: "node-0", Type: canvas.NodeTypeStatic})
+	reordered := historyDocument("same")
+	reordered.Created, reordered.Modified = 7000, 8000
+	reordered.Nodes = append([]canvas.Node{{ID: "node-0", Type: canvas.NodeTypeStatic}}, reordered.Nodes...)
+
+	if _, added, err := history.Record(path, again, ""); err != nil || !added {
+		t.Fatalf("Record with a new node = %v, %v, want a new version", added, err)
+	}
+	latest, added, err := history.Record(path, reordered, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added || latest.Number != 2 {
+		t.Errorf("Record of an unchanged canvas = %+v, %v, want version 2 returned without adding one", latest, added)
+	}
+
+	versions, err := history.Versions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[1].Snapshot != first.Snapshot {
+		t.Errorf("Versions = %+v, want the first and the one with a new node", versions)
+	}
+}
+
+func TestHistoryKeepsCanvasesApart(t *testing.T) {
+	history := newTestHistoryStore(t)
+	dir := t.TempDir()
+	one, two := filepath.Join(dir, "one.thoughtorio"), filepath.Join(dir, "two.thoughtorio")
+
+	if _, _, err := history.Record(one, historyDocument("one"), ""); err != nil {
+		t.Fatal(err)
+	}
+	// The same content under another path starts that path's own history
+	version, added, err := history.Record(two, historyDocument("one"), "")
+	if err != nil || !added || version.Number != 1 {
+		t.Fatalf("Record = %+v, %v, %v, want version 1 of the second canvas", version, added, err)
+	}
+
+	versions, err := history.Versions(filepath.Join(dir, "sub", "..", "one.thoughtorio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 {
+		t.Errorf("Versions of an equivalent path = %+v, want the one version", versions)
+	}
+	if versions, err := history.Versions(filepath.Join(dir, "none.thoughtorio")); err != nil || len(versions) != 0 {
+		t.Errorf("Versions of an unknown canvas = %+v, %v, want none", versions, err)
+	}
+}
+
+func TestRestoreVersionKeepsTheFileInHistory(t *testing.T) {
+	cs := NewCanvasStorage(context.Background())
+	cs.SetBackupGenerations(0)
+	cs.SetHistoryStore(newTestHistoryStore(t))
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+
+	if _, err := cs.WriteDocument(path, historyDocument("first")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.WriteDocument(path, historyDocument("second")); err != nil {
+		t.Fatal(err)
+	}
+	// An edit made outside the app never went through a save
+	edited := historyDocument("edited elsewhere")
+	data, err := canvas.MarshalCanonical(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := cs.RestoreVersion(path, 1)
+	if !result.Success {
+		t.Fatalf("RestoreVersion failed: %s", result.Error)
+	}
+	if result.Document.Nodes[0].Content != "first" || result.Document.Created != 1000 {
+		t.Errorf("restored = %+v, want the first content created when the file was", result.Document)
+	}
+
+	versions, err := cs.ListVersions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := []string{}
+	for _, version := range versions {
+		notes = append(notes, version.Note)
+	}
+	want := []string{"Restored version 1", "Before restoring version 1", "", ""}
+	if !reflect.DeepEqual(notes, want) {
+		t.Fatalf("version notes = %q, want %q", notes, want)
+	}
+	doc, _, err := cs.history.Document(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Nodes[0].Content != "edited elsewhere" {
+		t.Errorf("version 3 content = %q, want the file as it was before the restore", doc.Nodes[0].Content)
+	}
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"thoughtorio/internal/models"
+)
+
+// PackExtension marks canvases saved as a pack: a zip holding the canvas, its
+// assets and a manifest, so attachments travel with the canvas
+const PackExtension = ".thoughtorio-pack"
+
+// PackVersion is the layout of the packs written by this build
+const PackVersion = 1
+
+// Entries every pack holds besides its assets
+const (
+	packManifestName = "manifest.json"
+	packCanvasName   = "canvas.json"
+)
+
+// maxPackEntrySize bounds how much any one pack entry may expand to when read
+const maxPackEntrySize = 1 << 30
+
+// packModified is the timestamp given to every pack entry, so saving the same
+// canvas and assets always produces the same file
+var packModified = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// PackManifest describes the contents of a pack
+type PackManifest struct {
+	PackVersion int `json:"packVersion"`
+	// FormatVersion is the format of the canvas inside
+	FormatVersion int                  `json:"formatVersion"`
+	Canvas        string               `json:"canvas"`
+	Assets        []models.CanvasAsset `json:"assets"`
+}
+
+// IsPackPath reports whether a canvas saved to path is written as a pack
+func IsPackPath(filePath string) bool {
+	return strings.EqualFold(path.Ext(filePath), PackExtension)
+}
+
+// isPackData reports whether file contents are a pack rather than canvas JSON;
+// packs are recognised by content so their backups read like the packs themselves
+func isPackData(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// encodePack bundles canvas data with the assets it references, read from
+// store. Assets the canvas no longer references are left out, and each asset
+// is stored once however many times it is referenced.
+func encodePack(canvasData []byte, formatVersion int, store *AssetStore) ([]byte, error) {
+	refs := AssetRefs(canvasData)
+	if len(refs) > 0 && store == nil {
+		return nil, fmt.Errorf("asset storage not available")
+	}
+
+	manifest := PackManifest{PackVersion: PackVersion, FormatVersion: formatVersion, Canvas: packCanvasName, Assets: []models.CanvasAsset{}}
+	assets := make([][]byte, len(refs))
+	for i, ref := range refs {
+		data, err := store.Read(ref)
+		if err != nil {
+			return nil, err
+		}
+		assets[i] = data
+		manifest.Assets = append(manifest.Assets, models.CanvasAsset{
+			Ref:       ref,
+			MediaType: AssetMediaType(ref),
+			Size:      int64(len(data)),
+			SHA256:    refDigest(ref),
+		})
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := writePackEntry(zw, packManifestName, manifestData, zip.Deflate); err != nil {
+		return nil, err
+	}
+	if err := writePackEntry(zw, packCanvasName, canvasData, zip.Deflate); err != nil {
+		return nil, err
+	}
+	for i, ref := range refs {
+		// Images and PDFs are mostly compressed already
+		if err := writePackEntry(zw, ref, assets[i], zip.Store); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish pack: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writePackEntry(zw *zip.Writer, name string, data []byte, method uint16) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: packModified})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to pack: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s to pack: %w", name, err)
+	}
+	return nil
+}
+
+// decodePack returns the canvas data in a pack, adding its assets to store.
+// Assets are checked against their hashes; with a nil store they are skipped.
+func decodePack(data []byte, store *AssetStore) ([]byte, *PackManifest, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("canvas pack isn't a valid zip file: %w", err)
+	}
+
+	entries := map[string]*zip.File{}
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	manifestFile := entries[packManifestName]
+	if manifestFile == nil {
+		return nil, nil, fmt.Errorf("canvas pack has no %s", packManifestName)
+	}
+	manifestData, err := readPackEntry(manifestFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	var manifest PackManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("canvas pack manifest isn't valid JSON: %w", err)
+	}
+	if manifest.PackVersion > PackVersion {
+		return nil, nil, fmt.Errorf("canvas pack version %d is newer than this version of Thoughtorio supports", manifest.PackVersion)
+	}
+	if manifest.Canvas == "" {
+		manifest.Canvas = packCanvasName
+	}
+
+	canvasFile := entries[manifest.Canvas]
+	if canvasFile == nil {
+		return nil, nil, fmt.Errorf("canvas pack has no %s", manifest.Canvas)
+	}
+	canvasData, err := readPackEntry(canvasFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if store == nil {
+		return canvasData, &manifest, nil
+	}
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, AssetDir+"/") {
+			continue
+		}
+		if !validAssetRef(f.Name) {
+			log.Printf("skipping unexpected pack entry %s", f.Name)
+			continue
+		}
+		if store.Has(f.Name) {
+			continue
+		}
+		asset, err := readPackEntry(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		sum := sha256.Sum256(asset)
+		if hex.EncodeToString(sum[:]) != refDigest(f.Name) {
+			return nil, nil, fmt.Errorf("canvas pack asset %s is corrupt", f.Name)
+		}
+		if _, err := store.Put(path.Base(f.Name), asset); err != nil {
+			return nil, nil, err
+		}
+	}
+	return canvasData, &manifest, nil
+}
+
+func readPackEntry(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxPackEntrySize {
+		return nil, fmt.Errorf("canvas pack entry %s is too large", f.Name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from pack: %w", f.Name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, maxPackEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from pack: %w", f.Name, err)
+	}
+	if len(data) > maxPackEntrySize {
+		return nil, fmt.Errorf("canvas pack entry %s is too large", f.Name)
+	}
+	return data, nil
+}
+
+// ReadCanvasFile returns the canvas data of a saved canvas, taking it out of
+// the pack when the canvas was saved as one. Assets aren't touched.
+func ReadCanvasFile(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !isPackData(data) {
+		return data, nil
+	}
+	canvasData, _, err := decodePack(data, nil)
+	return canvasData, err
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestAssetStore(t *testing.T) *AssetStore {
+	t.Helper()
+	store, err := NewAssetStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestPackRoundTrip(t *testing.T) {
+	source := newTestAssetStore(t)
+	image, err := source.Put("photo.PNG", []byte("not really a png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, err := source.Put("notes.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unused, err := source.Put("unused.txt", []byte("left behind"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canvasData := []byte(fmt.Sprintf(`{"formatVersion":2,"nodes":[{"id":"a","content":"%s and %s"},{"id":"b","content":"%s again"}]}`, image.Ref, notes.Ref, image.Ref))
+	packed, err := encodePack(canvasData, 2, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isPackData(packed) {
+		t.Fatal("encoded pack isn't recognised as a pack")
+	}
+
+	again, err := encodePack(canvasData, 2, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed, again) {
+		t.Error("encoding the same canvas twice produced different packs")
+	}
+
+	target := newTestAssetStore(t)
+	decoded, manifest, err := decodePack(packed, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, canvasData) {
+		t.Errorf("canvas = %s, want %s", decoded, canvasData)
+	}
+	if manifest.PackVersion != PackVersion || manifest.FormatVersion != 2 || manifest.Canvas != packCanvasName {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if len(manifest.Assets) != 2 {
+		t.Fatalf("manifest lists %d assets, want the 2 referenced ones once each", len(manifest.Assets))
+	}
+
+	for _, asset := range []string{image.Ref, notes.Ref} {
+		want, _ := source.Read(asset)
+		got, err := target.Read(asset)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("unpacked %s = %q, %v; want %q", asset, got, err, want)
+		}
+	}
+	if target.Has(unused.Ref) {
+		t.Error("an unreferenced asset was packed")
+	}
+}
+
+func TestDecodePackWithoutStore(t *testing.T) {
+	source := newTestAssetStore(t)
+	asset, err := source.Put("a.txt", []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	canvasData := []byte(`{"content":"` + asset.Ref + `"}`)
+	packed, err := encodePack(canvasData, 2, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, _, err := decodePack(packed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, canvasData) {
+		t.Errorf("canvas = %s, want %s", decoded, canvasData)
+	}
+}
+
+func TestEncodePackMissingAsset(t *testing.T) {
+	sum := sha256.Sum256([]byte("never stored"))
+	canvasData := []byte(`{"content":"assets/` + hex.EncodeToString(sum[:]) + `.txt"}`)
+
+	if _, err := encodePack(canvasData, 2, newTestAssetStore(t)); err == nil {
+		t.Error("encodePack succeeded with a missing asset")
+	}
+	if _, err := encodePack(canvasData, 2, nil); err == nil {
+		t.Error("encodePack succeeded without an asset store")
+	}
+}
+
+// buildPack writes a pack by hand so tests can break it in ways encodePack won't
+func buildPack(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{packManifestName, packCanvasName} {
+		if data, ok := entries[name]; ok {
+			if err := writePackEntry(zw, name, []byte(data), zip.Deflate); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for name, data := range entries {
+		if name == packManifestName || name == packCanvasName {
+			continue
+		}
+		if err := writePackEntry(zw, name, []byte(data), zip.Store); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodePackRejectsHashMismatch(t *testing.T) {
+	sum := sha256.Sum256([]byte("original"))
+	ref := "assets/" + hex.EncodeToString(sum[:]) + ".txt"
+	packed := buildPack(t, map[string]string{
+		packManifestName: `{"packVersion":1,"formatVersion":2,"canvas":"canvas.json","assets":[]}`,
+		packCanvasName:   `{"content":"` + ref + `"}`,
+		ref:              "tampered",
+	})
+
+	store := newTestAssetStore(t)
+	_, _, err := decodePack(packed, store)
+	if err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Fatalf("decodePack = %v, want a corrupt asset error", err)
+	}
+	if store.Has(ref) {
+		t.Error("a corrupt asset was stored")
+	}
+}
+
+func TestDecodePackErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{
+			name:    "not a zip",
+			data:    []byte("{}"),
+			wantErr: "valid zip",
+		},
+		{
+			name:    "no manifest",
+			data:    buildPack(t, map[string]string{packCanvasName: "{}"}),
+			wantErr: "no manifest.json",
+		},
+		{
+			name:    "newer pack version",
+			data:    buildPack(t, map[string]string{packManifestName: `{"packVersion":99}`, packCanvasName: "{}"}),
+			wantErr: "newer",
+		},
+		{
+			name:    "no canvas",
+			data:    buildPack(t, map[string]string{packManifestName: `{"packVersion":1}`}),
+			wantErr: "no canvas.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodePack(tt.data, newTestAssetStore(t))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decodePack = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"thoughtorio/internal/models"
+)
+
+// presetExportVersion is written to exported preset files so future formats can be told apart
+const presetExportVersion = 1
+
+// presetNamePattern allows names like "fast-summarizer" or "deep_reasoner 2"
+var presetNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.-]{0,63}$`)
+
+// PresetStorage handles named model presets
+type PresetStorage struct {
+	configDir string
+	mu        sync.Mutex
+}
+
+// presetExport is the document written by ExportPresets
+type presetExport struct {
+	Version int                  `json:"version"`
+	Presets []models.ModelPreset `json:"presets"`
+}
+
+// NewPresetStorage creates a new preset storage instance
+func NewPresetStorage() (*PresetStorage, error) {
+	thoughtorioDir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewPresetStorageIn(thoughtorioDir)
+}
+
+// NewPresetStorageIn creates a preset storage instance keeping presets.json in dir
+func NewPresetStorageIn(thoughtorioDir string) (*PresetStorage, error) {
+	if err := os.MkdirAll(thoughtorioDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	return &PresetStorage{
+		configDir: thoughtorioDir,
+	}, nil
+}
+
+// ValidatePreset checks the fields every preset needs
+func ValidatePreset(preset models.ModelPreset) error {
+	if !presetNamePattern.MatchString(preset.Name) {
+		return fmt.Errorf("preset name %q must be 1-64 letters, digits, spaces, '.', '-' or '_'", preset.Name)
+	}
+	if strings.TrimSpace(preset.Provider) == "" {
+		return fmt.Errorf("preset %q needs a provider", preset.Name)
+	}
+	if strings.TrimSpace(preset.Model) == "" {
+		return fmt.Errorf("preset %q needs a model", preset.Name)
+	}
+	return nil
+}
+
+// ListPresets returns all presets sorted by name
+func (ps *PresetStorage) ListPresets() ([]models.ModelPreset, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.loadPresets()
+}
+
+// GetPreset returns the preset with the given name
+func (ps *PresetStorage) GetPreset(name string) (models.ModelPreset, error) {
+	presets, err := ps.ListPresets()
+	if err != nil {
+		return models.ModelPreset{}, err
+	}
+	for _, preset := range presets {
+		if preset.Name == name {
+			return preset, nil
+		}
+	}
+	return models.ModelPreset{}, fmt.Errorf("preset %q not found", name)
+}
+
+// SavePreset creates a preset or replaces the one with the same name
+func (ps *PresetStorage) SavePreset(preset models.ModelPreset) error {
+	if err := ValidatePreset(preset); err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	presets, err := ps.loadPresets()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	preset.UpdatedAt = now
+	preset.CreatedAt = now
+	for i, existing := range presets {
+		if existing.Name == preset.Name {
+			preset.CreatedAt = existing.CreatedAt
+			presets = append(presets[:i], presets[i+1:]...)
+			break
+		}
+	}
+
+	return ps.savePresets(append(presets, preset))
+}
+
+// DeletePreset removes the preset with the given name
+func (ps *PresetStorage) DeletePreset(name string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	presets, err := ps.loadPresets()
+	if err != nil {
+		return err
+	}
+
+	for i, preset := range presets {
+		if preset.Name == name {
+			return ps.savePresets(append(presets[:i], presets[i+1:]...))
+		}
+	}
+	return fmt.Errorf("preset %q not found", name)
+}
+
+// ExportPresets returns the named presets, or all presets when names is empty, as a JSON document
+func (ps *PresetStorage) ExportPresets(names []string) ([]byte, error) {
+	presets, err := ps.ListPresets()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) > 0 {
+		wanted := make(map[string]bool, len(names))
+		for _, name := range names {
+			wanted[name] = true
+		}
+		selected := []models.ModelPreset{}
+		for _, preset := range presets {
+			if wanted[preset.Name] {
+				selected = append(selected, preset)
+				delete(wanted, preset.Name)
+			}
+		}
+		for name := range wanted {
+			return nil, fmt.Errorf("preset %q not found", name)
+		}
+		presets = selected
+	}
+
+	return json.MarshalIndent(presetExport{Version: presetExportVersion, Presets: presets}, "", "  ")
+}
+
+// ImportPresets adds presets from an exported document (or a bare JSON array of
+// presets). Presets whose names already exist are skipped unless overwrite is
+// set. It returns the names that were imported.
+func (ps *PresetStorage) ImportPresets(data []byte, overwrite bool) ([]string, error) {
+	var incoming []models.ModelPreset
+
+	var document presetExport
+	if err := json.Unmarshal(data, &document); err == nil && document.Presets != nil {
+		if document.Version > presetExportVersion {
+			return nil, fmt.Errorf("preset file version %d is newer than this app supports", document.Version)
+		}
+		incoming = document.Presets
+	} else if err := json.Unmarshal(data, &incoming); err != nil {
+		return nil, fmt.Errorf("failed to parse presets: %w", err)
+	}
+
+	for _, preset := range incoming {
+		if err := ValidatePreset(preset); err != nil {
+			return nil, err
+		}
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	presets, err := ps.loadPresets()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(presets))
+	for i, preset := range presets {
+		index[preset.Name] = i
+	}
+
+	imported := []string{}
+	now := time.Now().UnixMilli()
+	for _, preset := range incoming {
+		preset.UpdatedAt = now
+		if preset.CreatedAt == 0 {
+			preset.CreatedAt = now
+		}
+
+		if i, exists := index[preset.Name]; exists {
+			if !overwrite {
+				continue
+			}
+			presets[i] = preset
+		} else {
+			index[preset.Name] = len(presets)
+			presets = append(presets, preset)
+		}
+		imported = append(imported, preset.Name)
+	}
+
+	if len(imported) == 0 {
+		return imported, nil
+	}
+	return imported, ps.savePresets(presets)
+}
+
+// GetPresets returns all presets in the frontend result format
+func (ps *PresetStorage) GetPresets() models.PresetsResult {
+	presets, err := ps.ListPresets()
+	if err != nil {
+		return models.PresetsResult{Success: false, Error: fmt.Sprintf("Failed to load presets: %v", err)}
+	}
+
+	return models.PresetsResult{Success: true, Presets: presets}
+}
+
+// loadPresets loads the presets from storage
+func (ps *PresetStorage) loadPresets() ([]models.ModelPreset, error) {
+	presetsFile := filepath.Join(ps.configDir, "presets.json")
+
+	data, err := os.ReadFile(presetsFile)
+	if os.IsNotExist(err) {
+		return []models.ModelPreset{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presets file: %w", err)
+	}
+
+	var presets []models.ModelPreset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("failed to parse presets file: %w", err)
+	}
+
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+
+	return presets, nil
+}
+
+// savePresets saves the presets to storage
+func (ps *PresetStorage) savePresets(presets []models.ModelPreset) error {
+	presetsFile := filepath.Join(ps.configDir, "presets.json")
+
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal presets: %w", err)
+	}
+
+	return os.WriteFile(presetsFile, data, 0644)
+}
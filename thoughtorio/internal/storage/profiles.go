@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"thoughtorio/internal/models"
+)
+
+// DefaultProfile is the profile whose data lives directly in the config directory,
+// where it was kept before profiles existed
+const DefaultProfile = "default"
+
+// profileIndex is the profiles.json document
+type profileIndex struct {
+	Active   string           `json:"active"`
+	Profiles []models.Profile `json:"profiles"`
+}
+
+// ProfileStorage keeps the list of profiles and which one is active
+type ProfileStorage struct {
+	configDir string
+	mu        sync.Mutex
+}
+
+// NewProfileStorage creates a new profile storage instance
+func NewProfileStorage() (*ProfileStorage, error) {
+	thoughtorioDir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(thoughtorioDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	return &ProfileStorage{
+		configDir: thoughtorioDir,
+	}, nil
+}
+
+// ProfileDir returns the directory holding a profile's data
+func (ps *ProfileStorage) ProfileDir(name string) string {
+	if name == DefaultProfile {
+		return ps.configDir
+	}
+	return filepath.Join(ps.configDir, "profiles", name)
+}
+
+// CacheDir returns where a profile's caches live
+func (ps *ProfileStorage) CacheDir(profile models.Profile) string {
+	if profile.CacheDir != "" {
+		return profile.CacheDir
+	}
+	return filepath.Join(ps.ProfileDir(profile.Name), "cache")
+}
+
+// ListProfiles returns all profiles sorted by name and the active profile's name
+func (ps *ProfileStorage) ListProfiles() (models.ProfilesResult, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.load()
+	if err != nil {
+		return models.ProfilesResult{}, err
+	}
+	return models.ProfilesResult{Active: index.Active, Profiles: index.Profiles}, nil
+}
+
+// GetProfile returns the named profile
+func (ps *ProfileStorage) GetProfile(name string) (models.Profile, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.load()
+	if err != nil {
+		return models.Profile{}, err
+	}
+	for _, profile := range index.Profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return models.Profile{}, fmt.Errorf("profile %q not found", name)
+}
+
+// ActiveProfile returns the profile in use
+func (ps *ProfileStorage) ActiveProfile() (models.Profile, error) {
+	result, err := ps.ListProfiles()
+	if err != nil {
+		return models.Profile{}, err
+	}
+	return ps.GetProfile(result.Active)
+}
+
+// CreateProfile adds a profile and creates its directory
+func (ps *ProfileStorage) CreateProfile(profile models.Profile) (models.Profile, error) {
+	if !presetNamePattern.MatchString(profile.Name) {
+		return models.Profile{}, fmt.Errorf("profile name %q must be 1-64 letters, digits, spaces, '.', '-' or '_'", profile.Name)
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.load()
+	if err != nil {
+		return models.Profile{}, err
+	}
+	for _, existing := range index.Profiles {
+		if existing.Name == profile.Name {
+			return models.Profile{}, fmt.Errorf("profile %q already exists", profile.Name)
+		}
+	}
+
+	if err := os.MkdirAll(ps.ProfileDir(profile.Name), 0755); err != nil {
+		return models.Profile{}, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	profile.CreatedAt = time.Now().UnixMilli()
+	index.Profiles = append(index.Profiles, profile)
+	return profile, ps.save(index)
+}
+
+// DeleteProfile removes a profile and its directory. The default and active profiles can't be deleted.
+func (ps *ProfileStorage) DeleteProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the default profile can't be deleted")
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.load()
+	if err != nil {
+		return err
+	}
+	if index.Active == name {
+		return fmt.Errorf("profile %q is active; switch to another profile first", name)
+	}
+
+	for i, profile := range index.Profiles {
+		if profile.Name == name {
+			index.Profiles = append(index.Profiles[:i], index.Profiles[i+1:]...)
+			if err := ps.save(index); err != nil {
+				return err
+			}
+			return os.RemoveAll(ps.ProfileDir(name))
+		}
+	}
+	return fmt.Errorf("profile %q not found", name)
+}
+
+// SetActiveProfile records which profile is in use
+func (ps *ProfileStorage) SetActiveProfile(name string) (models.Profile, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	index, err := ps.load()
+	if err != nil {
+		return models.Profile{}, err
+	}
+	for _, profile := range index.Profiles {
+		if profile.Name == name {
+			index.Active = name
+			return profile, ps.save(index)
+		}
+	}
+	return models.Profile{}, fmt.Errorf("profile %q not found", name)
+}
+
+// load reads profiles.json, always including the default profile
+func (ps *ProfileStorage) load() (profileIndex, error) {
+	index := profileIndex{Active: DefaultProfile}
+
+	data, err := os.ReadFile(filepath.Join(ps.configDir, "profiles.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return index, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &index); err != nil {
+			return index, fmt.Errorf("failed to parse profiles file: %w", err)
+		}
+	}
+
+	hasDefault := false
+	for _, profile := range index.Profiles {
+		if profile.Name == DefaultProfile {
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		index.Profiles = append(index.Profiles, models.Profile{Name: DefaultProfile})
+	}
+	if index.Active == "" {
+		index.Active = DefaultProfile
+	}
+
+	sort.Slice(index.Profiles, func(i, j int) bool {
+		return index.Profiles[i].Name < index.Profiles[j].Name
+	})
+	return index, nil
+}
+
+// save writes profiles.json
+func (ps *ProfileStorage) save(index profileIndex) error {
+	sort.Slice(index.Profiles, func(i, j int) bool {
+		return index.Profiles[i].Name < index.Profiles[j].Name
+	})
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profiles: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(ps.configDir, "profiles.json"), data, 0644)
+}
@@ -16,14 +16,26 @@ type RecentsStorage struct {
 	configDir string
 }
 
+// ConfigDir returns the Thoughtorio directory inside the user's config directory
+func ConfigDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, "thoughtorio"), nil
+}
+
 // NewRecentsStorage creates a new recents storage instance
 func NewRecentsStorage() (*RecentsStorage, error) {
-	configDir, err := os.UserConfigDir()
+	thoughtorioDir, err := ConfigDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get config directory: %w", err)
+		return nil, err
 	}
-	
-	thoughtorioDir := filepath.Join(configDir, "thoughtorio")
+	return NewRecentsStorageIn(thoughtorioDir)
+}
+
+// NewRecentsStorageIn creates a recents storage instance keeping recents.json in dir
+func NewRecentsStorageIn(thoughtorioDir string) (*RecentsStorage, error) {
 	if err := os.MkdirAll(thoughtorioDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"thoughtorio/internal/models"
+	"thoughtorio/internal/tokenizer"
+)
+
+// SettingsSchemaVersion is the settings.json schema this build reads and writes
+const SettingsSchemaVersion = 1
+
+// settingsMigrations upgrade a settings document one schema version at a time;
+// entry i takes a version i document to version i+1
+var settingsMigrations = []func(map[string]interface{}) error{
+	migrateSettingsV0,
+}
+
+// SettingsStorage persists the app settings in settings.json
+type SettingsStorage struct {
+	configDir string
+	mu        sync.Mutex
+}
+
+// NewSettingsStorage creates a new settings storage instance
+func NewSettingsStorage() (*SettingsStorage, error) {
+	thoughtorioDir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewSettingsStorageIn(thoughtorioDir)
+}
+
+// NewSettingsStorageIn creates a settings storage instance keeping settings.json in dir
+func NewSettingsStorageIn(thoughtorioDir string) (*SettingsStorage, error) {
+	if err := os.MkdirAll(thoughtorioDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	return &SettingsStorage{
+		configDir: thoughtorioDir,
+	}, nil
+}
+
+// DefaultSettings returns the settings used before anything is saved
+func DefaultSettings() models.Settings {
+	return models.Settings{
+		Version:             SettingsSchemaVersion,
+		ActiveMode:          "openrouter",
+		ShowContainerLabels: true,
+		DefaultNodeWidth:    200,
+		DefaultNodeHeight:   120,
+		ContainerPadding:    20,
+		CanvasBackups:       DefaultBackupGenerations,
+		PromptBudget:        tokenizer.DefaultBudgetConfig(),
+	}
+}
+
+// ValidateSettings checks field ranges
+func ValidateSettings(settings models.Settings) error {
+	if settings.ActiveMode == "" {
+		return fmt.Errorf("an active provider is required")
+	}
+	if settings.DefaultNodeWidth < 100 || settings.DefaultNodeWidth > 500 {
+		return fmt.Errorf("default node width must be between 100 and 500")
+	}
+	if settings.DefaultNodeHeight < 80 || settings.DefaultNodeHeight > 400 {
+		return fmt.Errorf("default node height must be between 80 and 400")
+	}
+	if settings.ContainerPadding < 0 || settings.ContainerPadding > 200 {
+		return fmt.Errorf("container padding must be between 0 and 200")
+	}
+	if settings.CanvasBackups < 0 || settings.CanvasBackups > 50 {
+		return fmt.Errorf("canvas backups must be between 0 and 50")
+	}
+	if err := settings.Ollama.Validate(); err != nil {
+		return fmt.Errorf("invalid Ollama configuration: %w", err)
+	}
+	if !settings.AzureOpenAI.IsZero() {
+		if err := settings.AzureOpenAI.Validate(); err != nil {
+			return fmt.Errorf("invalid Azure OpenAI configuration: %w", err)
+		}
+	}
+	if err := settings.PromptBudget.Validate(); err != nil {
+		return fmt.Errorf("invalid prompt budget: %w", err)
+	}
+	return nil
+}
+
+// Load returns the stored settings, migrated to the current schema and filled
+// with defaults for missing fields
+func (ss *SettingsStorage) Load() (models.Settings, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	data, err := os.ReadFile(ss.settingsFile())
+	if os.IsNotExist(err) {
+		return DefaultSettings(), nil
+	}
+	if err != nil {
+		return models.Settings{}, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	settings, migrated, err := ParseSettings(data)
+	if err != nil {
+		return models.Settings{}, err
+	}
+	if migrated {
+		if err := ss.save(settings); err != nil {
+			return models.Settings{}, err
+		}
+	}
+	return settings, nil
+}
+
+// Save validates and writes the settings
+func (ss *SettingsStorage) Save(settings models.Settings) (models.Settings, error) {
+	settings.Version = SettingsSchemaVersion
+	if err := ValidateSettings(settings); err != nil {
+		return models.Settings{}, err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return settings, ss.save(settings)
+}
+
+// ParseSettings decodes a settings document of any known schema version,
+// reporting whether it had to be migrated
+func ParseSettings(data []byte) (models.Settings, bool, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return models.Settings{}, false, fmt.Errorf("failed to parse settings: %w", err)
+	}
+
+	version := 0
+	if raw, ok := document["version"].(float64); ok {
+		version = int(raw)
+	}
+	if version > SettingsSchemaVersion {
+		return models.Settings{}, false, fmt.Errorf("settings version %d is newer than this app supports", version)
+	}
+
+	migrated := version < SettingsSchemaVersion
+	for ; version < SettingsSchemaVersion; version++ {
+		if err := settingsMigrations[version](document); err != nil {
+			return models.Settings{}, false, fmt.Errorf("failed to migrate settings from version %d: %w", version, err)
+		}
+		document["version"] = version + 1
+	}
+
+	normalized, err := json.Marshal(document)
+	if err != nil {
+		return models.Settings{}, false, fmt.Errorf("failed to encode settings: %w", err)
+	}
+	settings := DefaultSettings()
+	if err := json.Unmarshal(normalized, &settings); err != nil {
+		return models.Settings{}, false, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	return settings, migrated, nil
+}
+
+// migrateSettingsV0 adopts the unversioned blob the frontend kept in
+// localStorage. Cleared number inputs were saved as null or as strings, so
+// nulls are dropped in favour of defaults and numeric strings are converted.
+func migrateSettingsV0(document map[string]interface{}) error {
+	for key, value := range document {
+		switch typed := value.(type) {
+		case nil:
+			delete(document, key)
+		case string:
+			switch key {
+			case "defaultNodeWidth", "defaultNodeHeight", "containerPadding":
+				number, err := strconv.Atoi(typed)
+				if err != nil {
+					delete(document, key)
+					continue
+				}
+				document[key] = number
+			}
+		}
+	}
+	return nil
+}
+
+// settingsFile returns the path of settings.json
+func (ss *SettingsStorage) settingsFile() string {
+	return filepath.Join(ss.configDir, "settings.json")
+}
+
+// save writes the settings to storage
+func (ss *SettingsStorage) save(settings models.Settings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	return writeFileAtomic(ss.settingsFile(), data, 0644, nil)
+}
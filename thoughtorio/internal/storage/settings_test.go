@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"thoughtorio/internal/providers"
+	"thoughtorio/internal/tokenizer"
+)
+
+func TestSettingsKeepOllamaConfig(t *testing.T) {
+	store, err := NewSettingsStorageIn(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gpu := 0
+	settings := DefaultSettings()
+	settings.Ollama = providers.OllamaConfig{
+		Options:   providers.OllamaOptions{NumCtx: 16384, NumGPU: &gpu},
+		KeepAlive: "10m",
+		Raw:       true,
+	}
+	if _, err := store.Save(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ollama := loaded.Ollama
+	if ollama.Options.NumCtx != 16384 || ollama.Options.NumGPU == nil || *ollama.Options.NumGPU != 0 || ollama.KeepAlive != "10m" || !ollama.Raw {
+		t.Errorf("loaded Ollama config = %+v, want the saved one", ollama)
+	}
+
+	settings.Ollama.Options.NumCtx = -1
+	if _, err := store.Save(settings); err == nil {
+		t.Error("Save accepted a negative num_ctx")
+	}
+}
+
+func TestSettingsKeepPromptBudget(t *testing.T) {
+	store, err := NewSettingsStorageIn(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Settings saved before budgets were kept get the default
+	if err := os.WriteFile(filepath.Join(store.configDir, "settings.json"), []byte(`{"version":1,"activeMode":"openrouter","defaultNodeWidth":200,"defaultNodeHeight":120}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.PromptBudget != tokenizer.DefaultBudgetConfig() {
+		t.Errorf("prompt budget = %+v, want the default", loaded.PromptBudget)
+	}
+
+	loaded.PromptBudget = tokenizer.BudgetConfig{Strategy: tokenizer.StrategyMiddleOut, ReserveOutputTokens: 256}
+	if _, err := store.Save(loaded); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := store.Load(); err != nil || again.PromptBudget != loaded.PromptBudget {
+		t.Errorf("prompt budget = %+v, %v, want %+v", again.PromptBudget, err, loaded.PromptBudget)
+	}
+
+	loaded.PromptBudget.Strategy = "shuffle"
+	if _, err := store.Save(loaded); err == nil {
+		t.Error("Save accepted an unknown truncation strategy")
+	}
+}
+
+func TestSettingsKeepAzureConfig(t *testing.T) {
+	store, err := NewSettingsStorageIn(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Azure is left unconfigured until a resource is given
+	settings := DefaultSettings()
+	if _, err := store.Save(settings); err != nil {
+		t.Fatalf("Save without Azure configured = %v", err)
+	}
+
+	settings.AzureOpenAIAPIKey = "secret:azure"
+	settings.AzureOpenAI = providers.AzureOpenAIConfig{
+		Endpoint:    "https://my-resource.openai.azure.com",
+		APIVersion:  "2024-06-01",
+		Deployments: map[string]string{"gpt-4o": "prod-gpt4o"},
+	}
+	if _, err := store.Save(settings); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	azure := loaded.AzureOpenAI
+	if azure.Endpoint != settings.AzureOpenAI.Endpoint || azure.APIVersion != "2024-06-01" || azure.Deployments["gpt-4o"] != "prod-gpt4o" {
+		t.Errorf("loaded Azure config = %+v, want the saved one", azure)
+	}
+	if loaded.AzureOpenAIAPIKey != "secret:azure" {
+		t.Errorf("Azure key = %q, want the saved reference", loaded.AzureOpenAIAPIKey)
+	}
+
+	settings.AzureOpenAI.Deployments = nil
+	if _, err := store.Save(settings); err == nil {
+		t.Error("Save accepted an Azure config without deployments")
+	}
+}
+
+func TestSettingsLoadMigratesLegacySettings(t *testing.T) {
+	store, err := NewSettingsStorageIn(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing saved yet gives the defaults
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version != SettingsSchemaVersion || loaded.DefaultNodeWidth != 200 || loaded.ActiveMode != "openrouter" {
+		t.Errorf("settings before a save = %+v, want the defaults", loaded)
+	}
+
+	// The unversioned blob from localStorage kept cleared inputs as null and numbers as strings
+	path := filepath.Join(store.configDir, "settings.json")
+	legacy := `{"activeMode":"local","defaultNodeWidth":"250","defaultNodeHeight":"tall","containerPadding":null,"showContainerLabels":false}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version != SettingsSchemaVersion || loaded.ActiveMode != "local" || loaded.ShowContainerLabels {
+		t.Errorf("migrated settings = %+v, want the saved mode and labels at the current version", loaded)
+	}
+	if loaded.DefaultNodeWidth != 250 || loaded.DefaultNodeHeight != 120 || loaded.ContainerPadding != 20 {
+		t.Errorf("migrated sizes = %d, %d, %d, want 250 and the defaults for the rest", loaded.DefaultNodeWidth, loaded.DefaultNodeHeight, loaded.ContainerPadding)
+	}
+
+	// The migrated settings are written back at the current version
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, migrated, err := ParseSettings(data); err != nil || migrated || again.DefaultNodeWidth != 250 {
+		t.Errorf("rewritten settings = %+v, migrated %v, %v, want them current", again, migrated, err)
+	}
+
+	// Settings from a newer app are refused rather than overwritten
+	if err := os.WriteFile(path, []byte(`{"version":99,"activeMode":"local"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Load of a newer version = %v, want it refused", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), `"version":99`) {
+		t.Errorf("settings file = %s, %v, want it left alone", data, err)
+	}
+
+	if _, err := store.Save(DefaultSettings()); err != nil {
+		t.Fatal(err)
+	}
+	invalid := DefaultSettings()
+	invalid.DefaultNodeWidth = 40
+	if _, err := store.Save(invalid); err == nil {
+		t.Error("Save accepted a node width out of range")
+	}
+	if loaded, err := store.Load(); err != nil || loaded.DefaultNodeWidth != 200 {
+		t.Errorf("settings after a rejected save = %+v, %v, want the last valid ones", loaded, err)
+	}
+}
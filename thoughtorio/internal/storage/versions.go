@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"thoughtorio/internal/canvas"
+	"thoughtorio/internal/models"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// historyStore returns the history store, or an error when there is none
+func (cs *CanvasStorage) historyStore() (*HistoryStore, error) {
+	if cs.history == nil {
+		return nil, fmt.Errorf("canvas history not available")
+	}
+	return cs.history, nil
+}
+
+// ListVersions returns the versions kept of a canvas, newest first
+func (cs *CanvasStorage) ListVersions(canvasPath string) ([]models.CanvasVersion, error) {
+	history, err := cs.historyStore()
+	if err != nil {
+		return nil, err
+	}
+	return history.Versions(canvasPath)
+}
+
+// DiffVersions compares two versions of a canvas. Version 0 stands for an empty
+// canvas, so diffing from 0 lists everything in the other version as added.
+func (cs *CanvasStorage) DiffVersions(canvasPath string, from, to int) (models.CanvasVersionDiff, error) {
+	history, err := cs.historyStore()
+	if err != nil {
+		return models.CanvasVersionDiff{}, err
+	}
+
+	documents := make([]*canvas.Document, 2)
+	for i, number := range []int{from, to} {
+		if number == 0 {
+			continue
+		}
+		if documents[i], _, err = history.Document(canvasPath, number); err != nil {
+			return models.CanvasVersionDiff{}, err
+		}
+	}
+	return models.CanvasVersionDiff{From: from, To: to, Diff: canvas.Compare(documents[0], documents[1])}, nil
+}
+
+// RestoreVersion replaces a canvas with an earlier version of it and returns
+// the restored canvas. The restore is recorded as a new version, so the
+// versions after the restored one stay in the history, as does the file as it
+// was when it differs from the newest version.
+func (cs *CanvasStorage) RestoreVersion(canvasPath string, number int) models.CanvasFileResult {
+	history, err := cs.historyStore()
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Path: canvasPath, Error: err.Error()}
+	}
+	doc, _, err := history.Document(canvasPath, number)
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Path: canvasPath, Error: err.Error()}
+	}
+
+	doc.Created = time.Now().UnixMilli()
+	if current, _, err := cs.readDocument(canvasPath); err == nil {
+		// Changes made to the file outside the app aren't in the history yet
+		if _, _, err := history.Record(canvasPath, current.Document, fmt.Sprintf("Before restoring version %d", number)); err != nil {
+			return models.CanvasFileResult{Success: false, Path: canvasPath, Error: err.Error()}
+		}
+		// The canvas was created when the file was, not when the version was saved
+		if current.Document.Created != 0 {
+			doc.Created = current.Document.Created
+		}
+	}
+	if _, err := cs.writeDocument(canvasPath, doc, fmt.Sprintf("Restored version %d", number)); err != nil {
+		return models.CanvasFileResult{Success: false, Path: canvasPath, Error: err.Error()}
+	}
+	return cs.LoadCanvasFromPath(canvasPath)
+}
+
+// BranchVersion opens a save dialog and saves a version of a canvas as a new
+// canvas, whose history starts from that version. The original is untouched.
+func (cs *CanvasStorage) BranchVersion(canvasPath string, number int) models.CanvasFileResult {
+	history, err := cs.historyStore()
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Error: err.Error()}
+	}
+	doc, _, err := history.Document(canvasPath, number)
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Error: err.Error()}
+	}
+
+	ext := filepath.Ext(canvasPath)
+	filePath, err := runtime.SaveFileDialog(cs.ctx, runtime.SaveDialogOptions{
+		Title:            "Save Version As",
+		DefaultDirectory: filepath.Dir(canvasPath),
+		DefaultFilename:  fmt.Sprintf("%s-v%d%s", strings.TrimSuffix(filepath.Base(canvasPath), ext), number, ext),
+	})
+	if err != nil {
+		return models.CanvasFileResult{Success: false, Error: fmt.Sprintf("Failed to open save dialog: %v", err)}
+	}
+	if filePath == "" {
+		return models.CanvasFileResult{Success: false, Error: "Save cancelled by user"}
+	}
+	if filepath.Clean(filePath) == filepath.Clean(canvasPath) {
+		return models.CanvasFileResult{Success: false, Error: "Choose a new file for the branch, or restore the version instead"}
+	}
+
+	doc.Created = time.Now().UnixMilli()
+	note := fmt.Sprintf("Branched from version %d of %s", number, filepath.Base(canvasPath))
+	if _, err := cs.writeDocument(filePath, doc, note); err != nil {
+		return models.CanvasFileResult{Success: false, Path: filePath, Error: err.Error()}
+	}
+	return cs.LoadCanvasFromPath(filePath)
+}
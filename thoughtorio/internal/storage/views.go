@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"thoughtorio/internal/canvas"
+)
+
+// ViewStore keeps the viewport of each canvas file outside the file. The
+// viewport changes whenever the canvas is panned, so keeping it in the file
+// would make every save differ under version control.
+type ViewStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// canvasView is what a ViewStore keeps for one canvas
+type canvasView struct {
+	Path     string           `json:"path"`
+	Viewport *canvas.Viewport `json:"viewport"`
+}
+
+// NewViewStore creates a view store in dir
+func NewViewStore(dir string) (*ViewStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create views directory: %w", err)
+	}
+	return &ViewStore{dir: dir}, nil
+}
+
+// viewPath returns the file a canvas's view is kept in, keyed by its path
+func (vs *ViewStore) viewPath(canvasPath string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(canvasPath)))
+	return filepath.Join(vs.dir, hex.EncodeToString(sum[:])[:16]+".json")
+}
+
+// Viewport returns the viewport the canvas at canvasPath was last saved with,
+// or nil when none was kept
+func (vs *ViewStore) Viewport(canvasPath string) (*canvas.Viewport, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	data, err := os.ReadFile(vs.viewPath(canvasPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read canvas view: %w", err)
+	}
+	var view canvasView
+	if err := json.Unmarshal(data, &view); err != nil {
+		return nil, fmt.Errorf("canvas view is corrupt: %w", err)
+	}
+	return view.Viewport, nil
+}
+
+// SetViewport keeps the viewport of the canvas at canvasPath
+func (vs *ViewStore) SetViewport(canvasPath string, viewport *canvas.Viewport) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	data, err := json.Marshal(canvasView{Path: canvasPath, Viewport: viewport})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(vs.viewPath(canvasPath), data, 0600, nil); err != nil {
+		return fmt.Errorf("failed to write canvas view: %w", err)
+	}
+	return nil
+}
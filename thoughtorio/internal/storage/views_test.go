@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"thoughtorio/internal/canvas"
+)
+
+func TestSavesKeepTheViewportOutOfTheFile(t *testing.T) {
+	views, err := NewViewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := NewCanvasStorage(context.Background())
+	cs.SetBackupGenerations(0)
+	cs.SetViewStore(views)
+	path := filepath.Join(t.TempDir(), "plan.thoughtorio")
+
+	doc := historyDocument("content")
+	doc.Viewport = &canvas.Viewport{X: 10, Y: 20, Zoom: 1}
+	if _, err := cs.WriteDocument(path, doc); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Panning and saving again leaves the file as it was
+	doc = historyDocument("content")
+	doc.Modified = 9000
+	doc.Viewport = &canvas.Viewport{X: -300, Y: 45, Zoom: 2}
+	if _, err := cs.WriteDocument(path, doc); err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("file changed when only the viewport and save time did\nfirst:\n%s\nsecond:\n%s", first, second)
+	}
+
+	result := cs.LoadCanvasFromPath(path)
+	if !result.Success {
+		t.Fatalf("LoadCanvasFromPath failed: %s", result.Error)
+	}
+	if got := result.Document.Viewport; got == nil || *got != *doc.Viewport {
+		t.Errorf("loaded viewport = %+v, want the last one saved %+v", got, doc.Viewport)
+	}
+	if result.Document.Created != 1000 || result.Document.Modified == 0 {
+		t.Errorf("created %d and modified %d, want the creation time kept and the file time as modified", result.Document.Created, result.Document.Modified)
+	}
+
+	// A file the store has no viewport for keeps its own, as legacy files do
+	other := filepath.Join(filepath.Dir(path), "legacy.thoughtorio")
+	legacy := []byte(`{"formatVersion":2,"version":"1.0","viewport":{"x":5,"y":6,"zoom":0.5},"nodes":[],"connections":[],"metadata":{}}`)
+	if err := os.WriteFile(other, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+	result = cs.LoadCanvasFromPath(other)
+	if !result.Success {
+		t.Fatalf("LoadCanvasFromPath failed: %s", result.Error)
+	}
+	if got := result.Document.Viewport; got == nil || *got != (canvas.Viewport{X: 5, Y: 6, Zoom: 0.5}) {
+		t.Errorf("legacy viewport = %+v, want the file's own", got)
+	}
+}
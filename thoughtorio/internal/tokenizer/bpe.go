@@ -0,0 +1,219 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"unicode"
+)
+
+// BPE is a byte-pair encoder compatible with tiktoken's rank files
+// (cl100k_base.tiktoken, o200k_base.tiktoken)
+type BPE struct {
+	name  string
+	ranks map[string]int
+}
+
+// LoadBPEFile loads a tiktoken rank file from disk
+func LoadBPEFile(name, path string) (*BPE, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return LoadBPE(name, file)
+}
+
+// LoadBPE parses tiktoken's "<base64 token> <rank>" line format
+func LoadBPE(name string, r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := bytes.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid rank file line %d", lineNumber)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid token on line %d: %w", lineNumber, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank on line %d: %w", lineNumber, err)
+		}
+
+		ranks[string(token)] = rank
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rank file: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("rank file is empty")
+	}
+
+	return &BPE{name: name, ranks: ranks}, nil
+}
+
+// Name returns the encoding name
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Exact returns true since counts come from the real encoding
+func (b *BPE) Exact() bool {
+	return true
+}
+
+// CountTokens splits text with the cl100k pre-tokenizer and counts the BPE tokens of each piece
+func (b *BPE) CountTokens(text string) int {
+	count := 0
+	for _, piece := range splitPieces(text) {
+		if _, ok := b.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += b.mergeCount([]byte(piece))
+	}
+	return count
+}
+
+// mergeCount runs byte-pair merges on a piece, always merging the lowest-ranked
+// adjacent pair first, and returns the number of resulting tokens
+func (b *BPE) mergeCount(piece []byte) int {
+	// parts holds the start offset of every current token, plus a final end offset
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	for len(parts) > 2 {
+		bestRank := math.MaxInt
+		bestIndex := -1
+		for i := 0; i < len(parts)-2; i++ {
+			if rank, ok := b.ranks[string(piece[parts[i]:parts[i+2]])]; ok && rank < bestRank {
+				bestRank = rank
+				bestIndex = i
+			}
+		}
+		if bestIndex < 0 {
+			break
+		}
+		parts = append(parts[:bestIndex+1], parts[bestIndex+2:]...)
+	}
+
+	return len(parts) - 1
+}
+
+// splitPieces is a hand-written equivalent of tiktoken's cl100k pre-tokenizer pattern:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Go's regexp package has no lookahead, so the alternatives are matched manually in order.
+// o200k uses a slightly different pattern; reusing this one keeps counts within a few percent.
+func splitPieces(text string) []string {
+	runes := []rune(text)
+	pieces := []string{}
+
+	for i := 0; i < len(runes); {
+		end := matchPiece(runes, i)
+		pieces = append(pieces, string(runes[i:end]))
+		i = end
+	}
+
+	return pieces
+}
+
+// matchPiece returns the end offset of the pre-tokenizer piece starting at i
+func matchPiece(runes []rune, i int) int {
+	n := len(runes)
+	r := runes[i]
+
+	// Contractions
+	if r == '\'' && i+1 < n {
+		next := unicode.ToLower(runes[i+1])
+		if next == 's' || next == 't' || next == 'm' || next == 'd' {
+			return i + 2
+		}
+		if i+2 < n {
+			pair := string([]rune{next, unicode.ToLower(runes[i+2])})
+			if pair == "re" || pair == "ve" || pair == "ll" {
+				return i + 3
+			}
+		}
+	}
+
+	// Optional non-letter prefix followed by letters
+	if unicode.IsLetter(r) {
+		return scanWhile(runes, i+1, unicode.IsLetter)
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) && i+1 < n && unicode.IsLetter(runes[i+1]) {
+		return scanWhile(runes, i+2, unicode.IsLetter)
+	}
+
+	// Up to three digits
+	if unicode.IsNumber(r) {
+		end := i + 1
+		for end < n && end < i+3 && unicode.IsNumber(runes[end]) {
+			end++
+		}
+		return end
+	}
+
+	// Optional space followed by punctuation, then trailing newlines
+	start := i
+	if r == ' ' && i+1 < n && isPunct(runes[i+1]) {
+		start = i + 1
+	}
+	if isPunct(runes[start]) {
+		end := scanWhile(runes, start+1, isPunct)
+		return scanWhile(runes, end, isNewline)
+	}
+
+	// Whitespace runs
+	end := scanWhile(runes, i, unicode.IsSpace)
+	lastNewline := -1
+	for j := i; j < end; j++ {
+		if isNewline(runes[j]) {
+			lastNewline = j
+		}
+	}
+	if lastNewline >= 0 {
+		return lastNewline + 1
+	}
+	if end < n && end-i > 1 {
+		// Leave the final space to prefix the following word
+		return end - 1
+	}
+	return end
+}
+
+func scanWhile(runes []rune, i int, pred func(rune) bool) int {
+	for i < len(runes) && pred(runes[i]) {
+		i++
+	}
+	return i
+}
+
+func isPunct(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
@@ -0,0 +1,93 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSplitPieces checks the hand-written pre-tokenizer against pieces produced
+// by tiktoken's cl100k_base pattern
+func TestSplitPieces(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"Hello world", []string{"Hello", " world"}},
+		{"I'm here", []string{"I", "'m", " here"}},
+		{"IT'S THEY'RE we'll", []string{"IT", "'S", " THEY", "'RE", " we", "'ll"}},
+		{"12345", []string{"123", "45"}},
+		{"$100", []string{"$", "100"}},
+		{"x ...", []string{"x", " ..."}},
+		{"a!!\n\nb", []string{"a", "!!\n\n", "b"}},
+		{"foo  bar", []string{"foo", " ", " bar"}},
+		{"line\n  next", []string{"line", "\n", " ", " next"}},
+		{"end   ", []string{"end", "   "}},
+		{"(hi)", []string{"(hi", ")"}},
+		{"日本語 text", []string{"日本語", " text"}},
+	}
+
+	for _, test := range tests {
+		if got := splitPieces(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitPieces(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+// TestSplitPiecesCoversText checks that pieces always rejoin to the input
+func TestSplitPiecesCoversText(t *testing.T) {
+	for _, text := range []string{"  leading", "trailing\r\n", "mixed 12ab'd\t\tx", "\n\n\n", "🙂 emoji!"} {
+		if got := strings.Join(splitPieces(text), ""); got != text {
+			t.Errorf("pieces of %q rejoin to %q", text, got)
+		}
+	}
+}
+
+func rankFile(tokens ...string) string {
+	var b strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	return b.String()
+}
+
+func TestBPECountTokens(t *testing.T) {
+	bpe, err := LoadBPE("test", strings.NewReader(rankFile("a", "b", "c", " ", "ab", "bc", "abc", " a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},
+		// ab merges before bc, then abc, leaving abc + ab
+		{"abcab", 2},
+		// no rank for "ca" or "cc", so those bytes stay separate
+		{"cca", 3},
+		{"abc abc", 3},
+	}
+	for _, test := range tests {
+		if got := bpe.CountTokens(test.text); got != test.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
+
+func TestLoadBPERejectsInvalidFiles(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":  "\n\n",
+		"fields": "YQ== 0 extra\n",
+		"base64": "!!! 0\n",
+		"rank":   "YQ== first\n",
+	} {
+		if _, err := LoadBPE("test", strings.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package tokenizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Strategy selects how an over-long prompt is brought within a model's context window
+type Strategy string
+
+const (
+	// StrategyNone rejects prompts that don't fit
+	StrategyNone Strategy = "none"
+	// StrategyDropOldest removes context segments from the start, keeping the final task
+	StrategyDropOldest Strategy = "drop_oldest"
+	// StrategyMiddleOut keeps the first and last segments and removes from the middle
+	StrategyMiddleOut Strategy = "middle_out"
+	// StrategySummarize replaces the oldest segments with a model-written summary
+	StrategySummarize Strategy = "summarize"
+)
+
+// segmentSeparator splits prompts into context segments; the frontend joins
+// context chain entries and the task with blank lines
+const segmentSeparator = "\n\n"
+
+// truncationMarker replaces text removed from inside a segment
+const truncationMarker = "\n[...]\n"
+
+// ErrContextExceeded is returned when a prompt can't be made to fit the context window
+var ErrContextExceeded = errors.New("prompt exceeds model context window")
+
+// BudgetConfig configures prompt budgeting against model context limits
+type BudgetConfig struct {
+	Strategy Strategy `json:"strategy"`
+	// ReserveOutputTokens is kept free in the context window for the response
+	ReserveOutputTokens int `json:"reserveOutputTokens"`
+}
+
+// DefaultBudgetConfig returns the budgeting configuration used when none is set
+func DefaultBudgetConfig() BudgetConfig {
+	return BudgetConfig{
+		Strategy:            StrategyDropOldest,
+		ReserveOutputTokens: 1000,
+	}
+}
+
+// Validate checks the strategy name and reserve
+func (c BudgetConfig) Validate() error {
+	switch c.Strategy {
+	case StrategyNone, StrategyDropOldest, StrategyMiddleOut, StrategySummarize:
+	default:
+		return fmt.Errorf("unknown truncation strategy '%s'", c.Strategy)
+	}
+	if c.ReserveOutputTokens < 0 {
+		return fmt.Errorf("reserved output tokens cannot be negative")
+	}
+	return nil
+}
+
+// TruncationReport describes what budgeting did to a prompt
+type TruncationReport struct {
+	Strategy        Strategy `json:"strategy"`
+	Applied         bool     `json:"applied"`
+	Tokenizer       string   `json:"tokenizer"`
+	Exact           bool     `json:"exact"`
+	ContextLimit    int      `json:"contextLimit"`
+	OriginalTokens  int      `json:"originalTokens"`
+	FinalTokens     int      `json:"finalTokens"`
+	DroppedSegments int      `json:"droppedSegments"`
+	Summarized      bool     `json:"summarized,omitempty"`
+	Note            string   `json:"note,omitempty"`
+}
+
+// Summarizer condenses text to roughly targetTokens tokens, usually by calling the model itself
+type Summarizer func(ctx context.Context, text string, targetTokens int) (string, error)
+
+// Fit checks prompt against contextLimit minus the output reserve and applies the
+// configured strategy when it doesn't fit. A zero contextLimit means unknown and
+// the prompt is passed through unchanged.
+func Fit(ctx context.Context, prompt string, contextLimit int, tok Tokenizer, config BudgetConfig, summarize Summarizer) (string, TruncationReport, error) {
+	originalTokens := tok.CountTokens(prompt)
+	report := TruncationReport{
+		Strategy:       config.Strategy,
+		Tokenizer:      tok.Name(),
+		Exact:          tok.Exact(),
+		ContextLimit:   contextLimit,
+		OriginalTokens: originalTokens,
+		FinalTokens:    originalTokens,
+	}
+
+	if contextLimit <= 0 {
+		return prompt, report, nil
+	}
+
+	budget := contextLimit - config.ReserveOutputTokens
+	if budget <= 0 {
+		return prompt, report, fmt.Errorf("%w: %d tokens reserved for output leave no room in a %d token window", ErrContextExceeded, config.ReserveOutputTokens, contextLimit)
+	}
+	if originalTokens <= budget {
+		return prompt, report, nil
+	}
+
+	segments := splitSegments(prompt)
+	var fitted string
+	var dropped int
+
+	switch config.Strategy {
+	case StrategyDropOldest:
+		fitted, dropped = dropOldest(segments, budget, tok)
+	case StrategyMiddleOut:
+		fitted, dropped = middleOut(segments, budget, tok)
+	case StrategySummarize:
+		var summarized bool
+		fitted, dropped, summarized = summarizeOldest(ctx, segments, budget, tok, summarize)
+		report.Summarized = summarized
+		if !summarized {
+			report.Note = "summarization unavailable, fell back to dropping oldest context"
+		}
+	default:
+		return prompt, report, fmt.Errorf("%w: prompt needs %d tokens but only %d are available", ErrContextExceeded, originalTokens, budget)
+	}
+
+	report.Applied = true
+	report.DroppedSegments = dropped
+	report.FinalTokens = tok.CountTokens(fitted)
+
+	if report.FinalTokens > budget {
+		return prompt, report, fmt.Errorf("%w: prompt needs %d tokens after truncation but only %d are available", ErrContextExceeded, report.FinalTokens, budget)
+	}
+
+	return fitted, report, nil
+}
+
+func splitSegments(prompt string) []string {
+	segments := strings.Split(prompt, segmentSeparator)
+	nonEmpty := segments[:0]
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) != "" {
+			nonEmpty = append(nonEmpty, segment)
+		}
+	}
+	return nonEmpty
+}
+
+func joinSegments(segments []string) string {
+	return strings.Join(segments, segmentSeparator)
+}
+
+// dropOldest removes leading segments until the prompt fits, then trims the head
+// of the last remaining segment if the task alone is still too long
+func dropOldest(segments []string, budget int, tok Tokenizer) (string, int) {
+	dropped := 0
+	for len(segments) > 1 && tok.CountTokens(joinSegments(segments)) > budget {
+		segments = segments[1:]
+		dropped++
+	}
+
+	if len(segments) == 1 {
+		segments[0] = keepTail(segments[0], budget, tok)
+	}
+
+	return joinSegments(segments), dropped
+}
+
+// middleOut keeps the first and last segments, removing the segments nearest the
+// middle until the prompt fits, then cuts characters out of the middle if needed
+func middleOut(segments []string, budget int, tok Tokenizer) (string, int) {
+	dropped := 0
+	for len(segments) > 2 && tok.CountTokens(joinSegments(segments)) > budget {
+		middle := len(segments) / 2
+		segments = append(segments[:middle:middle], segments[middle+1:]...)
+		dropped++
+	}
+
+	joined := joinSegments(segments)
+	if tok.CountTokens(joined) > budget {
+		joined = cutMiddle(joined, budget, tok)
+	}
+
+	return joined, dropped
+}
+
+// summarizeOldest replaces the segments dropOldest would remove with a summary
+func summarizeOldest(ctx context.Context, segments []string, budget int, tok Tokenizer, summarize Summarizer) (string, int, bool) {
+	fallback := func() (string, int, bool) {
+		fitted, dropped := dropOldest(segments, budget, tok)
+		return fitted, dropped, false
+	}
+
+	if summarize == nil || len(segments) < 2 {
+		return fallback()
+	}
+
+	kept := segments
+	for len(kept) > 1 && tok.CountTokens(joinSegments(kept)) > budget/2 {
+		kept = kept[1:]
+	}
+	removed := segments[:len(segments)-len(kept)]
+	if len(removed) == 0 {
+		return fallback()
+	}
+
+	target := budget - tok.CountTokens(joinSegments(kept)) - len(segmentSeparator)
+	if target <= 0 {
+		return fallback()
+	}
+
+	// The summarizer sees at most one budget's worth of the removed context
+	source := keepTail(joinSegments(removed), budget, tok)
+	summary, err := summarize(ctx, source, target)
+	if err != nil || strings.TrimSpace(summary) == "" {
+		return fallback()
+	}
+
+	fitted := joinSegments(append([]string{"Summary of earlier context:\n" + strings.TrimSpace(summary)}, kept...))
+	if tok.CountTokens(fitted) > budget {
+		return fallback()
+	}
+
+	return fitted, len(removed), true
+}
+
+// keepTail trims the start of text until it fits within budget tokens
+func keepTail(text string, budget int, tok Tokenizer) string {
+	if tok.CountTokens(text) <= budget {
+		return text
+	}
+
+	runes := []rune(text)
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high) / 2
+		if tok.CountTokens(string(runes[mid:])) <= budget {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return string(runes[low:])
+}
+
+// cutMiddle removes characters from the middle of text until it fits within budget tokens
+func cutMiddle(text string, budget int, tok Tokenizer) string {
+	runes := []rune(text)
+	fits := func(remove int) bool {
+		half := (len(runes) - remove) / 2
+		candidate := string(runes[:half]) + truncationMarker + string(runes[len(runes)-(len(runes)-remove-half):])
+		return tok.CountTokens(candidate) <= budget
+	}
+
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high) / 2
+		if fits(mid) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+
+	half := (len(runes) - low) / 2
+	return string(runes[:half]) + truncationMarker + string(runes[len(runes)-(len(runes)-low-half):])
+}
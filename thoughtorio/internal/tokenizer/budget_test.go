@@ -0,0 +1,129 @@
+package tokenizer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// words counts whitespace-separated words, which keeps budgets easy to reason about
+type words struct{}
+
+func (words) Name() string { return "words" }
+
+func (words) Exact() bool { return true }
+
+func (words) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func fit(t *testing.T, prompt string, limit int, strategy Strategy, summarize Summarizer) (string, TruncationReport, error) {
+	t.Helper()
+	return Fit(context.Background(), prompt, limit, words{}, BudgetConfig{Strategy: strategy}, summarize)
+}
+
+func TestFitPassesThrough(t *testing.T) {
+	prompt := "one two\n\nthree"
+
+	for name, limit := range map[string]int{"unknown limit": 0, "fits": 3} {
+		fitted, report, err := fit(t, prompt, limit, StrategyNone, nil)
+		if err != nil || fitted != prompt || report.Applied {
+			t.Errorf("%s: got %q applied=%v err=%v", name, fitted, report.Applied, err)
+		}
+	}
+}
+
+func TestFitErrors(t *testing.T) {
+	_, _, err := Fit(context.Background(), "one", 10, words{}, BudgetConfig{Strategy: StrategyDropOldest, ReserveOutputTokens: 10}, nil)
+	if !errors.Is(err, ErrContextExceeded) {
+		t.Errorf("reserve filling the window: got %v", err)
+	}
+
+	if _, _, err := fit(t, "one two three", 2, StrategyNone, nil); !errors.Is(err, ErrContextExceeded) {
+		t.Errorf("strategy none: got %v", err)
+	}
+}
+
+func TestFitDropOldest(t *testing.T) {
+	fitted, report, err := fit(t, "one two\n\nthree four\n\nfive six", 4, StrategyDropOldest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fitted != "three four\n\nfive six" {
+		t.Errorf("got %q", fitted)
+	}
+	if !report.Applied || report.DroppedSegments != 1 || report.OriginalTokens != 6 || report.FinalTokens != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestFitDropOldestTrimsTask(t *testing.T) {
+	fitted, report, err := fit(t, "context\n\na b c d e", 2, StrategyDropOldest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(fitted) != "d e" || report.DroppedSegments != 1 {
+		t.Errorf("got %q, dropped %d", fitted, report.DroppedSegments)
+	}
+}
+
+func TestFitMiddleOut(t *testing.T) {
+	fitted, report, err := fit(t, "first\n\nm1\n\nm2\n\nm3\n\nlast", 3, StrategyMiddleOut, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fitted != "first\n\nm1\n\nlast" || report.DroppedSegments != 2 {
+		t.Errorf("got %q, dropped %d", fitted, report.DroppedSegments)
+	}
+}
+
+func TestFitMiddleOutCutsText(t *testing.T) {
+	fitted, _, err := fit(t, "a b c d e f g h", 5, StrategyMiddleOut, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(fitted, "a ") || !strings.HasSuffix(fitted, " h") || !strings.Contains(fitted, "[...]") {
+		t.Errorf("got %q", fitted)
+	}
+}
+
+func TestFitSummarize(t *testing.T) {
+	prompt := "a a a\n\nb b b\n\nc c c\n\ntask task task"
+
+	var source string
+	var target int
+	summarize := func(ctx context.Context, text string, targetTokens int) (string, error) {
+		source, target = text, targetTokens
+		return "gist", nil
+	}
+
+	fitted, report, err := fit(t, prompt, 10, StrategySummarize, summarize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fitted != "Summary of earlier context:\ngist\n\ntask task task" {
+		t.Errorf("got %q", fitted)
+	}
+	if !report.Summarized || report.DroppedSegments != 3 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if source != "a a a\n\nb b b\n\nc c c" || target != 5 {
+		t.Errorf("summarizer got %q with target %d", source, target)
+	}
+}
+
+func TestFitSummarizeFallsBack(t *testing.T) {
+	prompt := "a a a\n\nb b b\n\nc c c\n\ntask task task"
+	failing := func(ctx context.Context, text string, targetTokens int) (string, error) {
+		return "", errors.New("offline")
+	}
+
+	for name, summarize := range map[string]Summarizer{"no summarizer": nil, "summarizer fails": failing} {
+		fitted, report, err := fit(t, prompt, 10, StrategySummarize, summarize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fitted != "b b b\n\nc c c\n\ntask task task" || report.Summarized || report.Note == "" {
+			t.Errorf("%s: got %q, report %+v", name, fitted, report)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tokenizer
+
+import (
+	"math"
+	"unicode"
+)
+
+// Estimator approximates token counts for families whose tokenizers aren't available locally
+type Estimator struct {
+	family        Family
+	charsPerToken float64
+}
+
+// charsPerToken holds the average number of Latin-script characters per token
+// observed for each family on English prose
+var charsPerToken = map[Family]float64{
+	FamilyOpenAI:    4.0,
+	FamilyO200k:     4.2,
+	FamilyGemini:    4.0,
+	FamilyLlama:     3.6,
+	FamilyAnthropic: 3.5,
+	FamilyGeneric:   3.5,
+}
+
+// NewEstimator creates an estimator for the given family
+func NewEstimator(family Family) *Estimator {
+	ratio, ok := charsPerToken[family]
+	if !ok {
+		ratio = charsPerToken[FamilyGeneric]
+	}
+	return &Estimator{family: family, charsPerToken: ratio}
+}
+
+// Name returns the estimator name
+func (e *Estimator) Name() string {
+	return "estimate:" + string(e.family)
+}
+
+// Exact returns false since counts are approximations
+func (e *Estimator) Exact() bool {
+	return false
+}
+
+// CountTokens estimates tokens from character classes: CJK characters are roughly
+// one token each, punctuation usually gets its own token and the rest is divided
+// by the family's characters-per-token ratio
+func (e *Estimator) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	var plain, wide, punct int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			wide++
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			punct++
+		default:
+			plain++
+		}
+	}
+
+	estimate := float64(plain)/e.charsPerToken + float64(wide) + float64(punct)*0.5
+	return int(math.Ceil(estimate))
+}
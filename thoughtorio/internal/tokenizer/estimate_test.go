@@ -0,0 +1,37 @@
+package tokenizer
+
+import "testing"
+
+func TestEstimatorCountTokens(t *testing.T) {
+	tests := []struct {
+		family Family
+		text   string
+		want   int
+	}{
+		{FamilyOpenAI, "", 0},
+		{FamilyOpenAI, "abcdefgh", 2},
+		{FamilyOpenAI, "abcdefghi", 3},
+		{FamilyAnthropic, "abcdefg", 2},
+		// CJK characters count one token each
+		{FamilyOpenAI, "日本語", 3},
+		{FamilyOpenAI, "한국어", 3},
+		// punctuation counts half a token
+		{FamilyOpenAI, "!!!!", 2},
+		{FamilyOpenAI, "abcd!!", 2},
+		// unknown families use the generic ratio
+		{Family("unknown"), "abcdefg", 2},
+	}
+
+	for _, test := range tests {
+		if got := NewEstimator(test.family).CountTokens(test.text); got != test.want {
+			t.Errorf("%s CountTokens(%q) = %d, want %d", test.family, test.text, got, test.want)
+		}
+	}
+}
+
+func TestEstimatorName(t *testing.T) {
+	estimator := NewEstimator(FamilyGemini)
+	if estimator.Name() != "estimate:gemini" || estimator.Exact() {
+		t.Errorf("got %s exact=%v", estimator.Name(), estimator.Exact())
+	}
+}
@@ -0,0 +1,145 @@
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Tokenizer counts the tokens a provider family will see for a piece of text
+type Tokenizer interface {
+	// Name returns the encoding or estimator name, e.g. "cl100k_base" or "estimate:gemini"
+	Name() string
+
+	// CountTokens returns the number of tokens in text
+	CountTokens(text string) int
+
+	// Exact reports whether counts come from the real encoding rather than an estimate
+	Exact() bool
+}
+
+// Family identifies a group of models that share a tokenizer
+type Family string
+
+const (
+	FamilyOpenAI    Family = "openai"
+	FamilyO200k     Family = "openai-o200k"
+	FamilyGemini    Family = "gemini"
+	FamilyLlama     Family = "llama"
+	FamilyAnthropic Family = "anthropic"
+	FamilyGeneric   Family = "generic"
+)
+
+// Registry resolves tokenizers per provider and model, loading tiktoken-compatible
+// BPE rank files from its directory on first use
+type Registry struct {
+	dir      string
+	encoders map[string]*BPE
+	missing  map[string]bool
+	mu       sync.Mutex
+}
+
+// NewRegistry creates a registry that looks for "<encoding>.tiktoken" rank files in dir
+func NewRegistry(dir string) *Registry {
+	return &Registry{
+		dir:      dir,
+		encoders: make(map[string]*BPE),
+		missing:  make(map[string]bool),
+	}
+}
+
+// DefaultDir returns the directory tiktoken rank files are loaded from
+func DefaultDir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "thoughtorio", "tokenizers")
+}
+
+// FamilyFor maps a provider and model ID to the tokenizer family it uses
+func FamilyFor(provider, model string) Family {
+	id := strings.ToLower(model)
+
+	// OpenRouter model IDs are prefixed with the upstream vendor
+	if vendor, rest, ok := strings.Cut(id, "/"); ok {
+		switch vendor {
+		case "openai":
+			return openAIFamily(rest)
+		case "google":
+			return FamilyGemini
+		case "anthropic":
+			return FamilyAnthropic
+		case "meta-llama", "mistralai", "deepseek", "qwen":
+			return FamilyLlama
+		}
+		return FamilyGeneric
+	}
+
+	switch provider {
+	case "openai", "azure":
+		return openAIFamily(id)
+	case "gemini":
+		return FamilyGemini
+	case "local":
+		return FamilyLlama
+	}
+
+	return FamilyGeneric
+}
+
+// openAIFamily distinguishes the o200k encoding used by newer OpenAI models from cl100k
+func openAIFamily(model string) Family {
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4", "chatgpt-4o"} {
+		if strings.HasPrefix(model, prefix) {
+			return FamilyO200k
+		}
+	}
+	return FamilyOpenAI
+}
+
+// ForModel returns the best available tokenizer for the given provider and model.
+// OpenAI families use the real BPE encoding when its rank file is installed and
+// fall back to an estimate otherwise.
+func (r *Registry) ForModel(provider, model string) Tokenizer {
+	family := FamilyFor(provider, model)
+
+	switch family {
+	case FamilyOpenAI:
+		if bpe := r.encoding("cl100k_base"); bpe != nil {
+			return bpe
+		}
+	case FamilyO200k:
+		if bpe := r.encoding("o200k_base"); bpe != nil {
+			return bpe
+		}
+		if bpe := r.encoding("cl100k_base"); bpe != nil {
+			return bpe
+		}
+	}
+
+	return NewEstimator(family)
+}
+
+// encoding loads and caches a BPE rank file, returning nil when it isn't installed
+func (r *Registry) encoding(name string) *BPE {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if bpe, ok := r.encoders[name]; ok {
+		return bpe
+	}
+	if r.missing[name] || r.dir == "" {
+		return nil
+	}
+
+	bpe, err := LoadBPEFile(name, filepath.Join(r.dir, name+".tiktoken"))
+	if err != nil {
+		r.missing[name] = true
+		return nil
+	}
+
+	r.encoders[name] = bpe
+	return bpe
+}
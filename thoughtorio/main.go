@@ -26,6 +26,8 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        appInstance.Startup,
+		OnBeforeClose:    appInstance.BeforeClose,
+		OnShutdown:       appInstance.Shutdown,
 		Bind: []interface{}{
 			appInstance,
 		},